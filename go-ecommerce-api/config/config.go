@@ -0,0 +1,144 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Payment providers
+const (
+	PaymentProviderStripe = "stripe"
+	PaymentProviderFake   = "fake" // in-process gateway for local dev and tests
+)
+
+// Mail senders
+const (
+	MailSenderSMTP = "smtp"
+	MailSenderLog  = "log" // writes emails to the log or a file for local dev
+)
+
+type Config struct {
+	PaymentProvider     string
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	// How often the payment reconciliation worker runs, 0 disables it
+	ReconcileInterval time.Duration
+	// Open payments untouched for this long are checked against the provider
+	ReconcileAfter time.Duration
+	// How often unpaid orders are expired, 0 disables it
+	OrderExpiryInterval time.Duration
+	// Pending orders older than this are expired
+	OrderExpiryAfter time.Duration
+	// Put the items of expired orders back in the user's cart
+	OrderExpiryRestoreCart bool
+
+	MailSender   string
+	MailFrom     string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// File the log sender appends emails to, empty logs them instead
+	MailLogFile string
+	// Base URL of the app, used for the links in emails
+	AppURL string
+	// How long password reset and email verification links work
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+
+	// Failed logins for an email before each attempt has to wait, doubling
+	// from LoginBackoffBase
+	LoginBackoffAfter int
+	LoginBackoffBase  time.Duration
+	// Failed logins for an email, or from an IP, within LoginLockout that
+	// lock it out for LoginLockout
+	LoginMaxFailures   int
+	LoginIPMaxFailures int
+	LoginLockout       time.Duration
+
+	// Name authenticator apps show for the account
+	MFAIssuer string
+}
+
+// Load reads the application config from the environment
+func Load() *Config {
+	return &Config{
+		PaymentProvider:     getEnv("PAYMENT_PROVIDER", PaymentProviderStripe),
+		StripeSecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		StripeWebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		ReconcileInterval:   getDuration("RECONCILE_INTERVAL", 15*time.Minute),
+		ReconcileAfter:      getDuration("RECONCILE_AFTER", 30*time.Minute),
+
+		OrderExpiryInterval:    getDuration("ORDER_EXPIRY_INTERVAL", 10*time.Minute),
+		OrderExpiryAfter:       getDuration("ORDER_EXPIRY_AFTER", time.Hour),
+		OrderExpiryRestoreCart: getBool("ORDER_EXPIRY_RESTORE_CART", false),
+
+		MailSender:   getEnv("MAIL_SENDER", MailSenderLog),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailLogFile:  os.Getenv("MAIL_LOG_FILE"),
+		AppURL:       getEnv("APP_URL", "http://localhost:8081"),
+
+		PasswordResetTTL:     getDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL: getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+
+		LoginBackoffAfter:  getInt("LOGIN_BACKOFF_AFTER", 3),
+		LoginBackoffBase:   getDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginMaxFailures:   getInt("LOGIN_MAX_FAILURES", 10),
+		LoginIPMaxFailures: getInt("LOGIN_IP_MAX_FAILURES", 50),
+		LoginLockout:       getDuration("LOGIN_LOCKOUT", 15*time.Minute),
+
+		MFAIssuer: getEnv("MFA_ISSUER", "E-commerce"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("[error] invalid duration %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return d
+}
+
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("[error] invalid integer %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return i
+}
+
+func getBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("[error] invalid boolean %q for %s, using %t", value, key, fallback)
+		return fallback
+	}
+	return b
+}
@@ -48,6 +48,11 @@ func NewDatabase() (Database, error) {
 	return database, nil
 }
 
+// WrapDatabase wraps an already open connection, e.g. to a test database
+func WrapDatabase(db *gorm.DB) Database {
+	return &database{Db: db}
+}
+
 // METHODS
 
 func (d *database) GetDB() *gorm.DB {
@@ -57,15 +62,46 @@ func (d *database) GetDB() *gorm.DB {
 func (d *database) Migrate() error {
 	err := d.Db.AutoMigrate(
 		&models.User{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.TOTPFactor{},
+		&models.RecoveryCode{},
+		&models.Address{},
 		&models.Product{},
 		&models.Cart{},
 		&models.CartItem{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderStatusHistory{},
 		&models.Wishlist{},
 		&models.Payment{},
+		&models.StockReservation{},
+		&models.Refund{},
+		&models.RefundItem{},
+		&models.WebhookEvent{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.TaxRule{},
+		&models.ShippingMethod{},
+		&models.ShippingRate{},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := migrateMoneyColumns(d.Db); err != nil {
+		return err
+	}
+
+	if err := backfillOrderSubtotals(d.Db); err != nil {
+		return err
+	}
+
+	if err := uniqueOpenPayments(d.Db); err != nil {
+		return err
+	}
+
+	return seedShippingMethods(d.Db)
 }
 
 func (d *database) Close() error {
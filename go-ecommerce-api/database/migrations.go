@@ -0,0 +1,160 @@
+package database
+
+import (
+	"fmt"
+	"go-ecommerce-api/models"
+	"math"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// moneyColumn is a legacy float column holding an amount in major units
+// (19.99) that models.Money replaced with <column>_minor and <column>_currency
+type moneyColumn struct {
+	table  string
+	column string
+	// SQL expression giving each row's currency, empty if the table never
+	// stored one and amounts are in the default currency
+	currency string
+}
+
+// Listed children first: refund_items read their currency from refunds
+var moneyColumns = []moneyColumn{
+	{table: "products", column: "price"},
+	{table: "products", column: "original_price"},
+	{table: "orders", column: "total"},
+	{table: "orders", column: "shipping"},
+	{table: "orders", column: "tax"},
+	{table: "order_items", column: "price"},
+	{table: "refund_items", column: "amount", currency: "(SELECT refunds.currency FROM refunds WHERE refunds.id = refund_items.refund_id)"},
+	{table: "refunds", column: "amount", currency: "currency"},
+	{table: "payments", column: "amount", currency: "currency"},
+	{table: "payments", column: "refunded_amount", currency: "currency"},
+}
+
+// Currency columns made redundant by the currency stored with each amount
+var legacyCurrencyColumns = []struct{ table, column string }{
+	{"refunds", "currency"},
+	{"payments", "currency"},
+}
+
+// migrateMoneyColumns converts the legacy float amount columns to minor units
+// and drops them. It runs after AutoMigrate has added the new columns and is a
+// no-op once the old columns are gone.
+func migrateMoneyColumns(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range moneyColumns {
+			if err := migrateMoneyColumn(tx, c); err != nil {
+				return fmt.Errorf("migrating %s.%s: %w", c.table, c.column, err)
+			}
+		}
+
+		for _, c := range legacyCurrencyColumns {
+			if !tx.Migrator().HasColumn(c.table, c.column) {
+				continue
+			}
+			if err := dropColumn(tx, c.table, c.column); err != nil {
+				return fmt.Errorf("dropping %s.%s: %w", c.table, c.column, err)
+			}
+		}
+		return nil
+	})
+}
+
+func migrateMoneyColumn(tx *gorm.DB, c moneyColumn) error {
+	if !tx.Migrator().HasColumn(c.table, c.column) {
+		return nil
+	}
+
+	currency := fmt.Sprintf("UPPER(COALESCE(%s, '%s'))", c.currency, models.DefaultCurrency)
+	if c.currency == "" {
+		currency = fmt.Sprintf("'%s'", models.DefaultCurrency)
+	}
+
+	// Each currency is scaled by its own number of decimals
+	var currencies []string
+	if err := tx.Raw(fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL", currency, c.table, c.column)).
+		Scan(&currencies).Error; err != nil {
+		return err
+	}
+
+	for _, code := range currencies {
+		scale := math.Pow10(models.CurrencyExponent(code))
+		err := tx.Exec(fmt.Sprintf(
+			"UPDATE %[1]s SET %[2]s_minor = ROUND(%[2]s * ?), %[2]s_currency = ? WHERE %[2]s IS NOT NULL AND %[3]s = ?",
+			c.table, c.column, currency,
+		), scale, code, code).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return dropColumn(tx, c.table, c.column)
+}
+
+// dropColumn drops a column of a table that has no model any more. The SQLite
+// migrator can only drop columns of a model, so it's done in plain SQL.
+func dropColumn(tx *gorm.DB, table, column string) error {
+	return tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column}).Error
+}
+
+// seedShippingMethods adds a standard method charging the flat rate used before
+// shipping methods existed (5.99, free over 100) whenever there are no methods,
+// so checkout keeps working until an admin sets shipping up.
+func seedShippingMethods(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.ShippingMethod{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Create(&models.ShippingMethod{
+		Code:    "standard",
+		Name:    "Standard",
+		MinDays: 3,
+		MaxDays: 5,
+		Rates: []models.ShippingRate{{
+			Price:     models.MoneyFromDecimal(5.99, models.DefaultCurrency),
+			FreeAbove: models.MoneyFromDecimal(100.01, models.DefaultCurrency),
+		}},
+	}).Error
+}
+
+// backfillOrderSubtotals fills in the subtotal of orders placed before orders
+// stored one, from their items
+func backfillOrderSubtotals(db *gorm.DB) error {
+	return db.Exec(`UPDATE orders SET
+		subtotal_minor = (SELECT COALESCE(SUM(order_items.price_minor * order_items.quantity), 0) FROM order_items WHERE order_items.order_id = orders.id),
+		subtotal_currency = total_currency
+		WHERE subtotal_minor = 0 AND total_minor <> 0`).Error
+}
+
+// uniqueOpenPayments adds an index allowing each order only one open payment.
+// Open payments left over from before, other than each order's latest, are
+// closed as cancelled first so the index can be built.
+func uniqueOpenPayments(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE payments SET status = 'cancelled', failure_reason = 'superseded by a newer payment intent'
+			WHERE status IN ? AND EXISTS (
+				SELECT 1 FROM payments newer
+				WHERE newer.order_id = payments.order_id AND newer.id > payments.id AND newer.status IN ?
+			)`, models.OpenPaymentStatuses, models.OpenPaymentStatuses).Error
+		if err != nil {
+			return err
+		}
+
+		// Index predicates can't take bind parameters
+		statuses := make([]string, len(models.OpenPaymentStatuses))
+		for i, status := range models.OpenPaymentStatuses {
+			statuses[i] = "'" + status + "'"
+		}
+		return tx.Exec(fmt.Sprintf(
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_open_order ON payments (order_id) WHERE status IN (%s)",
+			strings.Join(statuses, ", "),
+		)).Error
+	})
+}
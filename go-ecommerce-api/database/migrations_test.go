@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMigrateMoneyColumns(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tables as AutoMigrate leaves them before the legacy columns go
+	for _, stmt := range []string{
+		"CREATE TABLE `products` (`id` integer PRIMARY KEY, `price` real, `price_minor` integer, `price_currency` text)",
+		"CREATE TABLE `payments` (`id` integer PRIMARY KEY, `amount` real, `refunded_amount` real, `currency` text, " +
+			"`amount_minor` integer, `amount_currency` text, `refunded_amount_minor` integer, `refunded_amount_currency` text)",
+		`INSERT INTO products (id, price) VALUES (1, 19.99), (2, 0.125), (3, NULL)`,
+		`INSERT INTO payments (id, amount, refunded_amount, currency) VALUES
+			(1, 24.99, 5.5, 'usd'), (2, 1500, 0, 'JPY'), (3, 1.234, 0, 'KWD'), (4, 10, 0, NULL)`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateMoneyColumns(db); err != nil {
+		t.Fatal(err)
+	}
+
+	type amount struct {
+		ID       uint
+		Minor    *int64
+		Currency *string
+	}
+	tests := []struct {
+		table, column string
+		want          map[uint]string
+	}{
+		{"products", "price", map[uint]string{1: "1999 USD", 2: "13 USD", 3: "<nil> <nil>"}},
+		{"payments", "amount", map[uint]string{1: "2499 USD", 2: "1500 JPY", 3: "1234 KWD", 4: "1000 USD"}},
+		{"payments", "refunded_amount", map[uint]string{1: "550 USD", 2: "0 JPY", 3: "0 KWD", 4: "0 USD"}},
+	}
+	for _, tt := range tests {
+		var rows []amount
+		err := db.Table(tt.table).Select("id, " + tt.column + "_minor AS minor, " + tt.column + "_currency AS currency").
+			Order("id").Scan(&rows).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			if got := format(row.Minor, row.Currency); got != tt.want[row.ID] {
+				t.Errorf("%s.%s of row %d is %s, want %s", tt.table, tt.column, row.ID, got, tt.want[row.ID])
+			}
+		}
+	}
+
+	for _, c := range []struct{ table, column string }{
+		{"products", "price"}, {"payments", "amount"}, {"payments", "refunded_amount"}, {"payments", "currency"},
+	} {
+		if db.Migrator().HasColumn(c.table, c.column) {
+			t.Errorf("legacy column %s.%s was not dropped", c.table, c.column)
+		}
+	}
+
+	// Nothing is left to convert on the next start
+	if err := migrateMoneyColumns(db); err != nil {
+		t.Errorf("second run got error %v", err)
+	}
+}
+
+func format(minor *int64, currency *string) string {
+	if minor == nil || currency == nil {
+		return fmt.Sprint(minor, " ", currency)
+	}
+	return fmt.Sprint(*minor, " ", *currency)
+}
@@ -9,9 +9,13 @@ import (
 
 type RedisClient interface {
 	Set(ctx context.Context, key string, value interface{}) error
+	SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
 	Get(ctx context.Context, key string) (string, error)
 	Del(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
+	IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
 	Close() error
 }
 
@@ -36,6 +40,15 @@ func (r *redisClient) Set(ctx context.Context, key string, value interface{}) er
 
 }
 
+func (r *redisClient) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+// SetNX sets the key only if it doesn't exist yet and reports whether it did
+func (r *redisClient) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
 func (r *redisClient) Get(ctx context.Context, key string) (string, error) {
 	val, err := r.client.Get(ctx, key).Result()
 
@@ -55,6 +68,23 @@ func (r *redisClient) Exists(ctx context.Context, key string) (bool, error) {
 	return result > 0, err
 }
 
+// IncrWithTTL increments the counter and restarts its expiry
+func (r *redisClient) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// TTL returns how long until the key expires. It is negative if the key
+// doesn't exist or has no expiry.
+func (r *redisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.client.TTL(ctx, key).Result()
+}
+
 func (r *redisClient) Close() error {
 	return r.client.Close()
 }
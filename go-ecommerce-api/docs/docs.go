@@ -24,14 +24,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/admin/orders": {
+        "/admin/coupons": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve all orders from all users (Admin only)",
+                "description": "Retrieve all coupons with how often they were redeemed (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -41,12 +41,12 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Get all orders (Admin)",
+                "summary": "List coupons (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.OrdersResponse"
+                            "$ref": "#/definitions/models.CouponsResponse"
                         }
                     },
                     "500": {
@@ -56,16 +56,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/admin/orders/{id}/status": {
-            "put": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the status of an order (Admin only)",
+                "description": "Create a coupon code (Admin only). Types: percentage, fixed_amount, free_shipping, buy_x_get_y",
                 "consumes": [
                     "application/json"
                 ],
@@ -75,30 +73,23 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Update order status (Admin)",
+                "summary": "Create a coupon (Admin)",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Status update",
-                        "name": "request",
+                        "description": "Coupon data",
+                        "name": "coupon",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateOrderStatusRequest"
+                            "$ref": "#/definitions/models.Coupon"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
+                            "$ref": "#/definitions/models.CouponResponse"
                         }
                     },
                     "400": {
@@ -106,48 +97,9 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/admin/products": {
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Create a new product (Admin only). Valid categories: Electronics, Accessories, Home, Office",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "admin"
-                ],
-                "summary": "Create a new product",
-                "parameters": [
-                    {
-                        "description": "Product data",
-                        "name": "product",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Product"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
-                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -155,14 +107,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/products/bulk": {
-            "post": {
+        "/admin/coupons/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create multiple products at once (Admin only). Valid categories: Electronics, Accessories, Home, Office",
+                "description": "Retrieve a coupon by ID (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -172,23 +124,21 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Create multiple products",
+                "summary": "Get a coupon (Admin)",
                 "parameters": [
                     {
-                        "description": "Array of products to create",
-                        "name": "products",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.BulkCreateProductsRequest"
-                        }
+                        "type": "integer",
+                        "description": "Coupon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ProductsResponse"
+                            "$ref": "#/definitions/models.CouponResponse"
                         }
                     },
                     "400": {
@@ -197,6 +147,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -204,16 +160,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/admin/products/{id}": {
+            },
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing product (Admin only)",
+                "description": "Replace a coupon's settings (Admin only). Its redemption count is kept.",
                 "consumes": [
                     "application/json"
                 ],
@@ -223,22 +177,22 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Update a product",
+                "summary": "Update a coupon (Admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Coupon ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated product data",
-                        "name": "product",
+                        "description": "Coupon data",
+                        "name": "coupon",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.Coupon"
                         }
                     }
                 ],
@@ -254,6 +208,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             },
@@ -263,7 +229,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a product by ID (Admin only)",
+                "description": "Delete a coupon by ID (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -273,11 +239,11 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Delete a product",
+                "summary": "Delete a coupon (Admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Coupon ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -295,18 +261,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/admin/users": {
+        "/admin/orders": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve all users (Admin only)",
+                "description": "Retrieve all orders from all users (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -316,16 +288,16 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Get all users (Admin)",
+                "summary": "Get all orders (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UsersResponse"
+                            "$ref": "#/definitions/models.OrdersResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -333,9 +305,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/login": {
-            "post": {
-                "description": "Authenticate user and return access and refresh tokens",
+        "/admin/orders/{id}/refunds": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve all refunds issued for an order (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -343,25 +320,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Login user",
+                "summary": "List refunds of an order (Admin)",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "login",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.LoginPayload"
-                        }
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.LoginResponse"
+                            "$ref": "#/definitions/models.RefundsResponse"
                         }
                     },
                     "400": {
@@ -370,18 +345,21 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/refresh": {
-            "get": {
-                "description": "Generate new access and refresh tokens using refresh token",
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Refund the listed order items, or everything not yet refunded when no items are given (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -389,18 +367,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "Refund an order (Admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Refund request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateRefundRequest"
+                        }
+                    }
                 ],
-                "summary": "Refresh access token",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.RefreshTokenResponse"
+                            "$ref": "#/definitions/models.RefundResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -414,9 +422,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Create a new user account",
+        "/admin/orders/{id}/status": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move an order along pending → processing → shipped → delivered, or to cancelled/returned (Admin only). Cancelling or expiring voids or refunds the payment and releases the order's stock.",
                 "consumes": [
                     "application/json"
                 ],
@@ -424,25 +437,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register new user",
+                "summary": "Update order status (Admin)",
                 "parameters": [
                     {
-                        "description": "User registration data",
-                        "name": "register",
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Status update",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.RegisterPayload"
+                            "$ref": "#/definitions/models.UpdateOrderStatusRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.RegisterResponse"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "400": {
@@ -451,8 +471,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -460,14 +486,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/cart": {
-            "get": {
+        "/admin/products": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve the authenticated user's shopping cart with calculated totals",
+                "description": "Create a new product (Admin only). Valid categories: Electronics, Accessories, Home, Office",
                 "consumes": [
                     "application/json"
                 ],
@@ -475,56 +501,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cart"
+                    "admin"
                 ],
-                "summary": "Get user's cart",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.CartSummaryResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                "summary": "Create a new product",
+                "parameters": [
+                    {
+                        "description": "Product data",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.Product"
                         }
                     }
-                }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Remove all items from the user's cart",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "cart"
                 ],
-                "summary": "Clear cart",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -532,14 +531,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/cart/items": {
+        "/admin/products/bulk": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Add a product to the user's cart",
+                "description": "Create multiple products at once (Admin only). Valid categories: Electronics, Accessories, Home, Office",
                 "consumes": [
                     "application/json"
                 ],
@@ -547,17 +546,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cart"
+                    "admin"
                 ],
-                "summary": "Add item to cart",
+                "summary": "Create multiple products",
                 "parameters": [
                     {
-                        "description": "Add to cart request",
-                        "name": "request",
+                        "description": "Array of products to create",
+                        "name": "products",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddToCartRequest"
+                            "$ref": "#/definitions/models.BulkCreateProductsRequest"
                         }
                     }
                 ],
@@ -565,7 +564,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.CartItemResponse"
+                            "$ref": "#/definitions/models.ProductsResponse"
                         }
                     },
                     "400": {
@@ -574,8 +573,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -583,14 +582,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/cart/items/{id}": {
+        "/admin/products/{id}": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the quantity of an item in the cart",
+                "description": "Update an existing product (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -598,24 +597,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cart"
+                    "admin"
                 ],
-                "summary": "Update cart item quantity",
+                "summary": "Update a product",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Cart Item ID",
+                        "description": "Product ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Update quantity",
-                        "name": "request",
+                        "description": "Updated product data",
+                        "name": "product",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateCartItemRequest"
+                            "$ref": "#/definitions/models.Product"
                         }
                     }
                 ],
@@ -631,12 +630,6 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
             },
@@ -646,7 +639,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Remove an item from the user's cart",
+                "description": "Delete a product by ID (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -654,13 +647,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cart"
+                    "admin"
                 ],
-                "summary": "Remove item from cart",
+                "summary": "Delete a product",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Cart Item ID",
+                        "description": "Product ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -678,24 +671,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/orders": {
+        "/admin/shipping-methods": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve all orders for the authenticated user",
+                "description": "Retrieve all shipping methods with their rates, including disabled ones (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -703,20 +690,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "admin"
                 ],
-                "summary": "Get user's orders",
+                "summary": "List shipping methods (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.OrdersResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.ShippingMethodsResponse"
                         }
                     },
                     "500": {
@@ -733,7 +714,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new order from the user's cart",
+                "description": "Create a shipping method such as standard, express or pickup with its rates by zone, weight and subtotal (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -741,17 +722,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "admin"
                 ],
-                "summary": "Create order from cart",
+                "summary": "Create a shipping method (Admin)",
                 "parameters": [
                     {
-                        "description": "Order creation request",
-                        "name": "request",
+                        "description": "Shipping method data",
+                        "name": "method",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateOrderRequest"
+                            "$ref": "#/definitions/models.ShippingMethod"
                         }
                     }
                 ],
@@ -759,7 +740,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.OrderResponse"
+                            "$ref": "#/definitions/models.ShippingMethodResponse"
                         }
                     },
                     "400": {
@@ -768,8 +749,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -777,14 +758,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/orders/checkout": {
-            "post": {
+        "/admin/shipping-methods/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create order and payment intent in one step. Cart is automatically cleared after successful checkout.",
+                "description": "Retrieve a shipping method with its rates by ID (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -792,14 +773,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "admin"
+                ],
+                "summary": "Get a shipping method (Admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Shipping method ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Checkout and create payment",
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.CheckoutResponse"
+                            "$ref": "#/definitions/models.ShippingMethodResponse"
                         }
                     },
                     "400": {
@@ -808,8 +798,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -821,16 +811,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/orders/{id}": {
-            "get": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve a specific order by its ID",
+                "description": "Replace a shipping method's settings and all of its rates (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -838,23 +826,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "admin"
                 ],
-                "summary": "Get order by ID",
+                "summary": "Update a shipping method (Admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Order ID",
+                        "description": "Shipping method ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Shipping method data",
+                        "name": "method",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ShippingMethod"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.OrderResponse"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "400": {
@@ -863,23 +860,27 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/payments/confirm-success/{orderId}": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Manually confirm payment success and update order status (for testing without webhooks)",
+                "description": "Delete a shipping method and its rates by ID (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -887,14 +888,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "payments"
+                    "admin"
                 ],
-                "summary": "Confirm payment success",
+                "summary": "Delete a shipping method (Admin)",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Order ID",
-                        "name": "orderId",
+                        "type": "integer",
+                        "description": "Shipping method ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -903,17 +904,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.PaymentSuccessResponse"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -921,9 +922,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/ping": {
+        "/admin/tax-rules": {
             "get": {
-                "description": "Check if the API is running",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the tax table (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -931,22 +937,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
                 ],
-                "summary": "Health check",
+                "summary": "List tax rules (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.HealthResponse"
+                            "$ref": "#/definitions/models.TaxRulesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/products": {
-            "get": {
-                "description": "Retrieve all products",
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a rate for a country, state and/or product category (Admin only). The most specific matching rule applies.",
                 "consumes": [
                     "application/json"
                 ],
@@ -954,18 +969,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "admin"
+                ],
+                "summary": "Create a tax rule (Admin)",
+                "parameters": [
+                    {
+                        "description": "Tax rule",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TaxRule"
+                        }
+                    }
                 ],
-                "summary": "Get all products",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.ProductsResponse"
+                            "$ref": "#/definitions/models.TaxRuleResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -973,9 +999,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/products/category/{category}": {
-            "get": {
-                "description": "Retrieve products filtered by category",
+        "/admin/tax-rules/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace a tax rule (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -983,90 +1014,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "admin"
                 ],
-                "summary": "Get products by category",
+                "summary": "Update a tax rule (Admin)",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Product Category",
-                        "name": "category",
+                        "type": "integer",
+                        "description": "Tax rule ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.ProductsResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/products/featured": {
-            "get": {
-                "description": "Retrieve all featured products",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "products"
-                ],
-                "summary": "Get featured products",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.ProductsResponse"
-                        }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    {
+                        "description": "Tax rule",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.TaxRule"
                         }
                     }
-                }
-            }
-        },
-        "/products/search": {
-            "get": {
-                "description": "Search products by name or description",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "products"
-                ],
-                "summary": "Search products",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Search query",
-                        "name": "query",
-                        "in": "query",
-                        "required": true
-                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ProductsResponse"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "400": {
@@ -1075,18 +1048,21 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/products/{id}": {
-            "get": {
-                "description": "Retrieve a specific product by its ID",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a tax rule by ID (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1094,13 +1070,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "admin"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Delete a tax rule (Admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Tax rule ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1110,7 +1086,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ProductResponse"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "400": {
@@ -1128,14 +1104,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/user": {
+        "/admin/users": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve the authenticated user's information",
+                "description": "Retrieve all users (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1143,14 +1119,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Get current user",
+                "summary": "Get all users (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UserResponse"
+                            "$ref": "#/definitions/models.UsersResponse"
                         }
                     },
                     "400": {
@@ -1158,22 +1134,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
-            },
+            }
+        },
+        "/admin/users/{id}/role": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the authenticated user's information",
+                "description": "Make a user an admin or a regular user. The user's tokens stop working, so they have to log in again (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1181,23 +1153,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Update current user",
+                "summary": "Change a user's role (Admin)",
                 "parameters": [
                     {
-                        "description": "Updated user data",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New role",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.UpdateUserRoleRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.SuccessResponse"
                         }
@@ -1208,21 +1187,23 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete the authenticated user's account",
+                "description": "Retrieve received payment webhook events, newest first, optionally filtered by status (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1230,24 +1211,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
+                ],
+                "summary": "List webhook events (Admin)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "processing, processed or failed",
+                        "name": "status",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Delete current user",
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
-                        "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.WebhookEventsResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -1255,14 +1238,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/wishlist": {
-            "get": {
+        "/admin/webhooks/{id}/replay": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve all items in the authenticated user's wishlist",
+                "description": "Process a failed webhook event, or one whose processing stalled for over 5 minutes, again from its stored payload (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1270,18 +1253,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wishlist"
+                    "admin"
+                ],
+                "summary": "Replay a failed webhook event (Admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get user's wishlist",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.WishlistResponse"
+                            "$ref": "#/definitions/models.WebhookEventResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -1293,14 +1297,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/forgot-password": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Add a product to the user's wishlist",
+                "description": "Email a single-use password reset link. The response is the same whether or not the email is registered.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1308,23 +1309,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wishlist"
+                    "auth"
                 ],
-                "summary": "Add product to wishlist",
+                "summary": "Request a password reset",
                 "parameters": [
                     {
-                        "description": "Add to wishlist request",
-                        "name": "request",
+                        "description": "Account email",
+                        "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddToWishlistRequest"
+                            "$ref": "#/definitions/handlers.ForgotPasswordPayload"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.SuccessResponse"
                         }
@@ -1334,24 +1335,13 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/wishlist/{product_id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Check if a product is in the user's wishlist",
+        "/auth/login": {
+            "post": {
+                "description": "Authenticate user and return access and refresh tokens. Accounts with two-factor authentication get 202 with an mfa_token instead, to exchange at /auth/mfa/verify. Wrong passwords and unknown emails get the same 401; repeated failures slow down and then lock out the email or IP with a 429 and a Retry-After header.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1359,23 +1349,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wishlist"
+                    "auth"
                 ],
-                "summary": "Check if product is in wishlist",
+                "summary": "Login user",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "product_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.WishlistCheckResponse"
+                        "description": "Login credentials",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.MFAChallengeResponse"
                         }
                     },
                     "400": {
@@ -1389,16 +1387,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/auth/logout": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Remove a product from the user's wishlist",
+                "description": "Revoke the current session. Its access and refresh tokens stop working.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1406,18 +1424,49 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "wishlist"
+                    "auth"
                 ],
-                "summary": "Remove product from wishlist",
-                "parameters": [
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout-all": {
+            "post": {
+                "security": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "product_id",
-                        "in": "path",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
+                "description": "Revoke every session of the authenticated user, on all devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout everywhere",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1425,6 +1474,52 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/verify": {
+            "post": {
+                "description": "Exchange the mfa_token from /auth/login and a code from the authenticator app, or a recovery code, for access and refresh tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete a two-factor login",
+                "parameters": [
+                    {
+                        "description": "MFA token and code",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VerifyMFAPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -1436,107 +1531,3097 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.LoginPayload": {
-            "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string",
-                    "minLength": 3,
-                    "example": "test@example.com"
-                },
-                "password": {
-                    "type": "string",
-                    "minLength": 8,
-                    "example": "password"
+        },
+        "/auth/refresh": {
+            "get": {
+                "description": "Exchange a refresh token for new access and refresh tokens. Each refresh token works once; using one again revokes its session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshTokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "handlers.RegisterPayload": {
-            "type": "object",
-            "required": [
-                "email",
-                "name",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string",
-                    "minLength": 3,
-                    "example": "test@example.com"
-                },
-                "name": {
-                    "type": "string",
-                    "minLength": 2,
-                    "example": "John Doe"
-                },
-                "password": {
-                    "type": "string",
-                    "minLength": 8,
-                    "example": "password"
+        "/auth/register": {
+            "post": {
+                "description": "Create a new user account and email a link to verify the address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register new user",
+                "parameters": [
+                    {
+                        "description": "User registration data",
+                        "name": "register",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.RegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "models.AddToCartRequest": {
-            "type": "object",
-            "required": [
-                "product_id",
+        "/auth/resend-verification": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Email the authenticated user a new verification link. Earlier links stop working.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Resend verification email",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reset-password": {
+            "post": {
+                "description": "Set a new password with the token from a reset link. All of the user's sessions are logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Reset password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResetPasswordPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-email": {
+            "post": {
+                "description": "Confirm the user's email with the token from a verification link",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify email",
+                "parameters": [
+                    {
+                        "description": "Verification token",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VerifyEmailPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cart": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the authenticated user's shopping cart with calculated totals. Tax and shipping are worked out for the given destination, with the cheapest shipping method unless one is chosen.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Get user's cart",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Destination country (ISO 3166-1 alpha-2)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination state or region",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Shipping method ID",
+                        "name": "shipping_method_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CartSummaryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove all items from the user's cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Clear cart",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cart/coupon": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a coupon code to the user's cart. Returns the cart summary with the discount line.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Apply a coupon",
+                "parameters": [
+                    {
+                        "description": "Coupon code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ApplyCouponRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination country (ISO 3166-1 alpha-2)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination state or region",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Shipping method ID",
+                        "name": "shipping_method_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CartSummaryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the coupon applied to the user's cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Remove the coupon",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cart/items": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a product to the user's cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Add item to cart",
+                "parameters": [
+                    {
+                        "description": "Add to cart request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddToCartRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CartItemResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cart/items/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the quantity of an item in the cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Update cart item quantity",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Cart Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update quantity",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateCartItemRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove an item from the user's cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Remove item from cart",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Cart Item ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cart/shipping-options": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the shipping methods that can deliver the user's cart to the destination, cheapest first, priced by the server",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "List shipping options",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Destination country (ISO 3166-1 alpha-2)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination state or region",
+                        "name": "state",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ShippingOptionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/cart/validate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Check the user's cart against the current products. Warns about products that are no longer available, quantities above stock and prices that changed since the items were added. Checkout runs the same check and refuses a cart with warnings, except price changes it was told to accept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Validate cart",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CartValidationResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve all orders for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get user's orders",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrdersResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new order from the user's cart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Create order from cart",
+                "parameters": [
+                    {
+                        "description": "Order creation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.CartChangedResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/checkout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create order and payment intent in one step. Cart is automatically cleared after successful checkout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Checkout and create payment",
+                "parameters": [
+                    {
+                        "description": "Shipping method and addresses",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CheckoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CheckoutResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.CartChangedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve one of the authenticated user's orders by its ID, including its status history",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel a pending or processing order. An unpaid payment is voided and a paid one is refunded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Cancel order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation reason",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CancelOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/confirm-success/{orderId}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a payment of a pending order without waiting for the webhook. The provider must report the order's payment intent as succeeded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Confirm payment success",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentSuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
+            "get": {
+                "description": "Check if the API is running",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "Retrieve all products",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get all products",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/category/{category}": {
+            "get": {
+                "description": "Retrieve products filtered by category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get products by category",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Product Category",
+                        "name": "category",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/featured": {
+            "get": {
+                "description": "Retrieve all featured products",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get featured products",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/search": {
+            "get": {
+                "description": "Search products by name or description",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Search products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "description": "Retrieve a specific product by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the authenticated user's information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the authenticated user's information. The role can only be changed by an admin.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update current user",
+                "parameters": [
+                    {
+                        "description": "Updated user data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete the authenticated user's account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete current user",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/addresses": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the authenticated user's address book, default address first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List addresses",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AddressesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add an address to the authenticated user's address book. The first address, or one sent with is_default, becomes the default.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Add an address",
+                "parameters": [
+                    {
+                        "description": "Address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Address"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.AddressResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/addresses/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve one of the authenticated user's addresses",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get an address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AddressResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace one of the authenticated user's addresses. Orders already placed keep the address they were placed with.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update an address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Address"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove an address from the authenticated user's address book",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete an address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/addresses/{id}/default": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Make one of the authenticated user's addresses the default, used when checkout doesn't name one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set the default address",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/mfa": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Tell whether the authenticated user has two-factor authentication enabled and how many recovery codes are left",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get two-factor status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MFAStatusResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/mfa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the recovery codes, used or not, with new ones. Takes a current code or a recovery code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app, or a recovery code",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MFACodePayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/mfa/totp": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a TOTP secret for an authenticator app. Show provisioning_uri as a QR code, then confirm with a code from the app.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Start two-factor enrolment",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.TOTPEnrolmentResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the authenticator app and recovery codes. Takes a current code or a recovery code. All of the user's sessions are logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app, or a recovery code",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MFACodePayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Enable two-factor authentication with a code from the authenticator app. Returns recovery codes, which are only shown once. Log in again to get an admin session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Confirm two-factor enrolment",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MFACodePayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wishlist": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve all items in the authenticated user's wishlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wishlist"
+                ],
+                "summary": "Get user's wishlist",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WishlistResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a product to the user's wishlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wishlist"
+                ],
+                "summary": "Add product to wishlist",
+                "parameters": [
+                    {
+                        "description": "Add to wishlist request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddToWishlistRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/wishlist/{product_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Check if a product is in the user's wishlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wishlist"
+                ],
+                "summary": "Check if product is in wishlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WishlistCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove a product from the user's wishlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wishlist"
+                ],
+                "summary": "Remove product from wishlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.ForgotPasswordPayload": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "test@example.com"
+                }
+            }
+        },
+        "handlers.LoginPayload": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "minLength": 3,
+                    "example": "test@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 8,
+                    "example": "password"
+                }
+            }
+        },
+        "handlers.MFACodePayload": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "from the authenticator app, or a recovery code",
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "handlers.RegisterPayload": {
+            "type": "object",
+            "required": [
+                "email",
+                "name",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "minLength": 3,
+                    "example": "test@example.com"
+                },
+                "name": {
+                    "type": "string",
+                    "minLength": 2,
+                    "example": "John Doe"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 8,
+                    "example": "password"
+                }
+            }
+        },
+        "handlers.ResetPasswordPayload": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "minLength": 8,
+                    "example": "new-password"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "3f1c9a0e5b7d4c2a8e6f0b1d3c5a7e9f"
+                }
+            }
+        },
+        "handlers.VerifyEmailPayload": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "example": "3f1c9a0e5b7d4c2a8e6f0b1d3c5a7e9f"
+                }
+            }
+        },
+        "handlers.VerifyMFAPayload": {
+            "type": "object",
+            "required": [
+                "code",
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "description": "from the authenticator app, or a recovery code",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AddToCartRequest": {
+            "type": "object",
+            "required": [
+                "product_id",
                 "quantity"
             ],
             "properties": {
-                "product_id": {
+                "product_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 2
+                }
+            }
+        },
+        "models.AddToWishlistRequest": {
+            "type": "object",
+            "required": [
+                "product_id"
+            ],
+            "properties": {
+                "product_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.Address": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "example": "San Francisco"
+                },
+                "company": {
+                    "type": "string"
+                },
+                "country": {
+                    "description": "ISO 3166-1 alpha-2",
+                    "type": "string",
+                    "example": "US"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_default": {
+                    "description": "used when checkout doesn't name an address",
+                    "type": "boolean"
+                },
+                "line1": {
+                    "type": "string",
+                    "example": "1 Market St"
+                },
+                "line2": {
+                    "type": "string",
+                    "example": "Suite 300"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Jane Doe"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+1 415 555 0100"
+                },
+                "postal_code": {
+                    "type": "string",
+                    "example": "94105"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AddressResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Address"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AddressesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Address"
+                    }
+                }
+            }
+        },
+        "models.ApplyCouponRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "SUMMER10"
+                }
+            }
+        },
+        "models.BulkCreateProductsRequest": {
+            "type": "object"
+        },
+        "models.CancelOrderRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "Ordered by mistake"
+                }
+            }
+        },
+        "models.Cart": {
+            "type": "object",
+            "properties": {
+                "coupon_code": {
+                    "description": "applied with POST /cart/coupon",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CartItem"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CartChangedResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Cart changed since items were added"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CartWarning"
+                    }
+                }
+            }
+        },
+        "models.CartItem": {
+            "type": "object",
+            "properties": {
+                "cart": {
+                    "$ref": "#/definitions/models.Cart"
+                },
+                "cart_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "price_at_add": {
+                    "description": "what the customer saw when adding it",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "product": {
+                    "$ref": "#/definitions/models.Product"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CartItemResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.CartItem"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CartSummary": {
+            "type": "object",
+            "properties": {
+                "coupon_code": {
+                    "description": "applied with POST /cart/coupon",
+                    "type": "string"
+                },
+                "coupon_error": {
+                    "description": "why the applied coupon no longer counts",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "discount": {
+                    "$ref": "#/definitions/models.Discount"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CartItem"
+                    }
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QuoteLine"
+                    }
+                },
+                "shipping": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "shipping_method": {
+                    "description": "the one Shipping is for, nil if none can ship the cart",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ShippingOption"
+                        }
+                    ]
+                },
+                "subtotal": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "tax": {
+                    "description": "added on top of the prices",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "tax_included": {
+                    "description": "already part of tax-inclusive prices",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "total": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CartSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.CartSummary"
+                }
+            }
+        },
+        "models.CartValidation": {
+            "type": "object",
+            "properties": {
+                "valid": {
+                    "description": "no warnings",
+                    "type": "boolean"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CartWarning"
+                    }
+                }
+            }
+        },
+        "models.CartValidationResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.CartValidation"
+                }
+            }
+        },
+        "models.CartWarning": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "cart_item_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "code": {
+                    "description": "product_unavailable, insufficient_stock, price_changed",
+                    "type": "string",
+                    "example": "price_changed"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Price changed from 89.99 USD to 99.99 USD"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Wireless Headphones"
+                },
+                "new_price": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "old_price": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "requested": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "models.CheckoutOrderResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "payment_status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "shipping": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "total": {
+                    "$ref": "#/definitions/models.Money"
+                }
+            }
+        },
+        "models.CheckoutPaymentResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "payment_intent_id": {
+                    "type": "string",
+                    "example": "pi_3SZA4XIH7jU9U4pR0VhsSo69"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "requires_payment_method"
+                }
+            }
+        },
+        "models.CheckoutRequest": {
+            "type": "object",
+            "required": [
+                "shipping_method_id"
+            ],
+            "properties": {
+                "acknowledge_price_changes": {
+                    "description": "order at current prices after a price_changed warning",
+                    "type": "boolean"
+                },
+                "billing_address_id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "shipping_address_id": {
+                    "description": "decides the tax and shipping rates",
+                    "type": "integer",
+                    "example": 3
+                },
+                "shipping_method_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.CheckoutResponse": {
+            "type": "object",
+            "properties": {
+                "client_secret": {
+                    "type": "string",
+                    "example": "pi_xxx_secret_yyy"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Checkout successful"
+                },
+                "order": {
+                    "$ref": "#/definitions/models.CheckoutOrderResponse"
+                },
+                "payment": {
+                    "$ref": "#/definitions/models.CheckoutPaymentResponse"
+                }
+            }
+        },
+        "models.Coupon": {
+            "type": "object",
+            "properties": {
+                "amount_off": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "buy_quantity": {
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "SUMMER10"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "10% off everything"
+                },
+                "disabled": {
+                    "description": "stops new redemptions, e.g. to retire a code",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "get_quantity": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "max_redemptions": {
+                    "description": "across all users, 0 for unlimited",
+                    "type": "integer"
+                },
+                "max_redemptions_per_user": {
+                    "description": "0 for unlimited",
+                    "type": "integer"
+                },
+                "min_subtotal": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "percent_off": {
+                    "type": "number",
+                    "example": 10
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "times_redeemed": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "percentage, fixed_amount, free_shipping, buy_x_get_y",
+                    "type": "string",
+                    "example": "percentage"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CouponResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Coupon"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CouponsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Coupon"
+                    }
+                }
+            }
+        },
+        "models.CreateOrderRequest": {
+            "type": "object",
+            "required": [
+                "shipping_method_id"
+            ],
+            "properties": {
+                "acknowledge_price_changes": {
+                    "description": "order at current prices after a price_changed warning",
+                    "type": "boolean"
+                },
+                "billing_address_id": {
                     "type": "integer",
-                    "example": 1
+                    "example": 4
                 },
-                "quantity": {
+                "shipping_address_id": {
+                    "description": "decides the tax and shipping rates",
                     "type": "integer",
-                    "minimum": 1,
-                    "example": 2
+                    "example": 3
+                },
+                "shipping_method_id": {
+                    "type": "integer",
+                    "example": 1
                 }
             }
         },
-        "models.AddToWishlistRequest": {
+        "models.CreateRefundRequest": {
             "type": "object",
-            "required": [
-                "product_id"
-            ],
             "properties": {
-                "product_id": {
-                    "type": "integer",
-                    "example": 1
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RefundItemRequest"
+                    }
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Damaged in transit"
                 }
             }
         },
-        "models.BulkCreateProductsRequest": {
-            "type": "object"
+        "models.Discount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "shipping": {
+                    "$ref": "#/definitions/models.Money"
+                }
+            }
         },
-        "models.Cart": {
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "Message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "user": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.MFAChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Two-factor code required"
+                },
+                "mfa_required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "mfa_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MFAStatus": {
+            "type": "object",
+            "properties": {
+                "recovery_codes_remaining": {
+                    "type": "integer"
+                },
+                "totp_enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.MFAStatusResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.MFAStatus"
+                }
+            }
+        },
+        "models.Money": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "example": 1999
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                }
+            }
+        },
+        "models.Order": {
             "type": "object",
             "properties": {
+                "billing_address": {
+                    "$ref": "#/definitions/models.PostalAddress"
+                },
+                "coupon_code": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "deleted_at": {
                     "type": "string"
                 },
+                "discount": {
+                    "$ref": "#/definitions/models.Money"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "items": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.CartItem"
+                        "$ref": "#/definitions/models.OrderItem"
+                    }
+                },
+                "payment_intent_id": {
+                    "type": "string"
+                },
+                "payment_method": {
+                    "description": "card, cash, etc.",
+                    "type": "string"
+                },
+                "payment_status": {
+                    "description": "pending, requires_action, processing, succeeded, failed, cancelled, partially_refunded, refunded, disputed",
+                    "type": "string"
+                },
+                "shipping": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "shipping_address": {
+                    "description": "copied at checkout, so address book edits don't rewrite it",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PostalAddress"
+                        }
+                    ]
+                },
+                "shipping_discount": {
+                    "description": "part of Discount that waived shipping",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "shipping_method": {
+                    "description": "code of the method chosen at checkout",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, processing, shipped, delivered, cancelled, returned, expired",
+                    "type": "string"
+                },
+                "status_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OrderStatusHistory"
                     }
                 },
+                "subtotal": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "tax": {
+                    "description": "added on top of the prices",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "tax_included": {
+                    "description": "already part of tax-inclusive prices",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "total": {
+                    "description": "subtotal - discount + shipping + tax",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 },
@@ -1548,193 +4633,302 @@ const docTemplate = `{
                 }
             }
         },
-        "models.CartItem": {
+        "models.OrderItem": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "discount": {
+                    "description": "this line's share of the order discount",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order": {
+                    "$ref": "#/definitions/models.Order"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "description": "Price at time of order",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "product": {
+                    "$ref": "#/definitions/models.Product"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "tax": {
+                    "description": "on the line after its discount, for invoicing",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrderResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Order"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrderStatusHistory": {
+            "type": "object",
+            "properties": {
+                "changed_by": {
+                    "description": "user ID, nil for automatic changes",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_status": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "source": {
+                    "description": "customer, admin, payment, system",
+                    "type": "string"
+                },
+                "to_status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OrdersResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Order"
+                    }
+                }
+            }
+        },
+        "models.PaymentSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Payment confirmed"
+                }
+            }
+        },
+        "models.PostalAddress": {
             "type": "object",
             "properties": {
-                "cart": {
-                    "$ref": "#/definitions/models.Cart"
-                },
-                "cart_id": {
-                    "type": "integer"
+                "city": {
+                    "type": "string",
+                    "example": "San Francisco"
                 },
-                "created_at": {
+                "company": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "country": {
+                    "description": "ISO 3166-1 alpha-2",
+                    "type": "string",
+                    "example": "US"
                 },
-                "product": {
-                    "$ref": "#/definitions/models.Product"
+                "line1": {
+                    "type": "string",
+                    "example": "1 Market St"
                 },
-                "product_id": {
-                    "type": "integer"
+                "line2": {
+                    "type": "string",
+                    "example": "Suite 300"
                 },
-                "quantity": {
-                    "type": "integer"
+                "name": {
+                    "type": "string",
+                    "example": "Jane Doe"
                 },
-                "updated_at": {
-                    "type": "string"
+                "phone": {
+                    "type": "string",
+                    "example": "+1 415 555 0100"
+                },
+                "postal_code": {
+                    "type": "string",
+                    "example": "94105"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
                 }
             }
         },
-        "models.CartItemResponse": {
+        "models.Product": {
             "type": "object",
             "properties": {
-                "data": {
-                    "$ref": "#/definitions/models.CartItem"
+                "badge": {
+                    "type": "string"
                 },
-                "message": {
+                "badge_color": {
                     "type": "string"
-                }
-            }
-        },
-        "models.CartSummary": {
-            "type": "object",
-            "properties": {
+                },
+                "category": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "deleted_at": {
                     "type": "string"
                 },
+                "description": {
+                    "type": "string"
+                },
+                "featured": {
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.CartItem"
-                    }
+                "image": {
+                    "type": "string"
                 },
-                "shipping": {
-                    "type": "number"
+                "name": {
+                    "type": "string"
                 },
-                "subtotal": {
-                    "type": "number"
+                "original_price": {
+                    "$ref": "#/definitions/models.Money"
                 },
-                "tax": {
-                    "type": "number"
+                "price": {
+                    "$ref": "#/definitions/models.Money"
                 },
-                "tax_rate": {
+                "rating": {
                     "type": "number"
                 },
-                "total": {
-                    "type": "number"
+                "stock": {
+                    "type": "integer"
                 },
                 "updated_at": {
                     "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/models.User"
-                },
-                "user_id": {
-                    "type": "integer"
+                "weight": {
+                    "description": "grams, for shipping rates",
+                    "type": "integer",
+                    "example": 350
                 }
             }
         },
-        "models.CartSummaryResponse": {
+        "models.ProductResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/models.CartSummary"
+                    "$ref": "#/definitions/models.Product"
                 }
             }
         },
-        "models.CheckoutOrderResponse": {
+        "models.ProductsResponse": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer",
-                    "example": 1
-                },
-                "payment_status": {
-                    "type": "string",
-                    "example": "pending"
-                },
-                "shipping": {
-                    "type": "number",
-                    "example": 5.99
-                },
-                "status": {
-                    "type": "string",
-                    "example": "pending"
-                },
-                "total": {
-                    "type": "number",
-                    "example": 292.64
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Product"
+                    }
                 }
             }
         },
-        "models.CheckoutPaymentResponse": {
+        "models.QuoteLine": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "type": "number",
-                    "example": 292.64
+                "cart_item_id": {
+                    "type": "integer"
                 },
-                "currency": {
-                    "type": "string",
-                    "example": "usd"
+                "discount": {
+                    "description": "this line's share of the discount",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
                 },
-                "payment_intent_id": {
-                    "type": "string",
-                    "example": "pi_3SZA4XIH7jU9U4pR0VhsSo69"
+                "product_id": {
+                    "type": "integer"
                 },
-                "status": {
-                    "type": "string",
-                    "example": "requires_payment_method"
-                }
-            }
-        },
-        "models.CheckoutResponse": {
-            "type": "object",
-            "properties": {
-                "client_secret": {
-                    "type": "string",
-                    "example": "pi_xxx_secret_yyy"
+                "quantity": {
+                    "type": "integer"
                 },
-                "message": {
-                    "type": "string",
-                    "example": "Checkout successful"
+                "subtotal": {
+                    "$ref": "#/definitions/models.Money"
                 },
-                "order": {
-                    "$ref": "#/definitions/models.CheckoutOrderResponse"
+                "tax": {
+                    "description": "on the line after its discount",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
                 },
-                "payment": {
-                    "$ref": "#/definitions/models.CheckoutPaymentResponse"
-                }
-            }
-        },
-        "models.CreateOrderRequest": {
-            "type": "object",
-            "properties": {
-                "shipping": {
-                    "type": "number",
-                    "minimum": 0,
-                    "example": 10
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "number"
+                },
+                "unit_price": {
+                    "$ref": "#/definitions/models.Money"
                 }
             }
         },
-        "models.ErrorResponse": {
+        "models.RecoveryCodesResponse": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string"
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k3j9d-x8a2m"
+                    ]
                 },
                 "message": {
                     "type": "string"
                 }
             }
         },
-        "models.HealthResponse": {
-            "type": "object",
-            "properties": {
-                "Message": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.LoginResponse": {
+        "models.RefreshTokenResponse": {
             "type": "object",
             "properties": {
                 "message": {
@@ -1743,20 +4937,16 @@ const docTemplate = `{
                 "tokens": {
                     "type": "object",
                     "additionalProperties": true
-                },
-                "user": {
-                    "type": "object",
-                    "additionalProperties": true
                 }
             }
         },
-        "models.Order": {
+        "models.Refund": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "amount": {
+                    "$ref": "#/definitions/models.Money"
                 },
-                "deleted_at": {
+                "created_at": {
                     "type": "string"
                 },
                 "id": {
@@ -1765,214 +4955,361 @@ const docTemplate = `{
                 "items": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.OrderItem"
+                        "$ref": "#/definitions/models.RefundItem"
                     }
                 },
-                "payment_intent_id": {
+                "order_id": {
+                    "type": "integer"
+                },
+                "payment_id": {
+                    "type": "integer"
+                },
+                "provider_refund_id": {
+                    "description": "nil until the provider confirms the refund",
                     "type": "string"
                 },
-                "payment_method": {
-                    "description": "card, cash, etc.",
+                "reason": {
                     "type": "string"
                 },
-                "payment_status": {
-                    "description": "pending, processing, succeeded, failed, cancelled",
+                "status": {
+                    "description": "pending, succeeded, failed",
                     "type": "string"
                 },
-                "shipping": {
-                    "type": "number"
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RefundItem": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/models.Money"
                 },
-                "status": {
-                    "description": "pending, processing, shipped, delivered, cancelled",
+                "created_at": {
                     "type": "string"
                 },
-                "total": {
-                    "type": "number"
+                "id": {
+                    "type": "integer"
                 },
-                "transaction_id": {
+                "order_item_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "refund_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RefundItemRequest": {
+            "type": "object",
+            "required": [
+                "order_item_id",
+                "quantity"
+            ],
+            "properties": {
+                "order_item_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 1
+                }
+            }
+        },
+        "models.RefundResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Refund"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RefundsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Refund"
+                    }
+                }
+            }
+        },
+        "models.RegisterResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
                     "type": "string"
                 },
-                "updated_at": {
-                    "type": "string"
+                "tokens": {
+                    "type": "object",
+                    "additionalProperties": true
                 },
                 "user": {
-                    "$ref": "#/definitions/models.User"
-                },
-                "user_id": {
-                    "type": "integer"
+                    "type": "object",
+                    "additionalProperties": true
                 }
             }
         },
-        "models.OrderItem": {
+        "models.ShippingMethod": {
             "type": "object",
             "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "express"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "deleted_at": {
+                    "type": "string"
                 },
-                "order": {
-                    "$ref": "#/definitions/models.Order"
+                "description": {
+                    "type": "string",
+                    "example": "Next business day"
                 },
-                "order_id": {
+                "disabled": {
+                    "description": "hides the method from shoppers",
+                    "type": "boolean"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "price": {
-                    "description": "Price at time of order",
-                    "type": "number"
+                "max_days": {
+                    "type": "integer",
+                    "example": 2
                 },
-                "product": {
-                    "$ref": "#/definitions/models.Product"
+                "min_days": {
+                    "description": "delivery estimate in business days",
+                    "type": "integer",
+                    "example": 1
                 },
-                "product_id": {
-                    "type": "integer"
+                "name": {
+                    "type": "string",
+                    "example": "Express"
                 },
-                "quantity": {
-                    "type": "integer"
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ShippingRate"
+                    }
                 },
                 "updated_at": {
                     "type": "string"
                 }
             }
         },
-        "models.OrderResponse": {
+        "models.ShippingMethodResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/models.Order"
+                    "$ref": "#/definitions/models.ShippingMethod"
                 },
                 "message": {
                     "type": "string"
                 }
             }
         },
-        "models.OrdersResponse": {
+        "models.ShippingMethodsResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Order"
+                        "$ref": "#/definitions/models.ShippingMethod"
                     }
                 }
             }
         },
-        "models.PaymentSuccessResponse": {
+        "models.ShippingOption": {
             "type": "object",
             "properties": {
-                "message": {
+                "code": {
                     "type": "string",
-                    "example": "Payment confirmed"
-                }
-            }
-        },
-        "models.Product": {
-            "type": "object",
-            "properties": {
-                "badge": {
-                    "type": "string"
-                },
-                "badge_color": {
-                    "type": "string"
-                },
-                "category": {
-                    "type": "string"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "deleted_at": {
-                    "type": "string"
+                    "example": "express"
                 },
                 "description": {
-                    "type": "string"
+                    "type": "string",
+                    "example": "Next business day"
                 },
-                "featured": {
-                    "type": "boolean"
+                "max_days": {
+                    "type": "integer",
+                    "example": 2
                 },
-                "id": {
-                    "type": "integer"
+                "method_id": {
+                    "type": "integer",
+                    "example": 2
                 },
-                "image": {
-                    "type": "string"
+                "min_days": {
+                    "type": "integer",
+                    "example": 1
                 },
                 "name": {
-                    "type": "string"
-                },
-                "original_price": {
-                    "type": "number"
+                    "type": "string",
+                    "example": "Express"
                 },
                 "price": {
-                    "type": "number"
-                },
-                "rating": {
-                    "type": "number"
-                },
-                "stock": {
-                    "type": "integer"
-                },
-                "updated_at": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.Money"
                 }
             }
         },
-        "models.ProductResponse": {
+        "models.ShippingOptionsResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/models.Product"
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ShippingOption"
+                    }
                 }
             }
         },
-        "models.ProductsResponse": {
+        "models.ShippingRate": {
             "type": "object",
             "properties": {
-                "data": {
+                "countries": {
+                    "description": "the zone, empty for everywhere else",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Product"
-                    }
+                        "type": "string"
+                    },
+                    "example": [
+                        "US",
+                        "CA"
+                    ]
+                },
+                "free_above": {
+                    "description": "subtotal from which shipping is free",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Money"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "max_weight": {
+                    "description": "grams, exclusive, 0 for no limit",
+                    "type": "integer",
+                    "example": 5000
+                },
+                "method_id": {
+                    "type": "integer"
+                },
+                "min_subtotal": {
+                    "$ref": "#/definitions/models.Money"
+                },
+                "min_weight": {
+                    "description": "grams",
+                    "type": "integer",
+                    "example": 0
+                },
+                "price": {
+                    "$ref": "#/definitions/models.Money"
                 }
             }
         },
-        "models.RefreshTokenResponse": {
+        "models.SuccessResponse": {
             "type": "object",
             "properties": {
+                "data": {},
                 "message": {
                     "type": "string"
+                }
+            }
+        },
+        "models.TOTPEnrolment": {
+            "type": "object",
+            "properties": {
+                "provisioning_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/E-commerce:test%40example.com?algorithm=SHA1\u0026digits=6\u0026issuer=E-commerce\u0026period=30\u0026secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
                 },
-                "tokens": {
-                    "type": "object",
-                    "additionalProperties": true
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
                 }
             }
         },
-        "models.RegisterResponse": {
+        "models.TOTPEnrolmentResponse": {
             "type": "object",
             "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.TOTPEnrolment"
+                },
                 "message": {
                     "type": "string"
+                }
+            }
+        },
+        "models.TaxRule": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "Electronics"
                 },
-                "tokens": {
-                    "type": "object",
-                    "additionalProperties": true
+                "country": {
+                    "type": "string",
+                    "example": "US"
                 },
-                "user": {
-                    "type": "object",
-                    "additionalProperties": true
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inclusive": {
+                    "description": "prices already include the tax, as in the EU",
+                    "type": "boolean"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 0.0725
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "models.SuccessResponse": {
+        "models.TaxRuleResponse": {
             "type": "object",
             "properties": {
-                "data": {},
+                "data": {
+                    "$ref": "#/definitions/models.TaxRule"
+                },
                 "message": {
                     "type": "string"
                 }
             }
         },
+        "models.TaxRulesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TaxRule"
+                    }
+                }
+            }
+        },
         "models.UpdateCartItemRequest": {
             "type": "object",
             "required": [
@@ -1998,6 +5335,22 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UpdateUserRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "admin"
+                    ],
+                    "example": "admin"
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "properties": {
@@ -2010,9 +5363,16 @@ const docTemplate = `{
                 "deleted_at": {
                     "type": "string"
                 },
+                "disabled": {
+                    "description": "disabled accounts can't log in or refresh",
+                    "type": "boolean"
+                },
                 "email": {
                     "type": "string"
                 },
+                "email_verified_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -2028,6 +5388,10 @@ const docTemplate = `{
                 "role": {
                     "type": "string"
                 },
+                "token_version": {
+                    "description": "bumped to invalidate every token issued so far",
+                    "type": "integer"
+                },
                 "updated_at": {
                     "type": "string"
                 },
@@ -2058,6 +5422,72 @@ const docTemplate = `{
                 }
             }
         },
+        "models.WebhookEvent": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "description": "Raw body as delivered",
+                    "type": "string"
+                },
+                "processed_at": {
+                    "type": "string"
+                },
+                "processing_started_at": {
+                    "description": "start of the current attempt, to spot ones that crashed",
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "processing, processed, failed",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookEventResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.WebhookEvent"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookEventsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookEvent"
+                    }
+                }
+            }
+        },
         "models.Wishlist": {
             "type": "object",
             "properties": {
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressHandler struct {
+	AddressServices *services.AddressServices
+}
+
+func NewAddressHandler(s *services.AddressServices) *AddressHandler {
+	return &AddressHandler{
+		AddressServices: s,
+	}
+}
+
+// GetAddresses godoc
+// @Summary      List addresses
+// @Description  Retrieve the authenticated user's address book, default address first
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.AddressesResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/addresses [get]
+func (h *AddressHandler) GetAddresses(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	addresses, err := h.AddressServices.GetAll(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": addresses})
+}
+
+// GetAddress godoc
+// @Summary      Get an address
+// @Description  Retrieve one of the authenticated user's addresses
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Address ID"
+// @Success      200  {object}  models.AddressResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/addresses/{id} [get]
+func (h *AddressHandler) GetAddress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid address ID",
+		})
+		return
+	}
+
+	address, err := h.AddressServices.GetByID(userID.(uint), uint(id))
+	if err != nil {
+		respondAddressError(c, err, "Failed to get address")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": address})
+}
+
+// CreateAddress godoc
+// @Summary      Add an address
+// @Description  Add an address to the authenticated user's address book. The first address, or one sent with is_default, becomes the default.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        address  body      models.Address  true  "Address"
+// @Success      201  {object}  models.AddressResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/addresses [post]
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	var address models.Address
+	if err := c.ShouldBindJSON(&address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid address data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.AddressServices.Create(userID.(uint), &address); err != nil {
+		respondAddressError(c, err, "Failed to add address")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Address added successfully",
+		"data":    address,
+	})
+}
+
+// UpdateAddress godoc
+// @Summary      Update an address
+// @Description  Replace one of the authenticated user's addresses. Orders already placed keep the address they were placed with.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true  "Address ID"
+// @Param        address  body      models.Address  true  "Address"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/addresses/{id} [put]
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid address ID",
+		})
+		return
+	}
+
+	var address models.Address
+	if err := c.ShouldBindJSON(&address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid address data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.AddressServices.Update(userID.(uint), uint(id), &address); err != nil {
+		respondAddressError(c, err, "Failed to update address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address updated successfully",
+	})
+}
+
+// DeleteAddress godoc
+// @Summary      Delete an address
+// @Description  Remove an address from the authenticated user's address book
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Address ID"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/addresses/{id} [delete]
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid address ID",
+		})
+		return
+	}
+
+	if err := h.AddressServices.Delete(userID.(uint), uint(id)); err != nil {
+		respondAddressError(c, err, "Failed to delete address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address deleted successfully",
+	})
+}
+
+// SetDefaultAddress godoc
+// @Summary      Set the default address
+// @Description  Make one of the authenticated user's addresses the default, used when checkout doesn't name one
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Address ID"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/addresses/{id}/default [put]
+func (h *AddressHandler) SetDefaultAddress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid address ID",
+		})
+		return
+	}
+
+	if err := h.AddressServices.SetDefault(userID.(uint), uint(id)); err != nil {
+		respondAddressError(c, err, "Failed to set default address")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Default address updated",
+	})
+}
+
+// respondAddressError writes the response for a failed address book call
+func respondAddressError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Address not found"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"message": message, "error": err.Error()})
+}
@@ -1,14 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/services"
 	"go-ecommerce-api/utils"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
+type AuthHandler struct {
+	AuthServices    *services.AuthServices
+	AccountServices *services.AccountServices
+	MFAServices     *services.MFAServices
+}
+
+func NewAuthHandler(s *services.AuthServices, accounts *services.AccountServices, mfa *services.MFAServices) *AuthHandler {
+	return &AuthHandler{
+		AuthServices:    s,
+		AccountServices: accounts,
+		MFAServices:     mfa,
+	}
+}
+
 type LoginPayload struct {
 	Email    string `json:"email" binding:"required,email,min=3" example:"test@example.com"`
 	Password string `json:"password" binding:"required,min=8" example:"password"`
@@ -20,18 +38,40 @@ type RegisterPayload struct {
 	Password string `json:"password" binding:"required,min=8" example:"password"`
 }
 
+type ForgotPasswordPayload struct {
+	Email string `json:"email" binding:"required,email" example:"test@example.com"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" binding:"required" example:"3f1c9a0e5b7d4c2a8e6f0b1d3c5a7e9f"`
+	Password string `json:"password" binding:"required,min=8" example:"new-password"`
+}
+
+type VerifyMFAPayload struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required" example:"123456"` // from the authenticator app, or a recovery code
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" binding:"required" example:"3f1c9a0e5b7d4c2a8e6f0b1d3c5a7e9f"`
+}
+
 // Login godoc
 // @Summary      Login user
-// @Description  Authenticate user and return access and refresh tokens
+// @Description  Authenticate user and return access and refresh tokens. Accounts with two-factor authentication get 202 with an mfa_token instead, to exchange at /auth/mfa/verify. Wrong passwords and unknown emails get the same 401; repeated failures slow down and then lock out the email or IP with a 429 and a Retry-After header.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        login body LoginPayload true "Login credentials"
 // @Success      200  {object}  models.LoginResponse
+// @Success      202  {object}  models.MFAChallengeResponse
 // @Failure      400  {object}  models.ErrorResponse
-// @Failure      404  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      429  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
 // @Router       /auth/login [post]
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
 	var LoginPayload LoginPayload
 
 	if err := c.ShouldBindBodyWithJSON(&LoginPayload); err != nil {
@@ -41,27 +81,65 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Get user service from gin context (injected via middleware)
-	userService := c.MustGet("userService").(services.UserServices)
-
-	user, err := userService.GetUserByEmail(LoginPayload.Email)
+	user, mfaRequired, err := h.AuthServices.Login(LoginPayload.Email, LoginPayload.Password, c.ClientIP())
 
+	var throttled *services.TooManyAttemptsError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"message": "Too many login attempts, please try again later",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Invalid email or password",
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"message": "User does not exista",
+		log.Printf("[error] login failed, got error %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Unable to log in",
 		})
 		return
 	}
 
-	if err := utils.CheckPassword(LoginPayload.Password, user.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid credentials",
-		})
+	if !mfaRequired {
+		h.startSession(c, user, false)
 		return
 	}
 
-	token, err := utils.GenerateTokenPair(user)
+	// The password was right; tokens wait for the second factor
+	if user.DeletedAt != nil || user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "Account is disabled",
+		})
+		return
+	}
+	challenge, err := h.MFAServices.Challenge(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Unable to generate token",
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Two-factor code required",
+		"mfa_required": true,
+		"mfa_token":    challenge,
+	})
+}
 
+// startSession logs the user in and writes the login response
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User, mfa bool) {
+	token, err := h.AuthServices.StartSession(user, c.Request.UserAgent(), c.ClientIP(), mfa)
+	if errors.Is(err, services.ErrAccountUnavailable) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "Account is disabled",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Unable to generate token",
@@ -78,18 +156,66 @@ func Login(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
 		"user": gin.H{
-			"id":    user.ID,
-			"name":  user.Name,
-			"email": user.Email,
-			"role":  user.Role,
+			"id":             user.ID,
+			"name":           user.Name,
+			"email":          user.Email,
+			"role":           user.Role,
+			"email_verified": user.EmailVerifiedAt != nil,
 		},
 		"tokens": token,
 	})
 }
 
+// VerifyMFA godoc
+// @Summary      Complete a two-factor login
+// @Description  Exchange the mfa_token from /auth/login and a code from the authenticator app, or a recovery code, for access and refresh tokens
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      VerifyMFAPayload  true  "MFA token and code"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      429  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var payload VerifyMFAPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, err := h.MFAServices.CompleteChallenge(payload.MFAToken, payload.Code, c.ClientIP())
+
+	var throttled *services.TooManyAttemptsError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many login attempts, please try again later"})
+		return
+	case errors.Is(err, services.ErrInvalidMFAChallenge), errors.Is(err, services.ErrMFANotEnabled):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Login expired, please log in again"})
+		return
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid two-factor code"})
+		return
+	case err != nil:
+		log.Printf("[error] two-factor login failed, got error %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Unable to log in"})
+		return
+	}
+
+	h.startSession(c, user, true)
+}
+
 // Register godoc
 // @Summary      Register new user
-// @Description  Create a new user account
+// @Description  Create a new user account and email a link to verify the address
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -98,7 +224,7 @@ func Login(c *gin.Context) {
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /auth/register [post]
-func Register(c *gin.Context) {
+func (h *AuthHandler) Register(c *gin.Context) {
 	var RegisterPayload RegisterPayload
 
 	if err := c.ShouldBindBodyWithJSON(&RegisterPayload); err != nil {
@@ -125,18 +251,19 @@ func Register(c *gin.Context) {
 		Role:     "user", // default role
 	}
 
-	// Get user service from gin context (injected via middleware)
-	userService := c.MustGet("userService").(services.UserServices)
-
-	if err := userService.Create(user); err != nil {
+	if err := h.AuthServices.Users.Create(user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Failed to create user",
 		})
 		return
 	}
 
+	if err := h.AccountServices.SendVerificationEmail(user); err != nil {
+		log.Printf("[error] failed to send verification email to user %d, got error %v", user.ID, err)
+	}
+
 	// Generate tokens for the new user
-	token, err := utils.GenerateTokenPair(user)
+	token, err := h.AuthServices.StartSession(user, c.Request.UserAgent(), c.ClientIP(), false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Failed to generate tokens",
@@ -153,10 +280,11 @@ func Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user": gin.H{
-			"id":    user.ID,
-			"name":  user.Name,
-			"email": user.Email,
-			"role":  user.Role,
+			"id":             user.ID,
+			"name":           user.Name,
+			"email":          user.Email,
+			"role":           user.Role,
+			"email_verified": user.EmailVerifiedAt != nil,
 		},
 		"tokens": token,
 	})
@@ -164,7 +292,7 @@ func Register(c *gin.Context) {
 
 // RefreshToken godoc
 // @Summary      Refresh access token
-// @Description  Generate new access and refresh tokens using refresh token
+// @Description  Exchange a refresh token for new access and refresh tokens. Each refresh token works once; using one again revokes its session.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -172,7 +300,7 @@ func Register(c *gin.Context) {
 // @Failure      401  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Router       /auth/refresh [get]
-func RefreshToken(c *gin.Context) {
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	// Get refresh token from cookie or header
 	// refreshToken, err := c.Cookie("ref_token")
 	// if err != nil {
@@ -181,28 +309,26 @@ func RefreshToken(c *gin.Context) {
 	// }
 
 	refreshToken := c.GetHeader("Authorization")
-	if refreshToken == "" {
+	token, found := strings.CutPrefix(refreshToken, "Bearer ")
+	if !found || token == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token not found"})
 		return
 	}
-	token := strings.Split(refreshToken, " ")[1]
 
-	// Validate refresh token
-	claims, err := utils.ValidateRefreshToken(token)
-	if err != nil {
+	newTokenPair, err := h.AuthServices.Refresh(token)
+	switch {
+	case errors.Is(err, services.ErrRefreshTokenReused):
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token was already used, please log in again"})
+		return
+	case errors.Is(err, services.ErrAccountUnavailable):
+		clearAuthCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Account is disabled"})
+		return
+	case errors.Is(err, services.ErrInvalidRefreshToken), errors.Is(err, services.ErrSessionRevoked):
 		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
 		return
-	}
-
-	// New TokenPair
-	user := &models.User{
-		ID:    claims.UserID,
-		Email: claims.Email,
-		Role:  claims.Role,
-	}
-
-	newTokenPair, err := utils.GenerateTokenPair(user)
-	if err != nil {
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate tokens"})
 		return
 	}
@@ -216,3 +342,203 @@ func RefreshToken(c *gin.Context) {
 		"tokens":  newTokenPair,
 	})
 }
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the current session. Its access and refresh tokens stop working.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, exists := c.Get("sessionID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	if err := h.AuthServices.Logout(sessionID.(string)); err != nil {
+		log.Printf("[error] failed to revoke session %s, got error %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to log out"})
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll godoc
+// @Summary      Logout everywhere
+// @Description  Revoke every session of the authenticated user, on all devices
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	if err := h.AuthServices.LogoutAll(userID.(uint)); err != nil {
+		log.Printf("[error] failed to revoke sessions of user %d, got error %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to log out"})
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out of all sessions",
+	})
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset link. The response is the same whether or not the email is registered.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      ForgotPasswordPayload  true  "Account email"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Router       /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var payload ForgotPasswordPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	h.AccountServices.RequestPasswordReset(payload.Email)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token from a reset link. All of the user's sessions are logged out.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      ResetPasswordPayload  true  "Reset token and new password"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var payload ResetPasswordPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	err := h.AccountServices.ResetPassword(payload.Token, payload.Password)
+	if errors.Is(err, services.ErrInvalidUserToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired reset link"})
+		return
+	}
+	if err != nil {
+		log.Printf("[error] password reset failed, got error %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to reset password"})
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully, please log in again",
+	})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  Confirm the user's email with the token from a verification link
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      VerifyEmailPayload  true  "Verification token"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var payload VerifyEmailPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	err := h.AccountServices.VerifyEmail(payload.Token)
+	if errors.Is(err, services.ErrInvalidUserToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired verification link"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to verify email", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Email the authenticated user a new verification link. Earlier links stop working.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	err := h.AccountServices.ResendVerificationEmail(userID.(uint))
+	if errors.Is(err, services.ErrEmailAlreadyVerified) {
+		c.JSON(http.StatusConflict, gin.H{"message": "Email is already verified"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to send verification email", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Verification email sent",
+	})
+}
+
+// clearAuthCookies expires the token cookies
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie("acc_token", "", -1, "/", "localhost", false, true)
+	c.SetCookie("ref_token", "", -1, "/", "localhost", false, true)
+}
@@ -1,9 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/services"
-	"go-ecommerce-api/utils"
 	"net/http"
 	"strconv"
 
@@ -22,12 +22,17 @@ func NewCartHandler(s *services.CartServices) *CartHandler {
 
 // GetCart godoc
 // @Summary      Get user's cart
-// @Description  Retrieve the authenticated user's shopping cart with calculated totals
+// @Description  Retrieve the authenticated user's shopping cart with calculated totals. Tax and shipping are worked out for the given destination, with the cheapest shipping method unless one is chosen.
 // @Tags         cart
 // @Accept       json
 // @Produce      json
+// @Param        country             query     string  false  "Destination country (ISO 3166-1 alpha-2)"
+// @Param        state               query     string  false  "Destination state or region"
+// @Param        shipping_method_id  query     int     false  "Shipping method ID"
 // @Success      200  {object}  models.CartSummaryResponse
+// @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /cart [get]
@@ -38,15 +43,30 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 		return
 	}
 
-	cart, err := h.CartServices.GetCartByUserID(userID.(uint))
+	var query models.CartQuery
+	c.ShouldBindQuery(&query)
+
+	summary, err := h.CartServices.GetCartSummary(userID.(uint), query.Destination, query.ShippingMethodID)
+	if errors.Is(err, services.ErrShippingUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Shipping method is not available",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, models.ErrCurrencyMismatch) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "Cart contains items priced in different currencies",
+			"error":   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
-
-	summary := utils.CalculateCartTotals(cart)
 	c.JSON(http.StatusOK, gin.H{"data": summary})
 }
 
@@ -112,9 +132,16 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 // @Success      200  {object}  models.SuccessResponse
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /cart/items/{id} [put]
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -132,7 +159,11 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 
-	if err := h.CartServices.UpdateItemQuantity(uint(id), req.Quantity); err != nil {
+	if err := h.CartServices.UpdateItemQuantity(userID.(uint), uint(id), req.Quantity); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Cart item not found"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Failed to update cart item",
 			"error":   err.Error(),
@@ -155,9 +186,16 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 // @Success      200  {object}  models.SuccessResponse
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /cart/items/{id} [delete]
 func (h *CartHandler) RemoveFromCart(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -166,7 +204,11 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 		return
 	}
 
-	if err := h.CartServices.RemoveItem(uint(id)); err != nil {
+	if err := h.CartServices.RemoveItem(userID.(uint), uint(id)); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Cart item not found"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Failed to remove item from cart",
 			"error":   err.Error(),
@@ -216,3 +258,159 @@ func (h *CartHandler) ClearCart(c *gin.Context) {
 		"message": "Cart cleared successfully",
 	})
 }
+
+// ApplyCoupon godoc
+// @Summary      Apply a coupon
+// @Description  Apply a coupon code to the user's cart. Returns the cart summary with the discount line.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        request             body      models.ApplyCouponRequest  true   "Coupon code"
+// @Param        country             query     string                     false  "Destination country (ISO 3166-1 alpha-2)"
+// @Param        state               query     string                     false  "Destination state or region"
+// @Param        shipping_method_id  query     int                        false  "Shipping method ID"
+// @Success      200  {object}  models.CartSummaryResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /cart/coupon [post]
+func (h *CartHandler) ApplyCoupon(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	var req models.ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	var query models.CartQuery
+	c.ShouldBindQuery(&query)
+
+	summary, err := h.CartServices.ApplyCoupon(userID.(uint), req.Code, query.Destination, query.ShippingMethodID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCouponUnavailable), errors.Is(err, models.ErrCouponNotApplicable):
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Coupon can't be applied", "error": err.Error()})
+		case errors.Is(err, services.ErrShippingUnavailable):
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Shipping method is not available", "error": err.Error()})
+		case errors.Is(err, models.ErrCurrencyMismatch):
+			c.JSON(http.StatusConflict, gin.H{"message": "Cart contains items priced in different currencies", "error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon applied",
+		"data":    summary,
+	})
+}
+
+// RemoveCoupon godoc
+// @Summary      Remove the coupon
+// @Description  Remove the coupon applied to the user's cart
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /cart/coupon [delete]
+func (h *CartHandler) RemoveCoupon(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	if err := h.CartServices.RemoveCoupon(userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to remove coupon",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon removed",
+	})
+}
+
+// GetShippingOptions godoc
+// @Summary      List shipping options
+// @Description  List the shipping methods that can deliver the user's cart to the destination, cheapest first, priced by the server
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        country  query     string  false  "Destination country (ISO 3166-1 alpha-2)"
+// @Param        state    query     string  false  "Destination state or region"
+// @Success      200  {object}  models.ShippingOptionsResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /cart/shipping-options [get]
+func (h *CartHandler) GetShippingOptions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	var destination models.Destination
+	c.ShouldBindQuery(&destination)
+
+	options, err := h.CartServices.GetShippingOptions(userID.(uint), destination)
+	if errors.Is(err, models.ErrCurrencyMismatch) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "Cart contains items priced in different currencies",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": options})
+}
+
+// ValidateCart godoc
+// @Summary      Validate cart
+// @Description  Check the user's cart against the current products. Warns about products that are no longer available, quantities above stock and prices that changed since the items were added. Checkout runs the same check and refuses a cart with warnings, except price changes it was told to accept.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.CartValidationResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /cart/validate [post]
+func (h *CartHandler) ValidateCart(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	validation, err := h.CartServices.ValidateCart(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": validation})
+}
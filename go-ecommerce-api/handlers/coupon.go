@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CouponHandler struct {
+	CouponServices *services.CouponServices
+}
+
+func NewCouponHandler(s *services.CouponServices) *CouponHandler {
+	return &CouponHandler{
+		CouponServices: s,
+	}
+}
+
+// GetCoupons godoc
+// @Summary      List coupons (Admin)
+// @Description  Retrieve all coupons with how often they were redeemed (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.CouponsResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/coupons [get]
+func (h *CouponHandler) GetCoupons(c *gin.Context) {
+	coupons, err := h.CouponServices.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": coupons})
+}
+
+// GetCoupon godoc
+// @Summary      Get a coupon (Admin)
+// @Description  Retrieve a coupon by ID (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Coupon ID"
+// @Success      200  {object}  models.CouponResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/coupons/{id} [get]
+func (h *CouponHandler) GetCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid coupon ID",
+		})
+		return
+	}
+
+	coupon, err := h.CouponServices.GetByID(uint(id))
+	if err != nil {
+		respondCouponError(c, err, "Failed to get coupon")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": coupon})
+}
+
+// CreateCoupon godoc
+// @Summary      Create a coupon (Admin)
+// @Description  Create a coupon code (Admin only). Types: percentage, fixed_amount, free_shipping, buy_x_get_y
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        coupon  body      models.Coupon  true  "Coupon data"
+// @Success      201  {object}  models.CouponResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/coupons [post]
+func (h *CouponHandler) CreateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid coupon data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.CouponServices.Create(&coupon); err != nil {
+		respondCouponError(c, err, "Failed to create coupon")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Coupon created successfully",
+		"data":    coupon,
+	})
+}
+
+// UpdateCoupon godoc
+// @Summary      Update a coupon (Admin)
+// @Description  Replace a coupon's settings (Admin only). Its redemption count is kept.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Coupon ID"
+// @Param        coupon  body      models.Coupon  true  "Coupon data"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/coupons/{id} [put]
+func (h *CouponHandler) UpdateCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid coupon ID",
+		})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid coupon data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.CouponServices.Update(uint(id), &coupon); err != nil {
+		respondCouponError(c, err, "Failed to update coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon updated successfully",
+	})
+}
+
+// DeleteCoupon godoc
+// @Summary      Delete a coupon (Admin)
+// @Description  Delete a coupon by ID (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Coupon ID"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/coupons/{id} [delete]
+func (h *CouponHandler) DeleteCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid coupon ID",
+		})
+		return
+	}
+
+	if err := h.CouponServices.Delete(uint(id)); err != nil {
+		respondCouponError(c, err, "Failed to delete coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon deleted successfully",
+	})
+}
+
+// respondCouponError writes the response for a failed admin coupon call
+func respondCouponError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Coupon not found"})
+	case errors.Is(err, services.ErrCouponCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"message": message, "error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": message, "error": err.Error()})
+	}
+}
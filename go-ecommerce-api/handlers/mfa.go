@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFACodePayload struct {
+	Code string `json:"code" binding:"required" example:"123456"` // from the authenticator app, or a recovery code
+}
+
+type MFAHandler struct {
+	MFAServices *services.MFAServices
+}
+
+func NewMFAHandler(s *services.MFAServices) *MFAHandler {
+	return &MFAHandler{
+		MFAServices: s,
+	}
+}
+
+// GetMFAStatus godoc
+// @Summary      Get two-factor status
+// @Description  Tell whether the authenticated user has two-factor authentication enabled and how many recovery codes are left
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.MFAStatusResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/mfa [get]
+func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	status, err := h.MFAServices.Status(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// BeginTOTPEnrolment godoc
+// @Summary      Start two-factor enrolment
+// @Description  Create a TOTP secret for an authenticator app. Show provisioning_uri as a QR code, then confirm with a code from the app.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  models.TOTPEnrolmentResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/mfa/totp [post]
+func (h *MFAHandler) BeginTOTPEnrolment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	enrolment, err := h.MFAServices.BeginTOTPEnrolment(userID.(uint))
+	if err != nil {
+		respondMFAError(c, err, "Failed to start two-factor enrolment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Scan the code with your authenticator app and confirm with a code from it",
+		"data":    enrolment,
+	})
+}
+
+// ConfirmTOTPEnrolment godoc
+// @Summary      Confirm two-factor enrolment
+// @Description  Enable two-factor authentication with a code from the authenticator app. Returns recovery codes, which are only shown once. Log in again to get an admin session.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      MFACodePayload  true  "Code from the authenticator app"
+// @Success      200  {object}  models.RecoveryCodesResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTPEnrolment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	var payload MFACodePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.MFAServices.ConfirmTOTP(userID.(uint), payload.Code)
+	if err != nil {
+		respondMFAError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled, store these recovery codes somewhere safe",
+		"data":    codes,
+	})
+}
+
+// DisableTOTP godoc
+// @Summary      Disable two-factor authentication
+// @Description  Remove the authenticator app and recovery codes. Takes a current code or a recovery code. All of the user's sessions are logged out.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      MFACodePayload  true  "Code from the authenticator app, or a recovery code"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/mfa/totp [delete]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	var payload MFACodePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.MFAServices.DisableTOTP(userID.(uint), payload.Code); err != nil {
+		respondMFAError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled, please log in again",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace the recovery codes, used or not, with new ones. Takes a current code or a recovery code.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      MFACodePayload  true  "Code from the authenticator app, or a recovery code"
+// @Success      200  {object}  models.RecoveryCodesResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /user/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	var payload MFACodePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.MFAServices.RegenerateRecoveryCodes(userID.(uint), payload.Code)
+	if err != nil {
+		respondMFAError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Recovery codes regenerated, the old ones no longer work",
+		"data":    codes,
+	})
+}
+
+// respondMFAError writes the response for a failed two-factor call
+func respondMFAError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"message": "Two-factor authentication is already enabled"})
+	case errors.Is(err, services.ErrMFAEnrolmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "No two-factor enrolment in progress"})
+	case errors.Is(err, services.ErrInvalidMFACode), errors.Is(err, services.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"message": message, "error": err.Error()})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": message, "error": err.Error()})
+	}
+}
@@ -1,9 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/services"
-	"go-ecommerce-api/utils"
 	"net/http"
 	"strconv"
 
@@ -11,22 +11,20 @@ import (
 )
 
 type OrderHandler struct {
-	OrderServices   *services.OrderServices
-	CartServices    *services.CartServices
-	PaymentServices *services.PaymentService
+	OrderServices    *services.OrderServices
+	CheckoutServices *services.CheckoutServices
 }
 
-func NewOrderHandler(orderServ *services.OrderServices, cartServ *services.CartServices, paymentServ *services.PaymentService) *OrderHandler {
+func NewOrderHandler(orderServ *services.OrderServices, checkoutServ *services.CheckoutServices) *OrderHandler {
 	return &OrderHandler{
-		OrderServices:   orderServ,
-		CartServices:    cartServ,
-		PaymentServices: paymentServ,
+		OrderServices:    orderServ,
+		CheckoutServices: checkoutServ,
 	}
 }
 
 // GetOrderByID godoc
 // @Summary      Get order by ID
-// @Description  Retrieve a specific order by its ID
+// @Description  Retrieve one of the authenticated user's orders by its ID, including its status history
 // @Tags         orders
 // @Accept       json
 // @Produce      json
@@ -34,9 +32,17 @@ func NewOrderHandler(orderServ *services.OrderServices, cartServ *services.CartS
 // @Success      200  {object}  models.OrderResponse
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /orders/{id} [get]
 func (h *OrderHandler) GetOrderByID(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -45,13 +51,19 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 		return
 	}
 
-	order, err := h.OrderServices.GetOrderByID(uint(id))
-	if err != nil {
+	order, err := h.OrderServices.GetOrderByID(userID.(uint), uint(id))
+	if errors.Is(err, services.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"message": "Order not found",
 		})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"data": order})
 }
@@ -116,6 +128,8 @@ func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 // @Success      201  {object}  models.OrderResponse
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.CartChangedResponse
 // @Security     BearerAuth
 // @Router       /orders [post]
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
@@ -126,53 +140,24 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	}
 
 	var req models.CreateOrderRequest
-
 	if err := c.ShouldBindJSON(&req); err != nil {
-		req.Shipping = 0 // Default shipping
-	}
-
-	// Get user's cart
-	cart, err := h.CartServices.GetCartByUserID(userID.(uint))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		return
-	}
-
-	if len(cart.Items) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Cart is empty",
+			"message": "Invalid request data",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	// Calculate total
-	var total float64
-	var orderItems []models.OrderItem
-
-	for _, item := range cart.Items {
-		itemTotal := item.Product.Price * float64(item.Quantity)
-		total += itemTotal
-		orderItems = append(orderItems, models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Product.Price,
-		})
-	}
-
-	total += req.Shipping
-
-	// Create order
-	order := models.Order{
-		UserID:   userID.(uint),
-		Status:   "pending",
-		Total:    total,
-		Shipping: req.Shipping,
-		Items:    orderItems,
-	}
-
-	if err := h.OrderServices.Create(&order); err != nil {
+	order, err := h.CheckoutServices.CreateOrder(userID.(uint), services.CheckoutDetails{
+		ShippingMethodID:        req.ShippingMethodID,
+		ShippingAddressID:       req.ShippingAddressID,
+		BillingAddressID:        req.BillingAddressID,
+		AcknowledgePriceChanges: req.AcknowledgePriceChanges,
+	})
+	if err != nil {
+		if respondCheckoutError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Failed to create order",
 			"error":   err.Error(),
@@ -180,9 +165,6 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Clear cart after order creation
-	h.CartServices.ClearCart(cart.ID)
-
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Order created successfully",
 		"data":    order,
@@ -191,7 +173,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 
 // UpdateOrderStatus godoc
 // @Summary      Update order status (Admin)
-// @Description  Update the status of an order (Admin only)
+// @Description  Move an order along pending → processing → shipped → delivered, or to cancelled/returned (Admin only). Cancelling or expiring voids or refunds the payment and releases the order's stock.
 // @Tags         admin
 // @Accept       json
 // @Produce      json
@@ -199,6 +181,8 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 // @Param        request  body      models.UpdateOrderStatusRequest  true  "Status update"
 // @Success      200  {object}  models.SuccessResponse
 // @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /admin/orders/{id}/status [put]
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
@@ -219,16 +203,90 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.OrderServices.UpdateStatus(uint(id), req.Status); err != nil {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	if err := h.OrderServices.UpdateStatus(uint(id), req.Status, adminID.(uint)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"message": "Order not found"})
+		case errors.Is(err, models.ErrInvalidOrderTransition),
+			errors.Is(err, services.ErrOrderNotCancellable):
+			c.JSON(http.StatusConflict, gin.H{
+				"message": "Order status cannot be changed",
+				"error":   err.Error(),
+			})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Failed to update order status",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order status updated successfully",
+	})
+}
+
+// CancelOrder godoc
+// @Summary      Cancel order
+// @Description  Cancel a pending or processing order. An unpaid payment is voided and a paid one is refunded.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Param        request  body      models.CancelOrderRequest  false  "Cancellation reason"
+// @Success      200  {object}  models.OrderResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /orders/{id}/cancel [post]
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Failed to update order status",
-			"error":   err.Error(),
+			"message": "Invalid order ID",
 		})
 		return
 	}
 
+	// The reason is optional
+	var req models.CancelOrderRequest
+	c.ShouldBindJSON(&req)
+
+	order, err := h.OrderServices.CancelOrder(userID.(uint), uint(id), req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"message": "Order not found"})
+		case errors.Is(err, services.ErrOrderNotCancellable):
+			c.JSON(http.StatusConflict, gin.H{"message": "Order can no longer be cancelled"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Failed to cancel order",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Order status updated successfully",
+		"message": "Order cancelled",
+		"data":    order,
 	})
 }
 
@@ -238,9 +296,12 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 // @Tags         orders
 // @Accept       json
 // @Produce      json
+// @Param        request  body      models.CheckoutRequest  true  "Shipping method and addresses"
 // @Success      201  {object}  models.CheckoutResponse
 // @Failure      400  {object}  models.ErrorResponse
 // @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.CartChangedResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /orders/checkout [post]
@@ -251,83 +312,118 @@ func (h *OrderHandler) Checkout(c *gin.Context) {
 		return
 	}
 
-	// Get user's cart
-	cart, err := h.CartServices.GetCartByUserID(userID.(uint))
+	var req models.CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result, err := h.CheckoutServices.Checkout(userID.(uint), services.CheckoutDetails{
+		ShippingMethodID:        req.ShippingMethodID,
+		ShippingAddressID:       req.ShippingAddressID,
+		BillingAddressID:        req.BillingAddressID,
+		AcknowledgePriceChanges: req.AcknowledgePriceChanges,
+	})
 	if err != nil {
+		if respondCheckoutError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve cart",
+			"message": "Checkout failed",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	if len(cart.Items) == 0 {
+	order := result.Order
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Checkout successful",
+		"client_secret": result.PaymentIntent.ClientSecret,
+		"order": gin.H{
+			"id":             order.ID,
+			"status":         order.Status,
+			"total":          order.Total,
+			"shipping":       order.Shipping,
+			"payment_status": order.PaymentStatus,
+		},
+		"payment": gin.H{
+			"payment_intent_id": result.PaymentIntent.ID,
+			"amount":            result.Payment.Amount,
+			"status":            result.Payment.Status,
+		},
+	})
+}
+
+// respondCheckoutError writes the response for errors the client can act on.
+// Returns false if err should be reported as a generic failure.
+func respondCheckoutError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrEmptyCart) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Cart is empty",
 		})
-		return
+		return true
 	}
 
-	// Calculate total using cart utility
-	cartSummary := utils.CalculateCartTotals(cart)
+	if errors.Is(err, services.ErrShippingUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Shipping method is not available",
+			"error":   err.Error(),
+		})
+		return true
+	}
 
-	// Create order items
-	var orderItems []models.OrderItem
-	for _, item := range cart.Items {
-		orderItems = append(orderItems, models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Product.Price,
+	if errors.Is(err, services.ErrAddressRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "A shipping address is required",
+			"error":   err.Error(),
 		})
+		return true
 	}
 
-	// Create order
-	order := models.Order{
-		UserID:        userID.(uint),
-		Status:        "pending",
-		Total:         cartSummary.Total,
-		Shipping:      cartSummary.Shipping,
-		Items:         orderItems,
-		PaymentStatus: "pending",
+	if errors.Is(err, services.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Address not found",
+			"error":   err.Error(),
+		})
+		return true
 	}
 
-	createdOrder, err := h.OrderServices.CreateOrderWithPayment(&order)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Failed to create order",
+	if errors.Is(err, models.ErrCurrencyMismatch) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "Cart contains items priced in different currencies",
 			"error":   err.Error(),
 		})
-		return
+		return true
 	}
 
-	// Create payment intent
-	pi, payment, err := h.PaymentServices.CreatePaymentIntent(createdOrder.ID, cartSummary.Total, "usd")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Order created but payment initialization failed",
+	if errors.Is(err, services.ErrCouponUnavailable) || errors.Is(err, models.ErrCouponNotApplicable) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Coupon can't be applied, remove it from the cart to continue",
 			"error":   err.Error(),
-			"order":   createdOrder,
 		})
-		return
+		return true
 	}
 
-	// Clear cart after successful order and payment intent creation
-	h.CartServices.ClearCart(cart.ID)
+	var changedErr *models.CartChangedError
+	if errors.As(err, &changedErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message":  "Cart changed since items were added",
+			"warnings": changedErr.Warnings,
+		})
+		return true
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":       "Checkout successful",
-		"client_secret": pi.ClientSecret,
-		"order": gin.H{
-			"id":             createdOrder.ID,
-			"status":         createdOrder.Status,
-			"total":          createdOrder.Total,
-			"shipping":       createdOrder.Shipping,
-			"payment_status": createdOrder.PaymentStatus,
-		},
-		"payment": gin.H{
-			"payment_intent_id": pi.ID,
-			"amount":            payment.Amount,
-			"currency":          payment.Currency,
-			"status":            payment.Status,
-		},
-	})
+	var stockErr *models.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "Insufficient stock",
+			"items":   stockErr.Items,
+		})
+		return true
+	}
+
+	return false
 }
@@ -1,9 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"go-ecommerce-api/services"
-	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,21 +17,37 @@ func NewPaymentHandler(service *services.PaymentService) *PaymentHandler {
 	return &PaymentHandler{service: service}
 }
 
+// CreatePaymentIntentRequest only names the order. The amount and currency come
+// from the stored order, so a client can't choose what it is charged.
 type CreatePaymentIntentRequest struct {
-	OrderID  uint    `json:"order_id" binding:"required"`
-	Amount   float64 `json:"amount" binding:"required"`
-	Currency string  `json:"currency" binding:"required"`
+	OrderID uint `json:"order_id" binding:"required"`
 }
 
 // Create payment intent
 func (h *PaymentHandler) CreatePaymentIntent(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	var req CreatePaymentIntentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	pi, payment, err := h.service.CreatePaymentIntent(req.OrderID, req.Amount, req.Currency)
+	pi, payment, err := h.service.CreatePaymentIntent(userID.(uint), req.OrderID)
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	case errors.Is(err, services.ErrOrderAlreadyPaid),
+		errors.Is(err, services.ErrOrderNotPayable),
+		errors.Is(err, services.ErrAmountMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -45,9 +62,19 @@ func (h *PaymentHandler) CreatePaymentIntent(c *gin.Context) {
 
 // Confirm payment
 func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	paymentIntentID := c.Param("id")
 
-	pi, err := h.service.ConfirmPaymentIntent(paymentIntentID)
+	pi, err := h.service.ConfirmPaymentIntent(userID.(uint), paymentIntentID)
+	if errors.Is(err, services.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -61,9 +88,23 @@ func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
 
 // Cancel payment
 func (h *PaymentHandler) CancelPayment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	paymentIntentID := c.Param("id")
 
-	pi, err := h.service.CancelPaymentIntent(paymentIntentID)
+	pi, err := h.service.CancelPaymentIntent(userID.(uint), paymentIntentID)
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	case errors.Is(err, services.ErrOrderNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Order can no longer be cancelled"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -75,30 +116,21 @@ func (h *PaymentHandler) CancelPayment(c *gin.Context) {
 	})
 }
 
-// Webhook handler
-func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
-	payload, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+// Get payment status
+func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	signature := c.GetHeader("Stripe-Signature")
+	paymentIntentID := c.Param("id")
 
-	err = h.service.HandleWebhook(payload, signature)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	pi, err := h.service.GetPaymentIntent(userID.(uint), paymentIntentID)
+	if errors.Is(err, services.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
-}
-
-// Get payment status
-func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
-	paymentIntentID := c.Param("id")
-
-	pi, err := h.service.GetPaymentIntent(paymentIntentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -113,13 +145,23 @@ func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
 
 // Get payment by order ID
 func (h *PaymentHandler) GetPaymentByOrderID(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
 	orderID := c.Param("orderId")
 
-	payment, err := h.service.GetPaymentByOrderID(orderID)
-	if err != nil {
+	payment, err := h.service.GetPaymentByOrderID(userID.(uint), orderID)
+	if errors.Is(err, services.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, payment)
 }
@@ -143,27 +185,43 @@ func (h *PaymentHandler) GetUserPayments(c *gin.Context) {
 
 // Confirm payment success godoc
 // @Summary      Confirm payment success
-// @Description  Manually confirm payment success and update order status (for testing without webhooks)
+// @Description  Apply a payment of a pending order without waiting for the webhook. The provider must report the order's payment intent as succeeded.
 // @Tags         payments
 // @Accept       json
 // @Produce      json
 // @Param        orderId  path  string  true  "Order ID"
 // @Success      200  {object}  models.PaymentSuccessResponse
 // @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Security     BearerAuth
 // @Router       /payments/confirm-success/{orderId} [post]
 func (h *PaymentHandler) ConfirmPaymentSuccess(c *gin.Context) {
-	orderID := c.Param("orderId")
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
-	payment, err := h.service.GetPaymentByOrderID(orderID)
+	orderID, err := strconv.ParseUint(c.Param("orderId"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
 		return
 	}
 
 	// Update payment and order status
-	err = h.service.ConfirmPaymentSuccess(payment.OrderID)
+	err = h.service.ConfirmPaymentSuccess(userID.(uint), uint(orderID))
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	case errors.Is(err, services.ErrPaymentNotSucceeded),
+		errors.Is(err, services.ErrOrderAlreadyPaid),
+		errors.Is(err, services.ErrOrderNotPayable),
+		errors.Is(err, services.ErrAmountMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
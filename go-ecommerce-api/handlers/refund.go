@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefundHandler struct {
+	RefundServices *services.RefundServices
+}
+
+func NewRefundHandler(s *services.RefundServices) *RefundHandler {
+	return &RefundHandler{
+		RefundServices: s,
+	}
+}
+
+// CreateRefund godoc
+// @Summary      Refund an order (Admin)
+// @Description  Refund the listed order items, or everything not yet refunded when no items are given (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Param        request  body      models.CreateRefundRequest  true  "Refund request"
+// @Success      201  {object}  models.RefundResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/orders/{id}/refunds [post]
+func (h *RefundHandler) CreateRefund(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid order ID",
+		})
+		return
+	}
+
+	var req models.CreateRefundRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	lines := make([]services.RefundLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, services.RefundLine{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	refund, err := h.RefundServices.RefundOrder(uint(id), lines, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"message": "Order not found"})
+		case errors.Is(err, services.ErrNotRefundable):
+			c.JSON(http.StatusConflict, gin.H{"message": "Order has no refundable payment"})
+		case errors.Is(err, services.ErrInvalidRefund):
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid refund", "error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to refund order", "error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Refund issued",
+		"data":    refund,
+	})
+}
+
+// GetOrderRefunds godoc
+// @Summary      List refunds of an order (Admin)
+// @Description  Retrieve all refunds issued for an order (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Success      200  {object}  models.RefundsResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/orders/{id}/refunds [get]
+func (h *RefundHandler) GetOrderRefunds(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid order ID",
+		})
+		return
+	}
+
+	refunds, err := h.RefundServices.GetRefundsByOrderID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": refunds})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShippingHandler struct {
+	ShippingServices *services.ShippingServices
+}
+
+func NewShippingHandler(s *services.ShippingServices) *ShippingHandler {
+	return &ShippingHandler{
+		ShippingServices: s,
+	}
+}
+
+// GetShippingMethods godoc
+// @Summary      List shipping methods (Admin)
+// @Description  Retrieve all shipping methods with their rates, including disabled ones (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.ShippingMethodsResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/shipping-methods [get]
+func (h *ShippingHandler) GetShippingMethods(c *gin.Context) {
+	methods, err := h.ShippingServices.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": methods})
+}
+
+// GetShippingMethod godoc
+// @Summary      Get a shipping method (Admin)
+// @Description  Retrieve a shipping method with its rates by ID (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Shipping method ID"
+// @Success      200  {object}  models.ShippingMethodResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/shipping-methods/{id} [get]
+func (h *ShippingHandler) GetShippingMethod(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid shipping method ID",
+		})
+		return
+	}
+
+	method, err := h.ShippingServices.GetByID(uint(id))
+	if err != nil {
+		respondShippingError(c, err, "Failed to get shipping method")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": method})
+}
+
+// CreateShippingMethod godoc
+// @Summary      Create a shipping method (Admin)
+// @Description  Create a shipping method such as standard, express or pickup with its rates by zone, weight and subtotal (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        method  body      models.ShippingMethod  true  "Shipping method data"
+// @Success      201  {object}  models.ShippingMethodResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/shipping-methods [post]
+func (h *ShippingHandler) CreateShippingMethod(c *gin.Context) {
+	var method models.ShippingMethod
+	if err := c.ShouldBindJSON(&method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid shipping method data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.ShippingServices.Create(&method); err != nil {
+		respondShippingError(c, err, "Failed to create shipping method")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Shipping method created successfully",
+		"data":    method,
+	})
+}
+
+// UpdateShippingMethod godoc
+// @Summary      Update a shipping method (Admin)
+// @Description  Replace a shipping method's settings and all of its rates (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Shipping method ID"
+// @Param        method  body      models.ShippingMethod  true  "Shipping method data"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/shipping-methods/{id} [put]
+func (h *ShippingHandler) UpdateShippingMethod(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid shipping method ID",
+		})
+		return
+	}
+
+	var method models.ShippingMethod
+	if err := c.ShouldBindJSON(&method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid shipping method data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.ShippingServices.Update(uint(id), &method); err != nil {
+		respondShippingError(c, err, "Failed to update shipping method")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipping method updated successfully",
+	})
+}
+
+// DeleteShippingMethod godoc
+// @Summary      Delete a shipping method (Admin)
+// @Description  Delete a shipping method and its rates by ID (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Shipping method ID"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/shipping-methods/{id} [delete]
+func (h *ShippingHandler) DeleteShippingMethod(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid shipping method ID",
+		})
+		return
+	}
+
+	if err := h.ShippingServices.Delete(uint(id)); err != nil {
+		respondShippingError(c, err, "Failed to delete shipping method")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipping method deleted successfully",
+	})
+}
+
+// respondShippingError writes the response for a failed admin shipping method call
+func respondShippingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Shipping method not found"})
+	case errors.Is(err, services.ErrShippingCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"message": message, "error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": message, "error": err.Error()})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxHandler struct {
+	TaxServices *services.TaxServices
+}
+
+func NewTaxHandler(s *services.TaxServices) *TaxHandler {
+	return &TaxHandler{
+		TaxServices: s,
+	}
+}
+
+// GetTaxRules godoc
+// @Summary      List tax rules (Admin)
+// @Description  Retrieve the tax table (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.TaxRulesResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/tax-rules [get]
+func (h *TaxHandler) GetTaxRules(c *gin.Context) {
+	rules, err := h.TaxServices.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// CreateTaxRule godoc
+// @Summary      Create a tax rule (Admin)
+// @Description  Add a rate for a country, state and/or product category (Admin only). The most specific matching rule applies.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        rule  body      models.TaxRule  true  "Tax rule"
+// @Success      201  {object}  models.TaxRuleResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/tax-rules [post]
+func (h *TaxHandler) CreateTaxRule(c *gin.Context) {
+	var rule models.TaxRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid tax rule data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.TaxServices.Create(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to create tax rule",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tax rule created successfully",
+		"data":    rule,
+	})
+}
+
+// UpdateTaxRule godoc
+// @Summary      Update a tax rule (Admin)
+// @Description  Replace a tax rule (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int             true  "Tax rule ID"
+// @Param        rule  body      models.TaxRule  true  "Tax rule"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/tax-rules/{id} [put]
+func (h *TaxHandler) UpdateTaxRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid tax rule ID",
+		})
+		return
+	}
+
+	var rule models.TaxRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid tax rule data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.TaxServices.Update(uint(id), &rule); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Tax rule not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to update tax rule",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax rule updated successfully",
+	})
+}
+
+// DeleteTaxRule godoc
+// @Summary      Delete a tax rule (Admin)
+// @Description  Delete a tax rule by ID (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Tax rule ID"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/tax-rules/{id} [delete]
+func (h *TaxHandler) DeleteTaxRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid tax rule ID",
+		})
+		return
+	}
+
+	if err := h.TaxServices.Delete(uint(id)); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Tax rule not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to delete tax rule",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax rule deleted successfully",
+	})
+}
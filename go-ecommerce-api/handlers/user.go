@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -37,6 +39,53 @@ func (u *userHandlers) GetAllAdmin(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": users})
 }
 
+// SetRoleAdmin godoc
+// @Summary      Change a user's role (Admin)
+// @Description  Make a user an admin or a regular user. The user's tokens stop working, so they have to log in again (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                           true  "User ID"
+// @Param        request  body      models.UpdateUserRoleRequest  true  "New role"
+// @Success      200  {object}  models.SuccessResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/role [put]
+func (u *userHandlers) SetRoleAdmin(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request data",
+		})
+		return
+	}
+
+	if err := u.services.SetRole(uint(id), req.Role); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to update user role",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User role updated successfully",
+	})
+}
+
 // GetByID godoc
 // @Summary      Get current user
 // @Description  Retrieve the authenticated user's information
@@ -92,7 +141,7 @@ func (u *userHandlers) Create(c *gin.Context) {
 }
 // Update godoc
 // @Summary      Update current user
-// @Description  Update the authenticated user's information
+// @Description  Update the authenticated user's information. The role can only be changed by an admin.
 // @Tags         users
 // @Accept       json
 // @Produce      json
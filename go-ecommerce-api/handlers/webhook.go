@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-api/services"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	WebhookServices *services.WebhookServices
+}
+
+func NewWebhookHandler(s *services.WebhookServices) *WebhookHandler {
+	return &WebhookHandler{
+		WebhookServices: s,
+	}
+}
+
+// Webhook handler. Events that fail to apply get a 500 so the provider
+// delivers them again.
+func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	signature := c.GetHeader("Stripe-Signature")
+
+	err = h.WebhookServices.HandleWebhook(payload, signature)
+	if errors.Is(err, services.ErrInvalidWebhook) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetWebhookEvents godoc
+// @Summary      List webhook events (Admin)
+// @Description  Retrieve received payment webhook events, newest first, optionally filtered by status (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "processing, processed or failed"
+// @Success      200  {object}  models.WebhookEventsResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/webhooks [get]
+func (h *WebhookHandler) GetWebhookEvents(c *gin.Context) {
+	events, err := h.WebhookServices.ListEvents(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+// ReplayWebhookEvent godoc
+// @Summary      Replay a failed webhook event (Admin)
+// @Description  Process a failed webhook event, or one whose processing stalled for over 5 minutes, again from its stored payload (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Webhook event ID"
+// @Success      200  {object}  models.WebhookEventResponse
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/webhooks/{id}/replay [post]
+func (h *WebhookHandler) ReplayWebhookEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid webhook event ID",
+		})
+		return
+	}
+
+	event, err := h.WebhookServices.ReplayEvent(uint(id))
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"message": "Webhook event not found"})
+	case errors.Is(err, services.ErrWebhookNotReplayable):
+		c.JSON(http.StatusConflict, gin.H{"message": "Webhook event can't be replayed", "error": err.Error()})
+	case err != nil && event != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Replay failed", "error": err.Error(), "data": event})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Replay failed", "error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook event replayed", "data": event})
+	}
+}
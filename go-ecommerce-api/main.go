@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"go-ecommerce-api/config"
 	"go-ecommerce-api/database"
 	"go-ecommerce-api/router"
+	"log"
+	"os"
 
 	_ "go-ecommerce-api/docs" // This is important for swagger to work
 )
@@ -38,9 +44,52 @@ func main() {
 	}
 	defer db.Close()
 
-	r := router.SetUpRouter(db)
+	cfg := config.Load()
+	svc, err := router.NewServices(db, cfg)
+	if err != nil {
+		log.Fatalf("[error] %v", err)
+	}
+
+	// go run . reconcile
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		code := reconcile(svc, cfg, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
+	if cfg.ReconcileInterval > 0 {
+		go svc.Reconcile.Run(context.Background(), cfg.ReconcileInterval, cfg.ReconcileAfter)
+	}
+	if cfg.OrderExpiryInterval > 0 {
+		go svc.Expiry.Run(context.Background(), cfg.OrderExpiryInterval, cfg.OrderExpiryAfter)
+	}
+
+	r := router.SetUpRouter(svc)
 
 	port := "8080"
 	fmt.Printf("Server starting on port %s\n", port)
 	r.Run(":" + port)
 }
+
+// reconcile checks open payments against the provider once, prints the
+// discrepancy report as JSON and returns the process exit code
+func reconcile(svc *router.Services, cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	olderThan := flags.Duration("older-than", cfg.ReconcileAfter, "only check payments unchanged for at least this long")
+	flags.Parse(args)
+
+	report, err := svc.Reconcile.Reconcile(*olderThan)
+	if err != nil {
+		log.Printf("[error] payment reconciliation failed, got error %v", err)
+		return 1
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	out.Encode(report)
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
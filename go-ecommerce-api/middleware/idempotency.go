@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-ecommerce-api/database"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyTTL            = 24 * time.Hour
+	idempotencyInProgressTTL  = 2 * time.Minute
+	idempotencyMaxKeyLength   = 255
+	idempotencyStatusProgress = 0
+)
+
+// idempotencyRecord is what is kept in Redis for a key. Status is 0 while the
+// first request is still being handled.
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// responseRecorder copies the response body while it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes retried requests safe. A request carrying an
+// Idempotency-Key header runs once; repeats with the same key and body get the
+// stored response back, a repeat with a different body gets 422, and a repeat
+// that arrives while the first is still running gets 409. Keys are scoped to
+// the authenticated user, so it must run after RequireAuth. Server errors
+// aren't stored, so those requests can be retried with the same key.
+func Idempotency(redis database.RedisClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyMaxKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := c.Get("userID")
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, userID, body)
+
+		ctx := context.Background()
+		redisKey := fmt.Sprintf("idempotency:%v:%s", userID, key)
+
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Status: idempotencyStatusProgress})
+		acquired, err := redis.SetNX(ctx, redisKey, pending, idempotencyInProgressTTL)
+		if err != nil {
+			// Without Redis the request is handled as if it had no key
+			log.Printf("[error] idempotency check failed, got error %v", err)
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			replayIdempotentResponse(c, redis, redisKey, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			redis.Del(ctx, redisKey)
+			return
+		}
+
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err := redis.SetWithTTL(ctx, redisKey, record, idempotencyTTL); err != nil {
+			log.Printf("[error] failed to store idempotent response, got error %v", err)
+		}
+	}
+}
+
+// replayIdempotentResponse answers a request whose key has been seen before
+func replayIdempotentResponse(c *gin.Context, redis database.RedisClient, redisKey string, fingerprint string) {
+	defer c.Abort()
+
+	val, err := redis.Get(context.Background(), redisKey)
+	var record idempotencyRecord
+	if err != nil || json.Unmarshal([]byte(val), &record) != nil {
+		// The key expired between the two calls, let the client retry
+		c.JSON(http.StatusConflict, gin.H{"message": "A request with this Idempotency-Key is still being processed"})
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Idempotency-Key was already used for a different request"})
+		return
+	}
+
+	if record.Status == idempotencyStatusProgress {
+		c.JSON(http.StatusConflict, gin.H{"message": "A request with this Idempotency-Key is still being processed"})
+		return
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+}
+
+// requestFingerprint identifies a request by what it asks for, so a key reused
+// for another endpoint, user or body can be told apart from a retry
+func requestFingerprint(method string, path string, userID interface{}, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%v\n", method, path, userID)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
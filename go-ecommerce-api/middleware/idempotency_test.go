@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"go-ecommerce-api/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// stubRedis keeps the keys the idempotency middleware uses in memory
+type stubRedis struct {
+	database.RedisClient
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newStubRedis() *stubRedis {
+	return &stubRedis{values: make(map[string]string)}
+}
+
+func (r *stubRedis) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = string(value.([]byte))
+	return nil
+}
+
+func (r *stubRedis) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.values[key]; ok {
+		return false, nil
+	}
+	r.values[key] = string(value.([]byte))
+	return true, nil
+}
+
+func (r *stubRedis) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (r *stubRedis) Del(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.values, key)
+	return nil
+}
+
+// newIdempotentRouter serves POST /orders for user 1 behind the idempotency
+// middleware, answering with the given handler
+func newIdempotentRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/orders", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	}, Idempotency(newStubRedis()), handler)
+	return r
+}
+
+func post(r http.Handler, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	var calls atomic.Int32
+	r := newIdempotentRouter(func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"order": calls.Add(1)})
+	})
+
+	first := post(r, "key-1", `{"cart_id":1}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request got status %d, want %d", first.Code, http.StatusCreated)
+	}
+
+	retry := post(r, "key-1", `{"cart_id":1}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry got %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry is missing the %s header", IdempotentReplayedHeader)
+	}
+
+	if other := post(r, "key-2", `{"cart_id":1}`); other.Code != http.StatusCreated || other.Body.String() == first.Body.String() {
+		t.Errorf("new key got %d %s, want a new order", other.Code, other.Body)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyRejectsKeyReusedForAnotherBody(t *testing.T) {
+	var calls atomic.Int32
+	r := newIdempotentRouter(func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	post(r, "key-1", `{"cart_id":1}`)
+	if w := post(r, "key-1", `{"cart_id":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key got status %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyRejectsRequestInFlight(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	var calls atomic.Int32
+	r := newIdempotentRouter(func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-finish
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- post(r, "key-1", `{"cart_id":1}`)
+	}()
+	<-started
+
+	if w := post(r, "key-1", `{"cart_id":1}`); w.Code != http.StatusConflict {
+		t.Errorf("request in flight got status %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(finish)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("first request got status %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	if w := post(r, "key-1", `{"cart_id":1}`); w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry after the first request finished got status %d, want a replay", w.Code)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	r := newIdempotentRouter(func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	post(r, "key-1", `{"cart_id":1}`)
+	if w := post(r, "key-1", `{"cart_id":1}`); w.Code != http.StatusCreated {
+		t.Errorf("retry after a server error got status %d, want %d", w.Code, http.StatusCreated)
+	}
+}
@@ -1,7 +1,8 @@
 package middleware
 
 import (
-	"go-ecommerce-api/repositories"
+	"errors"
+	"go-ecommerce-api/services"
 	"go-ecommerce-api/utils"
 	"log"
 	"net/http"
@@ -46,11 +47,20 @@ func RequireAdmin() gin.HandlerFunc {
 			return
 		}
 
+		// Admin sessions must have been started with a second factor
+		if !c.GetBool("mfa") {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Admin access requires two-factor authentication, enable it and log in again"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
 
-func RequireAuth() gin.HandlerFunc {
+// RequireAuth accepts access tokens of sessions that haven't been revoked, of
+// users that still exist and are enabled
+func RequireAuth(auth *services.AuthServices) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authorization := c.GetHeader("Authorization")
 		if authorization == "" {
@@ -70,22 +80,34 @@ func RequireAuth() gin.HandlerFunc {
 
 		// validate
 		claims, err := utils.ValidateAccessToken(token)
-		if err != nil {
+		if err != nil || claims.SessionID == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid access token"})
 			c.Abort()
 			return
 		}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("userRole", claims.Role)
-		c.Next()
-	}
-}
+		user, err := auth.Authenticate(claims)
+		switch {
+		case errors.Is(err, services.ErrSessionRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Session has been revoked"})
+			c.Abort()
+			return
+		case errors.Is(err, services.ErrAccountUnavailable):
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Account is disabled"})
+			c.Abort()
+			return
+		case err != nil:
+			log.Printf("[error] session check failed, got error %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to check session"})
+			c.Abort()
+			return
+		}
 
-func UserServContext(s repositories.UserRepositories) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Set("userService", s)
+		// Set user info in context, with the role as it is now
+		c.Set("userID", user.ID)
+		c.Set("userRole", user.Role)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("mfa", claims.MFA)
 		c.Next()
 	}
 }
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// postalCodePatterns checks postal codes for the countries we know the format
+// of. Other countries only get a length and character check.
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+}
+
+var genericPostalCode = regexp.MustCompile(`^[A-Z\d][A-Z\d -]{1,9}$`)
+
+// Countries whose addresses need a state or province
+var stateRequired = map[string]bool{"US": true, "CA": true, "AU": true}
+
+// PostalAddress is where a parcel or an invoice goes
+type PostalAddress struct {
+	Name       string `json:"name" example:"Jane Doe"`
+	Company    string `json:"company,omitempty"`
+	Line1      string `json:"line1" example:"1 Market St"`
+	Line2      string `json:"line2,omitempty" example:"Suite 300"`
+	City       string `json:"city" example:"San Francisco"`
+	State      string `json:"state,omitempty" example:"CA"`
+	PostalCode string `json:"postal_code,omitempty" example:"94105"`
+	Country    string `json:"country" gorm:"size:2" example:"US"` // ISO 3166-1 alpha-2
+	Phone      string `json:"phone,omitempty" example:"+1 415 555 0100"`
+}
+
+// Address is an entry in a user's address book
+type Address struct {
+	ID     uint `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID uint `json:"user_id" gorm:"not null;index"`
+	PostalAddress
+	IsDefault bool       `json:"is_default"` // used when checkout doesn't name an address
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// Normalize trims the fields and upper-cases the codes
+func (a *PostalAddress) Normalize() {
+	for _, field := range []*string{&a.Name, &a.Company, &a.Line1, &a.Line2, &a.City, &a.Phone} {
+		*field = strings.TrimSpace(*field)
+	}
+	destination := a.Destination().Normalize()
+	a.Country = destination.Country
+	a.State = destination.State
+	a.PostalCode = strings.ToUpper(strings.TrimSpace(a.PostalCode))
+}
+
+// Validate validates the address
+func (a *PostalAddress) Validate() error {
+	if a.Name == "" {
+		return errors.New("recipient name is required")
+	}
+	if a.Line1 == "" {
+		return errors.New("address line1 is required")
+	}
+	if a.City == "" {
+		return errors.New("city is required")
+	}
+	if len(a.Country) != 2 {
+		return errors.New("country must be a 2-letter ISO 3166 code")
+	}
+	if stateRequired[a.Country] && a.State == "" {
+		return errors.New("state is required for " + a.Country + " addresses")
+	}
+
+	pattern, known := postalCodePatterns[a.Country]
+	switch {
+	case known && !pattern.MatchString(a.PostalCode):
+		return errors.New("invalid postal code for " + a.Country)
+	case !known && a.PostalCode != "" && !genericPostalCode.MatchString(a.PostalCode):
+		return errors.New("invalid postal code")
+	}
+	return nil
+}
+
+// Destination is where the address is for tax and shipping
+func (a *PostalAddress) Destination() Destination {
+	return Destination{Country: a.Country, State: a.State}
+}
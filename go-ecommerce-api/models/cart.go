@@ -1,24 +1,75 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// Cart warning codes
+const (
+	CartWarningUnavailable       = "product_unavailable" // the product was deleted
+	CartWarningInsufficientStock = "insufficient_stock"
+	CartWarningPriceChanged      = "price_changed" // since the item was added
+)
 
 type Cart struct {
-	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    uint       `json:"user_id" gorm:"not null"`
-	User      *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Items     []CartItem `json:"items" gorm:"foreignKey:CartID"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `json:"user_id" gorm:"not null"`
+	User       *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Items      []CartItem `json:"items" gorm:"foreignKey:CartID"`
+	CouponCode string     `json:"coupon_code,omitempty"` // applied with POST /cart/coupon
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 type CartItem struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	CartID    uint      `json:"cart_id" gorm:"not null"`
-	Cart      *Cart     `json:"cart,omitempty" gorm:"foreignKey:CartID"`
-	ProductID uint      `json:"product_id" gorm:"not null"`
-	Product   Product   `json:"product" gorm:"foreignKey:ProductID"`
-	Quantity  int       `json:"quantity" gorm:"default:1;not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CartID     uint      `json:"cart_id" gorm:"not null"`
+	Cart       *Cart     `json:"cart,omitempty" gorm:"foreignKey:CartID"`
+	ProductID  uint      `json:"product_id" gorm:"not null"`
+	Product    Product   `json:"product" gorm:"foreignKey:ProductID"`
+	Quantity   int       `json:"quantity" gorm:"default:1;not null"`
+	PriceAtAdd Money     `json:"price_at_add,omitzero" gorm:"embedded;embeddedPrefix:price_at_add_"` // what the customer saw when adding it
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CartWarning flags a cart line that can't be ordered as it is, or whose
+// price changed since it was added
+type CartWarning struct {
+	Code       string `json:"code" example:"price_changed"` // product_unavailable, insufficient_stock, price_changed
+	CartItemID uint   `json:"cart_item_id" example:"7"`
+	ProductID  uint   `json:"product_id" example:"1"`
+	Name       string `json:"name,omitempty" example:"Wireless Headphones"`
+	Message    string `json:"message" example:"Price changed from 89.99 USD to 99.99 USD"`
+	Requested  int    `json:"requested,omitempty" example:"3"`
+	Available  *int   `json:"available,omitempty" example:"1"`
+	OldPrice   *Money `json:"old_price,omitempty"`
+	NewPrice   *Money `json:"new_price,omitempty"`
+}
+
+// Blocking reports whether the cart has to change before the line can be
+// ordered. A price change only needs the customer's acknowledgement.
+func (w CartWarning) Blocking() bool {
+	return w.Code != CartWarningPriceChanged
+}
+
+// CartValidation is the result of checking a cart against the current products
+type CartValidation struct {
+	Valid    bool          `json:"valid"` // no warnings
+	Warnings []CartWarning `json:"warnings"`
+}
+
+// CartChangedError is returned by checkout when the cart has warnings the
+// customer hasn't acknowledged or that block the order
+type CartChangedError struct {
+	Warnings []CartWarning
+}
+
+func (e *CartChangedError) Error() string {
+	if len(e.Warnings) == 1 {
+		return fmt.Sprintf("cart item %d: %s", e.Warnings[0].CartItemID, e.Warnings[0].Message)
+	}
+	return fmt.Sprintf("%d cart items changed", len(e.Warnings))
 }
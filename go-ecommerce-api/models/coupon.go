@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Coupon types
+const (
+	CouponPercentage   = "percentage"    // PercentOff of the eligible items
+	CouponFixedAmount  = "fixed_amount"  // AmountOff the eligible items
+	CouponFreeShipping = "free_shipping" // waives the shipping charge
+	CouponBuyXGetY     = "buy_x_get_y"   // every BuyQuantity eligible units, the GetQuantity cheapest are free
+)
+
+// ErrCouponNotApplicable is returned when a coupon's conditions aren't met by the cart
+var ErrCouponNotApplicable = errors.New("coupon does not apply to this cart")
+
+// Coupon is an admin-managed promotion code. Category limits the discount to
+// products of that category; MinSubtotal is checked against the whole cart.
+type Coupon struct {
+	ID                    uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code                  string     `json:"code" gorm:"not null;uniqueIndex" example:"SUMMER10"`
+	Description           string     `json:"description" example:"10% off everything"`
+	Type                  string     `json:"type" gorm:"not null" example:"percentage"` // percentage, fixed_amount, free_shipping, buy_x_get_y
+	PercentOff            float64    `json:"percent_off,omitempty" example:"10"`
+	AmountOff             Money      `json:"amount_off,omitzero" gorm:"embedded;embeddedPrefix:amount_off_"`
+	BuyQuantity           int        `json:"buy_quantity,omitempty"`
+	GetQuantity           int        `json:"get_quantity,omitempty"`
+	Category              string     `json:"category,omitempty"`
+	MinSubtotal           Money      `json:"min_subtotal,omitzero" gorm:"embedded;embeddedPrefix:min_subtotal_"`
+	MaxRedemptions        int        `json:"max_redemptions,omitempty"`          // across all users, 0 for unlimited
+	MaxRedemptionsPerUser int        `json:"max_redemptions_per_user,omitempty"` // 0 for unlimited
+	TimesRedeemed         int        `json:"times_redeemed" gorm:"default:0"`
+	StartsAt              *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt             *time.Time `json:"expires_at,omitempty"`
+	Disabled              bool       `json:"disabled"` // stops new redemptions, e.g. to retire a code
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// NormalizeCouponCode makes codes case-insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate validates the coupon
+func (c *Coupon) Validate() error {
+	if c.Code == "" {
+		return errors.New("coupon code is required")
+	}
+
+	switch c.Type {
+	case CouponPercentage:
+		if c.PercentOff <= 0 || c.PercentOff > 100 {
+			return errors.New("percent_off must be between 0 and 100")
+		}
+	case CouponFixedAmount:
+		if c.AmountOff.Amount <= 0 {
+			return errors.New("amount_off must be greater than 0")
+		}
+		if !IsValidCurrency(c.AmountOff.Currency) {
+			return errors.New("amount_off currency must be a 3-letter ISO 4217 code")
+		}
+	case CouponFreeShipping:
+	case CouponBuyXGetY:
+		if c.BuyQuantity <= 0 || c.GetQuantity <= 0 {
+			return errors.New("buy_quantity and get_quantity must be greater than 0")
+		}
+	default:
+		return errors.New("invalid coupon type. Must be one of: percentage, fixed_amount, free_shipping, buy_x_get_y")
+	}
+
+	if c.Category != "" && !IsValidCategory(c.Category) {
+		return errors.New("invalid category. Must be one of: Electronics, Accessories, Home, Office")
+	}
+	if c.MinSubtotal.Amount < 0 {
+		return errors.New("min_subtotal must not be negative")
+	}
+	if c.MaxRedemptions < 0 || c.MaxRedemptionsPerUser < 0 {
+		return errors.New("redemption limits must not be negative")
+	}
+	if c.StartsAt != nil && c.ExpiresAt != nil && !c.ExpiresAt.After(*c.StartsAt) {
+		return errors.New("expires_at must be after starts_at")
+	}
+	return nil
+}
+
+// CouponRedemption records a coupon used on an order
+type CouponRedemption struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CouponID  uint      `json:"coupon_id" gorm:"not null;index:idx_coupon_redemptions_coupon_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;index:idx_coupon_redemptions_coupon_user"`
+	OrderID   uint      `json:"order_id" gorm:"not null;uniqueIndex"`
+	Code      string    `json:"code"`
+	Discount  Money     `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Discount is the coupon line of a cart summary. Shipping is the part of
+// Amount that waives the shipping charge.
+type Discount struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Amount      Money  `json:"amount"`
+	Shipping    Money  `json:"shipping,omitzero"`
+}
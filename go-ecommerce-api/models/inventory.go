@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stock reservation statuses
+const (
+	ReservationReserved  = "reserved"  // stock held for an unpaid order
+	ReservationCommitted = "committed" // order paid, stock permanently consumed
+	ReservationReleased  = "released"  // stock returned to the product
+)
+
+// StockReservation records stock taken from a product for an order so that it
+// can be given back if the order is never paid.
+type StockReservation struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	OrderID   uint      `json:"order_id" gorm:"not null;index"`
+	ProductID uint      `json:"product_id" gorm:"not null;index"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+	Status    string    `json:"status" gorm:"default:'reserved';index"` // reserved, committed, released
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StockShortage describes a single cart line that cannot be fulfilled
+type StockShortage struct {
+	ProductID uint   `json:"product_id" example:"1"`
+	Name      string `json:"name" example:"Wireless Headphones"`
+	Requested int    `json:"requested" example:"3"`
+	Available int    `json:"available" example:"1"`
+}
+
+// InsufficientStockError is returned when one or more items cannot be reserved
+type InsufficientStockError struct {
+	Items []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	if len(e.Items) == 1 {
+		item := e.Items[0]
+		return fmt.Sprintf("insufficient stock for product %d: requested %d, available %d", item.ProductID, item.Requested, item.Available)
+	}
+	return fmt.Sprintf("insufficient stock for %d products", len(e.Items))
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// TOTPFactor is a user's authenticator app. It is pending until the user
+// confirms enrolment with a first code, and only confirmed factors are asked
+// for at login.
+type TOTPFactor struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	Secret       string     `json:"-" gorm:"size:64;not null"` // base32
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"` // time step of the last accepted code, so codes can't be replayed
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// RecoveryCode is a single-use code that stands in for the authenticator app.
+// Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAStatus is what a user sees of their second factor
+type MFAStatus struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrolment is returned when enrolment starts. The URI is what the QR
+// code for authenticator apps encodes.
+type TOTPEnrolment struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/E-commerce:test%40example.com?algorithm=SHA1&digits=6&issuer=E-commerce&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultCurrency is the ISO 4217 code used when an amount doesn't name one
+const DefaultCurrency = "USD"
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// currencyExponents lists the currencies whose minor unit isn't a hundredth.
+// Every other currency has two decimals.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// CurrencyExponent returns the number of decimals in the currency's minor unit
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// IsValidCurrency checks if a currency looks like an ISO 4217 code
+func IsValidCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Money is an amount in the minor unit of its currency (cents for USD, yen
+// for JPY). Embed it with an embeddedPrefix so each amount is stored in a
+// <prefix>minor and a <prefix>currency column.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"column:minor;not null;default:0" example:"1999"`
+	Currency string `json:"currency" gorm:"column:currency;size:3;not null;default:'USD'" example:"USD"`
+}
+
+// NewMoney creates an amount from minor units
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// MoneyFromDecimal converts a decimal amount (19.99) to minor units, rounding
+// half away from zero to the currency's precision
+func MoneyFromDecimal(value float64, currency string) Money {
+	scale := math.Pow10(CurrencyExponent(currency))
+	return NewMoney(int64(math.Round(value*scale)), currency)
+}
+
+// Decimal returns the amount in major units, for display only
+func (m Money) Decimal() float64 {
+	return float64(m.Amount) / math.Pow10(CurrencyExponent(m.Currency))
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add sums two amounts of the same currency. A zero Money without a currency
+// takes the currency of the other operand, so totals can start from Money{}.
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.sameCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}, nil
+}
+
+// Sub subtracts an amount of the same currency
+func (m Money) Sub(o Money) (Money, error) {
+	currency, err := m.sameCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: currency}, nil
+}
+
+// Mul multiplies the amount by a quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// MulRate multiplies the amount by a rate (e.g. a tax rate), rounding the
+// result to the nearest minor unit
+func (m Money) MulRate(rate float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * rate)), Currency: m.Currency}
+}
+
+// UnmarshalJSON reads {"amount": 1999, "currency": "usd"}, normalizing the
+// currency code to upper case
+func (m *Money) UnmarshalJSON(data []byte) error {
+	type money Money
+	var v money
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = NewMoney(v.Amount, v.Currency)
+	return nil
+}
+
+// String formats the amount with the currency's precision, e.g. "19.99 USD"
+func (m Money) String() string {
+	return fmt.Sprintf("%.*f %s", CurrencyExponent(m.Currency), m.Decimal(), m.Currency)
+}
+
+func (m Money) sameCurrency(o Money) (string, error) {
+	switch {
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency, nil
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency, nil
+	case !strings.EqualFold(m.Currency, o.Currency):
+		return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return strings.ToUpper(m.Currency), nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoneyFromDecimal(t *testing.T) {
+	tests := []struct {
+		value    float64
+		currency string
+		want     int64
+	}{
+		{19.99, "USD", 1999},
+		{19.999, "usd", 2000},
+		{0.5, "USD", 50},
+		{-19.99, "USD", -1999},
+		{1500, "JPY", 1500},
+		{1500.4, "JPY", 1500},
+		{1500.5, "JPY", 1501},
+		{-1500.5, "JPY", -1501},
+		{1.234, "KWD", 1234},
+		{9.99, "XYZ", 999},
+	}
+	for _, tt := range tests {
+		got := MoneyFromDecimal(tt.value, tt.currency)
+		if got.Amount != tt.want {
+			t.Errorf("MoneyFromDecimal(%v, %s) = %d, want %d", tt.value, tt.currency, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRate(t *testing.T) {
+	tests := []struct {
+		amount int64
+		rate   float64
+		want   int64
+	}{
+		{1999, 0.2, 400},
+		{1000, 0.0825, 83},
+		{25, 0.1, 3},
+		{-25, 0.1, -3},
+		{24, 0.1, 2},
+		{1999, 0, 0},
+	}
+	for _, tt := range tests {
+		got := NewMoney(tt.amount, "USD").MulRate(tt.rate)
+		if got.Amount != tt.want || got.Currency != "USD" {
+			t.Errorf("%d * %v = %s, want %d USD", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(1999, "usd"), "19.99 USD"},
+		{NewMoney(5, "USD"), "0.05 USD"},
+		{NewMoney(1500, "JPY"), "1500 JPY"},
+		{NewMoney(1234, "KWD"), "1.234 KWD"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{"same currency", NewMoney(1999, "USD"), NewMoney(1, "USD"), NewMoney(2000, "USD"), nil},
+		{"case insensitive", NewMoney(100, "jpy"), Money{Amount: 50, Currency: "JPY"}, NewMoney(150, "JPY"), nil},
+		{"zero takes the other currency", Money{}, NewMoney(1500, "JPY"), NewMoney(1500, "JPY"), nil},
+		{"other zero", NewMoney(1500, "JPY"), Money{}, NewMoney(1500, "JPY"), nil},
+		{"mismatch", NewMoney(100, "USD"), NewMoney(100, "JPY"), Money{}, ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
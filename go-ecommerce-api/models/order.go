@@ -1,32 +1,129 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// Order statuses
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusReturned   = "returned"
+	OrderStatusExpired    = "expired" // never paid within the checkout window
+)
+
+// Who moved an order to a new status
+const (
+	StatusSourceCustomer = "customer"
+	StatusSourceAdmin    = "admin"
+	StatusSourcePayment  = "payment"
+	StatusSourceSystem   = "system"
+)
+
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderTransitions lists the statuses an order may move to from each status.
+// Statuses without an entry (cancelled, returned, expired) are final.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusCancelled, OrderStatusExpired},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered:  {OrderStatusReturned},
+}
+
+// IsValidOrderStatus checks if a status is one of the known order statuses
+func IsValidOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusPending, OrderStatusProcessing, OrderStatusShipped,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusReturned, OrderStatusExpired:
+		return true
+	}
+	return false
+}
+
+// CanTransitionOrderStatus checks if an order may move from one status to another
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
 
 type Order struct {
-	ID              uint        `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID          uint        `json:"user_id" gorm:"not null"`
-	User            User        `json:"user" gorm:"foreignKey:UserID"`
-	Status          string      `json:"status" gorm:"default:'pending'"` // pending, processing, shipped, delivered, cancelled
-	Total           float64     `json:"total" gorm:"not null"`
-	Items           []OrderItem `json:"items" gorm:"foreignKey:OrderID"`
-	Shipping        float64     `json:"shipping" gorm:"default:0"`
-	PaymentIntentID string      `json:"payment_intent_id,omitempty"`
-	PaymentStatus   string      `json:"payment_status" gorm:"default:'pending'"` // pending, processing, succeeded, failed, cancelled
-	PaymentMethod   string      `json:"payment_method,omitempty"`                // card, cash, etc.
-	TransactionID   string      `json:"transaction_id,omitempty"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
-	DeletedAt       *time.Time  `json:"deleted_at,omitempty" gorm:"index"`
+	ID               uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID           uint                 `json:"user_id" gorm:"not null"`
+	User             User                 `json:"user" gorm:"foreignKey:UserID"`
+	Status           string               `json:"status" gorm:"default:'pending'"`             // pending, processing, shipped, delivered, cancelled, returned, expired
+	Total            Money                `json:"total" gorm:"embedded;embeddedPrefix:total_"` // subtotal - discount + shipping + tax
+	Items            []OrderItem          `json:"items" gorm:"foreignKey:OrderID"`
+	Subtotal         Money                `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	Shipping         Money                `json:"shipping" gorm:"embedded;embeddedPrefix:shipping_"`
+	ShippingMethod   string               `json:"shipping_method,omitempty"`                                         // code of the method chosen at checkout
+	ShippingAddress  PostalAddress        `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_address_"` // copied at checkout, so address book edits don't rewrite it
+	BillingAddress   PostalAddress        `json:"billing_address" gorm:"embedded;embeddedPrefix:billing_address_"`
+	Tax              Money                `json:"tax" gorm:"embedded;embeddedPrefix:tax_"`                   // added on top of the prices
+	TaxIncluded      Money                `json:"tax_included" gorm:"embedded;embeddedPrefix:tax_included_"` // already part of tax-inclusive prices
+	Discount         Money                `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	ShippingDiscount Money                `json:"shipping_discount" gorm:"embedded;embeddedPrefix:shipping_discount_"` // part of Discount that waived shipping
+	CouponCode       string               `json:"coupon_code,omitempty"`
+	PaymentIntentID  string               `json:"payment_intent_id,omitempty"`
+	PaymentStatus    string               `json:"payment_status" gorm:"default:'pending'"` // pending, requires_action, processing, succeeded, failed, cancelled, partially_refunded, refunded, disputed
+	PaymentMethod    string               `json:"payment_method,omitempty"`                // card, cash, etc.
+	TransactionID    string               `json:"transaction_id,omitempty"`
+	StatusHistory    []OrderStatusHistory `json:"status_history,omitempty" gorm:"foreignKey:OrderID"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	DeletedAt        *time.Time           `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 type OrderItem struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	OrderID   uint      `json:"order_id" gorm:"not null"`
-	Order     Order     `json:"order" gorm:"foreignKey:OrderID"`
-	ProductID uint      `json:"product_id" gorm:"not null"`
-	Product   Product   `json:"product" gorm:"foreignKey:ProductID"`
-	Quantity  int       `json:"quantity" gorm:"not null"`
-	Price     float64   `json:"price" gorm:"not null"` // Price at time of order
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	OrderID      uint      `json:"order_id" gorm:"not null"`
+	Order        Order     `json:"order" gorm:"foreignKey:OrderID"`
+	ProductID    uint      `json:"product_id" gorm:"not null"`
+	Product      Product   `json:"product" gorm:"foreignKey:ProductID"`
+	Quantity     int       `json:"quantity" gorm:"not null"`
+	Price        Money     `json:"price" gorm:"embedded;embeddedPrefix:price_"`       // Price at time of order
+	Discount     Money     `json:"discount" gorm:"embedded;embeddedPrefix:discount_"` // this line's share of the order discount
+	Tax          Money     `json:"tax" gorm:"embedded;embeddedPrefix:tax_"`           // on the line after its discount, for invoicing
+	TaxRate      float64   `json:"tax_rate"`
+	TaxInclusive bool      `json:"tax_inclusive"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Total is what the customer paid for the line: its price less its share of
+// the discount, plus tax unless the price already includes it
+func (i OrderItem) Total() (Money, error) {
+	total := i.Price.Mul(i.Quantity)
+	var err error
+	if !i.Discount.IsZero() {
+		if total, err = total.Sub(i.Discount); err != nil {
+			return Money{}, err
+		}
+	}
+	if !i.TaxInclusive && !i.Tax.IsZero() {
+		if total, err = total.Add(i.Tax); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// OrderStatusHistory records one change of an order's status
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	OrderID    uint      `json:"order_id" gorm:"not null;index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	ChangedBy  *uint     `json:"changed_by,omitempty"` // user ID, nil for automatic changes
+	Source     string    `json:"source"`               // customer, admin, payment, system
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
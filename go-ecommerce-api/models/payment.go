@@ -7,14 +7,35 @@ type Payment struct {
 	OrderID         uint       `json:"order_id" gorm:"not null;index"`
 	Order           Order      `json:"order" gorm:"foreignKey:OrderID"`
 	PaymentIntentID string     `json:"payment_intent_id" gorm:"uniqueIndex"`
-	Amount          float64    `json:"amount" gorm:"not null"`
-	Currency        string     `json:"currency" gorm:"default:'usd'"`
-	Status          string     `json:"status" gorm:"default:'pending'"` // pending, processing, succeeded, failed, cancelled
+	Amount          Money      `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Status          string     `json:"status" gorm:"default:'pending'"` // pending, requires_action, processing, succeeded, failed, cancelled, partially_refunded, refunded, disputed
 	PaymentMethod   string     `json:"payment_method,omitempty"`        // card, cash, wallet, etc.
 	TransactionID   string     `json:"transaction_id,omitempty"`
 	FailureReason   string     `json:"failure_reason,omitempty"`
+	RefundedAmount  Money      `json:"refunded_amount" gorm:"embedded;embeddedPrefix:refunded_amount_"`
+	Refunds         []Refund   `json:"refunds,omitempty" gorm:"foreignKey:PaymentID"`
 	Metadata        *string    `json:"metadata,omitempty" gorm:"type:jsonb"` // Additional metadata as JSON
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 	DeletedAt       *time.Time `json:"deleted_at,omitempty" gorm:"index"`
 }
+
+// OpenPaymentStatuses are the statuses in which a payment can still change at
+// the provider. An order has at most one open payment at a time.
+var OpenPaymentStatuses = []string{
+	"pending",
+	"requires_payment_method",
+	"requires_confirmation",
+	"requires_action",
+	"processing",
+	"failed",
+}
+
+// CapturedPaymentStatuses are the statuses of a payment that took the
+// customer's money, whatever has been refunded or disputed since
+var CapturedPaymentStatuses = []string{
+	"succeeded",
+	"partially_refunded",
+	"refunded",
+	"disputed",
+}
@@ -0,0 +1,43 @@
+package models
+
+// PriceQuote is the itemized price of a cart. Total is Subtotal less the
+// discount plus Shipping and Tax; TaxIncluded is already part of Subtotal.
+type PriceQuote struct {
+	Lines          []QuoteLine     `json:"lines"`
+	Subtotal       Money           `json:"subtotal"`
+	Discount       *Discount       `json:"discount,omitempty"`
+	Shipping       Money           `json:"shipping"`
+	ShippingMethod *ShippingOption `json:"shipping_method,omitempty"` // the one Shipping is for, nil if none can ship the cart
+	Tax            Money           `json:"tax"`                       // added on top of the prices
+	TaxIncluded    Money           `json:"tax_included,omitzero"`     // already part of tax-inclusive prices
+	Total          Money           `json:"total"`
+}
+
+// QuoteLine is one cart line of a PriceQuote
+type QuoteLine struct {
+	CartItemID   uint    `json:"cart_item_id"`
+	ProductID    uint    `json:"product_id"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    Money   `json:"unit_price"`
+	Subtotal     Money   `json:"subtotal"`
+	Discount     Money   `json:"discount"` // this line's share of the discount
+	TaxRate      float64 `json:"tax_rate"`
+	Tax          Money   `json:"tax"` // on the line after its discount
+	TaxInclusive bool    `json:"tax_inclusive"`
+}
+
+// DiscountAmount is the whole discount, zero without a coupon
+func (q *PriceQuote) DiscountAmount() Money {
+	if q.Discount == nil {
+		return NewMoney(0, q.Subtotal.Currency)
+	}
+	return q.Discount.Amount
+}
+
+// ShippingDiscount is the part of the discount that waives shipping
+func (q *PriceQuote) ShippingDiscount() Money {
+	if q.Discount == nil || q.Discount.Shipping.IsZero() {
+		return NewMoney(0, q.Subtotal.Currency)
+	}
+	return q.Discount.Shipping
+}
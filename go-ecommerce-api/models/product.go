@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -34,8 +35,8 @@ type Product struct {
 	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
 	Name          string     `json:"name" gorm:"not null"`
 	Description   string     `json:"description"`
-	Price         float64    `json:"price" gorm:"not null"`
-	OriginalPrice *float64   `json:"original_price,omitempty"`
+	Price         Money      `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	OriginalPrice Money      `json:"original_price,omitzero" gorm:"embedded;embeddedPrefix:original_price_"`
 	Rating        float64    `json:"rating" gorm:"default:0"`
 	Image         string     `json:"image"`
 	Category      string     `json:"category" gorm:"not null"`
@@ -43,6 +44,7 @@ type Product struct {
 	BadgeColor    *string    `json:"badge_color,omitempty"`
 	Featured      bool       `json:"featured" gorm:"default:false"`
 	Stock         int        `json:"stock" gorm:"default:0"`
+	Weight        int        `json:"weight" gorm:"default:0" example:"350"` // grams, for shipping rates
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 	DeletedAt     *time.Time `json:"deleted_at,omitempty" gorm:"index"`
@@ -53,9 +55,18 @@ func (p *Product) Validate() error {
 	if p.Name == "" {
 		return errors.New("product name is required")
 	}
-	if p.Price <= 0 {
+	if p.Price.Amount <= 0 {
 		return errors.New("product price must be greater than 0")
 	}
+	if !IsValidCurrency(p.Price.Currency) {
+		return errors.New("product price currency must be a 3-letter ISO 4217 code")
+	}
+	if !p.OriginalPrice.IsZero() && !strings.EqualFold(p.OriginalPrice.Currency, p.Price.Currency) {
+		return errors.New("product original price must be in the same currency as its price")
+	}
+	if p.Weight < 0 {
+		return errors.New("product weight must not be negative")
+	}
 	if p.Category == "" {
 		return errors.New("product category is required")
 	}
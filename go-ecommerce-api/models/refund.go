@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type Refund struct {
+	ID               uint         `json:"id" gorm:"primaryKey;autoIncrement"`
+	PaymentID        uint         `json:"payment_id" gorm:"not null;index"`
+	OrderID          uint         `json:"order_id" gorm:"not null;index"`
+	ProviderRefundID *string      `json:"provider_refund_id,omitempty" gorm:"uniqueIndex"` // nil until the provider confirms the refund
+	Amount           Money        `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Status           string       `json:"status" gorm:"default:'pending'"` // pending, succeeded, failed
+	Reason           string       `json:"reason,omitempty"`
+	Items            []RefundItem `json:"items,omitempty" gorm:"foreignKey:RefundID"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// RefundItem is the part of a refund attributed to an order line
+type RefundItem struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	RefundID    uint      `json:"refund_id" gorm:"not null;index"`
+	OrderItemID uint      `json:"order_item_id" gorm:"not null;index"`
+	Quantity    int       `json:"quantity" gorm:"not null"`
+	Amount      Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	CreatedAt   time.Time `json:"created_at"`
+}
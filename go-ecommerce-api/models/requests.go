@@ -11,13 +11,36 @@ type UpdateCartItemRequest struct {
 	Quantity int `json:"quantity" binding:"required,min=1" example:"3"`
 }
 
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required" example:"SUMMER10"`
+}
+
+// CartQuery is how GET /cart and friends price the cart. Without a shipping
+// method the cheapest one available is used.
+type CartQuery struct {
+	Destination
+	ShippingMethodID uint `form:"shipping_method_id" example:"1"`
+}
+
+// CreateOrderRequest picks the shipping method, which the server prices, and
+// addresses from the user's address book. Without a shipping address the
+// default one is used; without a billing address the shipping one.
 type CreateOrderRequest struct {
-	Shipping float64 `json:"shipping" binding:"min=0" example:"10"`
+	ShippingMethodID        uint `json:"shipping_method_id" binding:"required" example:"1"`
+	ShippingAddressID       uint `json:"shipping_address_id" example:"3"` // decides the tax and shipping rates
+	BillingAddressID        uint `json:"billing_address_id" example:"4"`
+	AcknowledgePriceChanges bool `json:"acknowledge_price_changes"` // order at current prices after a price_changed warning
 }
 
 type CheckoutRequest struct {
-	Shipping float64 `json:"shipping" binding:"min=0" example:"10"`
-	Currency string  `json:"currency" binding:"required" example:"usd"`
+	ShippingMethodID        uint `json:"shipping_method_id" binding:"required" example:"1"`
+	ShippingAddressID       uint `json:"shipping_address_id" example:"3"` // decides the tax and shipping rates
+	BillingAddressID        uint `json:"billing_address_id" example:"4"`
+	AcknowledgePriceChanges bool `json:"acknowledge_price_changes"` // order at current prices after a price_changed warning
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason" example:"Ordered by mistake"`
 }
 
 type AddToWishlistRequest struct {
@@ -28,6 +51,21 @@ type UpdateOrderStatusRequest struct {
 	Status string `json:"status" binding:"required" example:"shipped"`
 }
 
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin" example:"admin"`
+}
+
 type BulkCreateProductsRequest struct {
-	Products []Product `json:"products" binding:"required,min=1,dive" example:"[{\"name\":\"Product 1\",\"description\":\"Description\",\"price\":99.99,\"category\":\"Electronics\",\"stock\":10}]"`
+	Products []Product `json:"products" binding:"required,min=1,dive" example:"[{\"name\":\"Product 1\",\"description\":\"Description\",\"price\":{\"amount\":9999,\"currency\":\"USD\"},\"category\":\"Electronics\",\"stock\":10}]"`
+}
+
+type RefundItemRequest struct {
+	OrderItemID uint `json:"order_item_id" binding:"required" example:"1"`
+	Quantity    int  `json:"quantity" binding:"required,min=1" example:"1"`
+}
+
+// CreateRefundRequest refunds the listed items, or the whole order when Items is empty
+type CreateRefundRequest struct {
+	Items  []RefundItemRequest `json:"items" binding:"omitempty,dive"`
+	Reason string              `json:"reason" example:"Damaged in transit"`
 }
@@ -24,19 +24,27 @@ type CartResponse struct {
 	Data Cart `json:"data"`
 }
 
+// CartSummary is the cart with its price quote
 type CartSummary struct {
 	Cart
-	Subtotal float64 `json:"subtotal"`
-	Shipping float64 `json:"shipping"`
-	Tax      float64 `json:"tax"`
-	TaxRate  float64 `json:"tax_rate"`
-	Total    float64 `json:"total"`
+	PriceQuote
+	CouponError string `json:"coupon_error,omitempty"` // why the applied coupon no longer counts
 }
 
 type CartSummaryResponse struct {
 	Data CartSummary `json:"data"`
 }
 
+type CartValidationResponse struct {
+	Data CartValidation `json:"data"`
+}
+
+// CartChangedResponse is returned by checkout when the cart needs review
+type CartChangedResponse struct {
+	Message  string        `json:"message" example:"Cart changed since items were added"`
+	Warnings []CartWarning `json:"warnings"`
+}
+
 type CartItemResponse struct {
 	Message string   `json:"message"`
 	Data    CartItem `json:"data"`
@@ -60,18 +68,17 @@ type WishlistCheckResponse struct {
 }
 
 type CheckoutOrderResponse struct {
-	ID            uint    `json:"id" example:"1"`
-	Status        string  `json:"status" example:"pending"`
-	Total         float64 `json:"total" example:"292.64"`
-	Shipping      float64 `json:"shipping" example:"5.99"`
-	PaymentStatus string  `json:"payment_status" example:"pending"`
+	ID            uint   `json:"id" example:"1"`
+	Status        string `json:"status" example:"pending"`
+	Total         Money  `json:"total"`
+	Shipping      Money  `json:"shipping"`
+	PaymentStatus string `json:"payment_status" example:"pending"`
 }
 
 type CheckoutPaymentResponse struct {
-	PaymentIntentID string  `json:"payment_intent_id" example:"pi_3SZA4XIH7jU9U4pR0VhsSo69"`
-	Amount          float64 `json:"amount" example:"292.64"`
-	Currency        string  `json:"currency" example:"usd"`
-	Status          string  `json:"status" example:"requires_payment_method"`
+	PaymentIntentID string `json:"payment_intent_id" example:"pi_3SZA4XIH7jU9U4pR0VhsSo69"`
+	Amount          Money  `json:"amount"`
+	Status          string `json:"status" example:"requires_payment_method"`
 }
 
 type CheckoutResponse struct {
@@ -81,6 +88,69 @@ type CheckoutResponse struct {
 	Payment      CheckoutPaymentResponse `json:"payment"`
 }
 
+type InsufficientStockResponse struct {
+	Message string          `json:"message" example:"Insufficient stock"`
+	Items   []StockShortage `json:"items"`
+}
+
+type RefundResponse struct {
+	Message string `json:"message"`
+	Data    Refund `json:"data"`
+}
+
+type RefundsResponse struct {
+	Data []Refund `json:"data"`
+}
+
+type CouponResponse struct {
+	Message string `json:"message"`
+	Data    Coupon `json:"data"`
+}
+
+type CouponsResponse struct {
+	Data []Coupon `json:"data"`
+}
+
+type AddressResponse struct {
+	Message string  `json:"message"`
+	Data    Address `json:"data"`
+}
+
+type AddressesResponse struct {
+	Data []Address `json:"data"`
+}
+
+type ShippingOptionsResponse struct {
+	Data []ShippingOption `json:"data"`
+}
+
+type ShippingMethodResponse struct {
+	Message string         `json:"message"`
+	Data    ShippingMethod `json:"data"`
+}
+
+type ShippingMethodsResponse struct {
+	Data []ShippingMethod `json:"data"`
+}
+
+type TaxRuleResponse struct {
+	Message string  `json:"message"`
+	Data    TaxRule `json:"data"`
+}
+
+type TaxRulesResponse struct {
+	Data []TaxRule `json:"data"`
+}
+
+type WebhookEventResponse struct {
+	Message string       `json:"message"`
+	Data    WebhookEvent `json:"data"`
+}
+
+type WebhookEventsResponse struct {
+	Data []WebhookEvent `json:"data"`
+}
+
 type PaymentSuccessResponse struct {
 	Message string `json:"message" example:"Payment confirmed"`
 }
@@ -105,6 +175,28 @@ type RegisterResponse struct {
 	Tokens  map[string]interface{} `json:"tokens"`
 }
 
+// MFAChallengeResponse is returned by login for accounts with two-factor
+// authentication, instead of tokens
+type MFAChallengeResponse struct {
+	Message     string `json:"message" example:"Two-factor code required"`
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type MFAStatusResponse struct {
+	Data MFAStatus `json:"data"`
+}
+
+type TOTPEnrolmentResponse struct {
+	Message string        `json:"message"`
+	Data    TOTPEnrolment `json:"data"`
+}
+
+type RecoveryCodesResponse struct {
+	Message string   `json:"message"`
+	Data    []string `json:"data" example:"k3j9d-x8a2m"`
+}
+
 type RefreshTokenResponse struct {
 	Message string                 `json:"message"`
 	Tokens  map[string]interface{} `json:"tokens"`
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session is one login of a user. Its refresh tokens form a family: every
+// refresh swaps the current token for a new one, and presenting a token that
+// was already swapped revokes the whole session.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:32"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	RefreshJTI string     `json:"-" gorm:"size:32;not null"` // the only refresh token that can still be used
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	MFA        bool       `json:"mfa"` // started with a second factor
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the session can still be used
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
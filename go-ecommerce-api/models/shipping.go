@@ -0,0 +1,164 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ShippingMethod is an admin-defined way of delivering an order, such as
+// standard, express or pickup. What it costs comes from its rates.
+type ShippingMethod struct {
+	ID          uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code        string         `json:"code" gorm:"not null;uniqueIndex" example:"express"`
+	Name        string         `json:"name" gorm:"not null" example:"Express"`
+	Description string         `json:"description" example:"Next business day"`
+	MinDays     int            `json:"min_days" example:"1"` // delivery estimate in business days
+	MaxDays     int            `json:"max_days" example:"2"`
+	Disabled    bool           `json:"disabled"` // hides the method from shoppers
+	Rates       []ShippingRate `json:"rates" gorm:"foreignKey:MethodID"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   *time.Time     `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// ShippingRate prices a shipping method for carts going to a zone within a
+// weight band. Rates listing the destination country beat catch-all rates.
+type ShippingRate struct {
+	ID          uint     `json:"id" gorm:"primaryKey;autoIncrement"`
+	MethodID    uint     `json:"method_id" gorm:"not null;index"`
+	Countries   []string `json:"countries" gorm:"serializer:json;type:text" example:"US,CA"` // the zone, empty for everywhere else
+	MinWeight   int      `json:"min_weight" example:"0"`                                     // grams
+	MaxWeight   int      `json:"max_weight" example:"5000"`                                  // grams, exclusive, 0 for no limit
+	MinSubtotal Money    `json:"min_subtotal,omitzero" gorm:"embedded;embeddedPrefix:min_subtotal_"`
+	Price       Money    `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	FreeAbove   Money    `json:"free_above,omitzero" gorm:"embedded;embeddedPrefix:free_above_"` // subtotal from which shipping is free
+}
+
+// ShippingOption is a shipping method priced for a cart
+type ShippingOption struct {
+	MethodID    uint   `json:"method_id" example:"2"`
+	Code        string `json:"code" example:"express"`
+	Name        string `json:"name" example:"Express"`
+	Description string `json:"description,omitempty" example:"Next business day"`
+	MinDays     int    `json:"min_days" example:"1"`
+	MaxDays     int    `json:"max_days" example:"2"`
+	Price       Money  `json:"price"`
+}
+
+// NormalizeShippingCode makes method codes case-insensitive
+func NormalizeShippingCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// Validate validates the shipping method and its rates
+func (m *ShippingMethod) Validate() error {
+	if m.Code == "" {
+		return errors.New("shipping method code is required")
+	}
+	if m.Name == "" {
+		return errors.New("shipping method name is required")
+	}
+	if m.MinDays < 0 || m.MaxDays < m.MinDays {
+		return errors.New("max_days must not be less than min_days")
+	}
+	if len(m.Rates) == 0 {
+		return errors.New("shipping method needs at least one rate")
+	}
+	for i := range m.Rates {
+		if err := m.Rates[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates the shipping rate
+func (r *ShippingRate) Validate() error {
+	for _, country := range r.Countries {
+		if len(country) != 2 {
+			return errors.New("countries must be 2-letter ISO 3166 codes")
+		}
+	}
+	if r.MinWeight < 0 || r.MaxWeight < 0 {
+		return errors.New("weights must not be negative")
+	}
+	if r.MaxWeight != 0 && r.MaxWeight <= r.MinWeight {
+		return errors.New("max_weight must be greater than min_weight")
+	}
+	if r.Price.Amount < 0 {
+		return errors.New("shipping price must not be negative")
+	}
+	if !IsValidCurrency(r.Price.Currency) {
+		return errors.New("shipping price currency must be a 3-letter ISO 4217 code")
+	}
+	for _, limit := range []Money{r.MinSubtotal, r.FreeAbove} {
+		if limit.Amount < 0 {
+			return errors.New("subtotal limits must not be negative")
+		}
+		if !limit.IsZero() && limit.Currency != r.Price.Currency {
+			return errors.New("subtotal limits must be in the same currency as the price")
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rate applies to a cart of the given weight and
+// subtotal shipped to country
+func (r *ShippingRate) Matches(country string, weight int, subtotal Money) bool {
+	if len(r.Countries) > 0 && !slices.Contains(r.Countries, country) {
+		return false
+	}
+	if weight < r.MinWeight || (r.MaxWeight != 0 && weight >= r.MaxWeight) {
+		return false
+	}
+	if subtotal.Currency != r.Price.Currency {
+		return false
+	}
+	return subtotal.Amount >= r.MinSubtotal.Amount
+}
+
+// Cost is the rate's price for a cart with the given subtotal
+func (r *ShippingRate) Cost(subtotal Money) Money {
+	if !r.FreeAbove.IsZero() && subtotal.Amount >= r.FreeAbove.Amount {
+		return NewMoney(0, r.Price.Currency)
+	}
+	return r.Price
+}
+
+// Quote prices the method for a cart, using the cheapest matching rate for
+// the destination's zone. ok is false if no rate matches.
+func (m *ShippingMethod) Quote(destination Destination, weight int, subtotal Money) (option ShippingOption, ok bool) {
+	var best *ShippingRate
+	for i := range m.Rates {
+		rate := &m.Rates[i]
+		if !rate.Matches(destination.Country, weight, subtotal) {
+			continue
+		}
+		if best != nil {
+			zoned, bestZoned := len(rate.Countries) > 0, len(best.Countries) > 0
+			if zoned != bestZoned {
+				if !zoned {
+					continue
+				}
+			} else if rate.Cost(subtotal).Amount >= best.Cost(subtotal).Amount {
+				continue
+			}
+		}
+		best = rate
+	}
+	if best == nil {
+		return ShippingOption{}, false
+	}
+
+	return ShippingOption{
+		MethodID:    m.ID,
+		Code:        m.Code,
+		Name:        m.Name,
+		Description: m.Description,
+		MinDays:     m.MinDays,
+		MaxDays:     m.MaxDays,
+		Price:       best.Cost(subtotal),
+	}, true
+}
@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestShippingMethodQuote(t *testing.T) {
+	usd := func(amount int64) Money { return NewMoney(amount, "USD") }
+	method := ShippingMethod{Code: "standard", Rates: []ShippingRate{
+		{Price: usd(1500)},
+		{Price: usd(1200), MaxWeight: 2000},
+		{Countries: []string{"US"}, Price: usd(599), FreeAbove: usd(10000), MaxWeight: 2000},
+		{Countries: []string{"US"}, Price: usd(1999), MinWeight: 2000},
+		{Countries: []string{"CA"}, Price: usd(1800)},
+		{Countries: []string{"CA"}, Price: usd(900), MinSubtotal: usd(5000)},
+	}}
+
+	tests := []struct {
+		name     string
+		country  string
+		weight   int
+		subtotal Money
+		want     int64
+		wantOK   bool
+	}{
+		{"zone rate beats a cheaper catch-all", "US", 500, usd(3000), 599, true},
+		{"free above the threshold", "US", 500, usd(10000), 0, true},
+		{"weight band upper bound is exclusive", "US", 2000, usd(3000), 1999, true},
+		{"cheapest catch-all elsewhere", "FR", 500, usd(3000), 1200, true},
+		{"heavy catch-all", "FR", 2500, usd(3000), 1500, true},
+		{"cheapest zone rate", "CA", 500, usd(6000), 900, true},
+		{"zone rate under its minimum subtotal", "CA", 500, usd(3000), 1800, true},
+		{"other currency", "US", 500, NewMoney(3000, "EUR"), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, ok := method.Quote(Destination{Country: tt.country}, tt.weight, tt.subtotal)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if ok && option.Price.Amount != tt.want {
+				t.Errorf("got price %s, want %d", option.Price, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Destination is where an order ships to, which decides the tax rates
+type Destination struct {
+	Country string `json:"country" form:"country" example:"US"` // ISO 3166-1 alpha-2
+	State   string `json:"state" form:"state" example:"CA"`
+}
+
+// Normalize upper-cases the codes so they match tax rules
+func (d Destination) Normalize() Destination {
+	return Destination{
+		Country: strings.ToUpper(strings.TrimSpace(d.Country)),
+		State:   strings.ToUpper(strings.TrimSpace(d.State)),
+	}
+}
+
+// TaxRule is one row of the tax table. Empty Country, State or Category match
+// anything; the most specific matching rule wins.
+type TaxRule struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Country   string     `json:"country" gorm:"size:2;index" example:"US"`
+	State     string     `json:"state" example:"CA"`
+	Category  string     `json:"category,omitempty" example:"Electronics"`
+	Rate      float64    `json:"rate" gorm:"not null" example:"0.0725"`
+	Inclusive bool       `json:"inclusive"` // prices already include the tax, as in the EU
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// Validate validates the tax rule
+func (r *TaxRule) Validate() error {
+	if r.Country != "" && len(r.Country) != 2 {
+		return errors.New("country must be a 2-letter ISO 3166 code")
+	}
+	if r.State != "" && r.Country == "" {
+		return errors.New("a state requires a country")
+	}
+	if r.Category != "" && !IsValidCategory(r.Category) {
+		return errors.New("invalid category. Must be one of: Electronics, Accessories, Home, Office")
+	}
+	if r.Rate < 0 || r.Rate >= 1 {
+		return errors.New("rate must be a fraction between 0 and 1")
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a line of the category shipped to d
+func (r *TaxRule) Matches(d Destination, category string) bool {
+	return (r.Country == "" || r.Country == d.Country) &&
+		(r.State == "" || r.State == d.State) &&
+		(r.Category == "" || r.Category == category)
+}
+
+// Specificity ranks matching rules: a state beats a country, and either beats a category
+func (r *TaxRule) Specificity() int {
+	score := 0
+	if r.Country != "" {
+		score += 4
+	}
+	if r.State != "" {
+		score += 2
+	}
+	if r.Category != "" {
+		score++
+	}
+	return score
+}
@@ -5,15 +5,18 @@ import (
 )
 
 type User struct {
-	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string     `json:"name"`
-	Email     string     `json:"email" gorm:"uniqueIndex"`
-	Password  string     `json:"-" gorm:"not null"`
-	Role      string     `json:"role" gorm:"default:'user'"`
-	Cart      *Cart      `json:"cart,omitempty" gorm:"foreignKey:UserID"`
-	Orders    []Order    `json:"orders,omitempty" gorm:"foreignKey:UserID"`
-	Wishlist  []Wishlist `json:"wishlist,omitempty" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email" gorm:"uniqueIndex"`
+	Password        string     `json:"-" gorm:"not null"`
+	Role            string     `json:"role" gorm:"default:'user'"`
+	Disabled        bool       `json:"disabled"` // disabled accounts can't log in or refresh
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	TokenVersion    int        `json:"token_version" gorm:"not null;default:0"` // bumped to invalidate every token issued so far
+	Cart            *Cart      `json:"cart,omitempty" gorm:"foreignKey:UserID"`
+	Orders          []Order    `json:"orders,omitempty" gorm:"foreignKey:UserID"`
+	Wishlist        []Wishlist `json:"wishlist,omitempty" gorm:"foreignKey:UserID"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" gorm:"index"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Webhook event processing statuses
+const (
+	WebhookEventProcessing = "processing"
+	WebhookEventProcessed  = "processed"
+	WebhookEventFailed     = "failed"
+)
+
+// WebhookEvent is a payment provider event as received, kept so that
+// redelivered events are only applied once and failed ones can be replayed
+type WebhookEvent struct {
+	ID                  uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Provider            string     `json:"provider" gorm:"not null;uniqueIndex:idx_webhook_events_provider_event"`
+	EventID             string     `json:"event_id" gorm:"not null;uniqueIndex:idx_webhook_events_provider_event"`
+	Type                string     `json:"type" gorm:"not null;index"`
+	Payload             string     `json:"payload" gorm:"type:text;not null"` // Raw body as delivered
+	Status              string     `json:"status" gorm:"not null;index"`      // processing, processed, failed
+	Attempts            int        `json:"attempts" gorm:"default:0"`
+	Error               string     `json:"error,omitempty"`
+	ProcessingStartedAt *time.Time `json:"processing_started_at,omitempty"` // start of the current attempt, to spot ones that crashed
+	ProcessedAt         *time.Time `json:"processed_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
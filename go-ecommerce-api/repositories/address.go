@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+
+	"gorm.io/gorm"
+)
+
+type AddressRepository struct {
+	db *gorm.DB
+}
+
+func NewAddressRepository(db *gorm.DB) *AddressRepository {
+	return &AddressRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *AddressRepository) WithTx(tx *gorm.DB) *AddressRepository {
+	return &AddressRepository{db: tx}
+}
+
+// Create an address. A default address takes the flag from the user's others.
+func (r *AddressRepository) Create(address *models.Address) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(address).Error; err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+		return setDefaultAddress(tx, address.UserID, address.ID)
+	})
+}
+
+// Update replaces every editable field of the address
+func (r *AddressRepository) Update(id uint, address *models.Address) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Address{}).Where("id = ?", id).
+			Select("*").Omit("id", "user_id", "created_at", "deleted_at").
+			Updates(address)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if !address.IsDefault {
+			return nil
+		}
+		return setDefaultAddress(tx, address.UserID, id)
+	})
+}
+
+// Delete an address. Orders keep their own copy.
+func (r *AddressRepository) Delete(id uint) error {
+	result := r.db.Where("id = ?", id).Delete(&models.Address{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Get address by ID
+func (r *AddressRepository) GetByID(id uint) (*models.Address, error) {
+	var address models.Address
+	err := r.db.Where("id = ?", id).First(&address).Error
+	return &address, err
+}
+
+// Get the user's default address
+func (r *AddressRepository) GetDefault(userID uint) (*models.Address, error) {
+	var address models.Address
+	err := r.db.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	return &address, err
+}
+
+// Get the user's addresses, default first, then newest first
+func (r *AddressRepository) ListByUser(userID uint) ([]models.Address, error) {
+	var addresses []models.Address
+	err := r.db.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&addresses).Error
+	return addresses, err
+}
+
+// Make the address the user's only default
+func (r *AddressRepository) SetDefault(userID uint, id uint) error {
+	return setDefaultAddress(r.db, userID, id)
+}
+
+// setDefaultAddress flags id and unflags the user's other addresses in one statement
+func setDefaultAddress(db *gorm.DB, userID uint, id uint) error {
+	return db.Model(&models.Address{}).Where("user_id = ?", userID).
+		Update("is_default", gorm.Expr("id = ?", id)).Error
+}
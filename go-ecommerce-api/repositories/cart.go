@@ -11,12 +11,15 @@ import (
 )
 
 type CartRepository interface {
+	WithTx(tx *gorm.DB) CartRepository
 	GetCartByUserID(userID uint) (*models.Cart, error)
+	RefreshCart(userID uint) (*models.Cart, error)
 	GetCartItemByID(id uint) (*models.CartItem, error)
 	AddItem(cartID uint, productID uint, quantity int) (*models.CartItem, error)
 	UpdateItemQuantity(id uint, quantity int) error
 	RemoveItem(id uint) error
 	ClearCart(cartID uint) error
+	SetCoupon(cartID uint, code string) error
 	CreateCart(userID uint) (*models.Cart, error)
 }
 
@@ -32,6 +35,14 @@ func NewCartRepository(db *gorm.DB, redis database.RedisClient) CartRepository {
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *cartRepository) WithTx(tx *gorm.DB) CartRepository {
+	return &cartRepository{
+		DB:    tx,
+		Redis: r.Redis,
+	}
+}
+
 func (r *cartRepository) GetCartByUserID(userID uint) (*models.Cart, error) {
 	ctx := context.Background()
 	redisKey := fmt.Sprintf("cart:user:%d", userID)
@@ -44,8 +55,17 @@ func (r *cartRepository) GetCartByUserID(userID uint) (*models.Cart, error) {
 		}
 	}
 
+	return r.RefreshCart(userID)
+}
+
+// RefreshCart loads the cart with its current products from the database,
+// replacing the cached copy, which can hold stale prices and stock
+func (r *cartRepository) RefreshCart(userID uint) (*models.Cart, error) {
+	ctx := context.Background()
+	redisKey := fmt.Sprintf("cart:user:%d", userID)
+
 	var cart models.Cart
-	err = r.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+	err := r.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
 		return db.Order("created_at ASC")
 	}).Preload("Items.Product").Where("user_id = ?", userID).First(&cart).Error
 	if err == gorm.ErrRecordNotFound {
@@ -71,13 +91,19 @@ func (r *cartRepository) GetCartItemByID(id uint) (*models.CartItem, error) {
 }
 
 func (r *cartRepository) AddItem(cartID uint, productID uint, quantity int) (*models.CartItem, error) {
+	var product models.Product
+	if err := r.DB.Where("id = ?", productID).First(&product).Error; err != nil {
+		return nil, err
+	}
+
 	// Check if item already exists in cart
 	var existingItem models.CartItem
 	err := r.DB.Where("cart_id = ? AND product_id = ?", cartID, productID).First(&existingItem).Error
 
 	if err == nil {
-		// Update quantity
+		// Update quantity, at the price the customer is looking at now
 		existingItem.Quantity += quantity
+		existingItem.PriceAtAdd = product.Price
 		if err := r.DB.Save(&existingItem).Error; err != nil {
 			return nil, err
 		}
@@ -87,9 +113,10 @@ func (r *cartRepository) AddItem(cartID uint, productID uint, quantity int) (*mo
 
 	// Create new item
 	item := models.CartItem{
-		CartID:    cartID,
-		ProductID: productID,
-		Quantity:  quantity,
+		CartID:     cartID,
+		ProductID:  productID,
+		Quantity:   quantity,
+		PriceAtAdd: product.Price,
 	}
 
 	if err := r.DB.Create(&item).Error; err != nil {
@@ -141,6 +168,16 @@ func (r *cartRepository) ClearCart(cartID uint) error {
 	return nil
 }
 
+// SetCoupon applies a coupon code to the cart, or removes it when code is empty
+func (r *cartRepository) SetCoupon(cartID uint, code string) error {
+	if err := r.DB.Model(&models.Cart{}).Where("id = ?", cartID).Update("coupon_code", code).Error; err != nil {
+		return err
+	}
+
+	r.invalidateCartCache(cartID)
+	return nil
+}
+
 func (r *cartRepository) CreateCart(userID uint) (*models.Cart, error) {
 	cart := models.Cart{UserID: userID}
 	if err := r.DB.Create(&cart).Error; err != nil {
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) *CouponRepository {
+	return &CouponRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *CouponRepository) WithTx(tx *gorm.DB) *CouponRepository {
+	return &CouponRepository{db: tx}
+}
+
+// Create a coupon
+func (r *CouponRepository) Create(coupon *models.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+// Update replaces every editable field of the coupon, so it can also be
+// deactivated or have its limits cleared
+func (r *CouponRepository) Update(id uint, coupon *models.Coupon) error {
+	result := r.db.Model(&models.Coupon{}).Where("id = ?", id).
+		Select("*").Omit("id", "times_redeemed", "created_at", "deleted_at").
+		Updates(coupon)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete a coupon. Past redemptions keep their code.
+func (r *CouponRepository) Delete(id uint) error {
+	result := r.db.Where("id = ?", id).Delete(&models.Coupon{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Get coupon by ID
+func (r *CouponRepository) GetByID(id uint) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.Where("id = ?", id).First(&coupon).Error
+	return &coupon, err
+}
+
+// Get coupon by its code
+func (r *CouponRepository) GetByCode(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.Where("code = ?", code).First(&coupon).Error
+	return &coupon, err
+}
+
+// Get coupon by its code and lock it until the transaction ends
+func (r *CouponRepository) GetByCodeForUpdate(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&coupon).Error
+	return &coupon, err
+}
+
+// Get all coupons, newest first
+func (r *CouponRepository) List() ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := r.db.Order("created_at DESC").Find(&coupons).Error
+	return coupons, err
+}
+
+// Number of times the user has redeemed the coupon
+func (r *CouponRepository) CountUserRedemptions(couponID uint, userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// Redeem records the coupon's use on an order and counts it against its limit
+func (r *CouponRepository) Redeem(redemption *models.CouponRedemption) error {
+	if err := r.db.Create(redemption).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&models.Coupon{}).Where("id = ?", redemption.CouponID).
+		Update("times_redeemed", gorm.Expr("times_redeemed + 1")).Error
+}
+
+// Release removes the coupon's use on an order that was never fulfilled, so it
+// no longer counts against the coupon's limits
+func (r *CouponRepository) Release(orderID uint) error {
+	var redemptions []models.CouponRedemption
+	err := r.db.Clauses(clause.Returning{}).Where("order_id = ?", orderID).Delete(&redemptions).Error
+	if err != nil {
+		return err
+	}
+	for _, redemption := range redemptions {
+		err := r.db.Model(&models.Coupon{}).Where("id = ? AND times_redeemed > 0", redemption.CouponID).
+			Update("times_redeemed", gorm.Expr("times_redeemed - 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
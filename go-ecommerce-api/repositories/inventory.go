@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-ecommerce-api/database"
+	"go-ecommerce-api/models"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InventoryRepository interface {
+	WithTx(tx *gorm.DB) InventoryRepository
+	Reserve(orderID uint, items []models.OrderItem) error
+	Commit(orderID uint) error
+	Release(orderID uint) error
+}
+
+type inventoryRepository struct {
+	DB    *gorm.DB
+	Redis database.RedisClient
+}
+
+func NewInventoryRepository(db *gorm.DB, redis database.RedisClient) InventoryRepository {
+	return &inventoryRepository{
+		DB:    db,
+		Redis: redis,
+	}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *inventoryRepository) WithTx(tx *gorm.DB) InventoryRepository {
+	return &inventoryRepository{
+		DB:    tx,
+		Redis: r.Redis,
+	}
+}
+
+// Reserve locks the products of the order, checks that every line can be
+// fulfilled and decrements their stock. Nothing is reserved unless all lines fit.
+func (r *inventoryRepository) Reserve(orderID uint, items []models.OrderItem) error {
+	// Merge lines for the same product
+	quantities := make(map[uint]int)
+	for _, item := range items {
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	// Lock in a stable order so concurrent checkouts can't deadlock
+	productIDs := make([]uint, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	var products []models.Product
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", productIDs).
+			Order("id ASC").
+			Find(&products).Error; err != nil {
+			return err
+		}
+
+		byID := make(map[uint]models.Product, len(products))
+		for _, product := range products {
+			byID[product.ID] = product
+		}
+
+		var shortages []models.StockShortage
+		for _, id := range productIDs {
+			product, ok := byID[id]
+			if !ok || product.Stock < quantities[id] {
+				shortages = append(shortages, models.StockShortage{
+					ProductID: id,
+					Name:      product.Name,
+					Requested: quantities[id],
+					Available: product.Stock,
+				})
+			}
+		}
+		if len(shortages) > 0 {
+			return &models.InsufficientStockError{Items: shortages}
+		}
+
+		for _, id := range productIDs {
+			if err := tx.Model(&models.Product{}).
+				Where("id = ?", id).
+				Update("stock", gorm.Expr("stock - ?", quantities[id])).Error; err != nil {
+				return err
+			}
+
+			reservation := models.StockReservation{
+				OrderID:   orderID,
+				ProductID: id,
+				Quantity:  quantities[id],
+				Status:    models.ReservationReserved,
+			}
+			if err := tx.Create(&reservation).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.invalidateProductCache(products)
+	return nil
+}
+
+// Commit marks the order's reservations as permanently consumed
+func (r *inventoryRepository) Commit(orderID uint) error {
+	return r.DB.Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationReserved).
+		Update("status", models.ReservationCommitted).Error
+}
+
+// Release gives the stock held by the order's reservations back to the products.
+// Committed reservations are released too, for paid orders that get cancelled.
+func (r *inventoryRepository) Release(orderID uint) error {
+	var products []models.Product
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var reservations []models.StockReservation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND status IN ?", orderID, []string{models.ReservationReserved, models.ReservationCommitted}).
+			Order("product_id ASC").
+			Find(&reservations).Error; err != nil {
+			return err
+		}
+
+		productIDs := make([]uint, 0, len(reservations))
+		for _, reservation := range reservations {
+			if err := tx.Model(&models.Product{}).
+				Where("id = ?", reservation.ProductID).
+				Update("stock", gorm.Expr("stock + ?", reservation.Quantity)).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&reservation).Update("status", models.ReservationReleased).Error; err != nil {
+				return err
+			}
+
+			productIDs = append(productIDs, reservation.ProductID)
+		}
+
+		if len(productIDs) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", productIDs).Find(&products).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	r.invalidateProductCache(products)
+	return nil
+}
+
+func (r *inventoryRepository) invalidateProductCache(products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	for _, product := range products {
+		r.Redis.Del(ctx, fmt.Sprintf("product:%d", product.ID))
+		if product.Category != "" {
+			r.Redis.Del(ctx, fmt.Sprintf("products:category:%s", product.Category))
+		}
+	}
+	r.Redis.Del(ctx, "products:all")
+	r.Redis.Del(ctx, "products:featured")
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *MFARepository) WithTx(tx *gorm.DB) *MFARepository {
+	return &MFARepository{db: tx}
+}
+
+// Get the user's TOTP factor, confirmed or not
+func (r *MFARepository) GetTOTP(userID uint) (*models.TOTPFactor, error) {
+	var factor models.TOTPFactor
+	err := r.db.Where("user_id = ?", userID).First(&factor).Error
+	return &factor, err
+}
+
+// HasConfirmedTOTP reports whether the user has to give a code at login
+func (r *MFARepository) HasConfirmedTOTP(userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.TOTPFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// StartTOTP saves a pending factor, replacing a pending one from an earlier
+// attempt
+func (r *MFARepository) StartTOTP(factor *models.TOTPFactor) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND confirmed_at IS NULL", factor.UserID).
+			Delete(&models.TOTPFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(factor).Error
+	})
+}
+
+// ConfirmTOTP marks the user's pending factor confirmed and replaces their
+// recovery codes
+func (r *MFARepository) ConfirmTOTP(userID uint, step int64, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TOTPFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{
+				"confirmed_at":   time.Now(),
+				"last_used_step": step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+// UseTOTPStep records an accepted code's time step. Reports false if that
+// step or a later one was already used.
+func (r *MFARepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.TOTPFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteTOTP removes the user's factor and recovery codes
+func (r *MFARepository) DeleteTOTP(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TOTPFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes drops the user's recovery codes, used or not, for new ones
+func (r *MFARepository) ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+// UseRecoveryCode marks one of the user's unused codes used. Reports false if
+// there is no such code.
+func (r *MFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountUnusedRecoveryCodes counts the recovery codes the user has left
+func (r *MFARepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []models.RecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
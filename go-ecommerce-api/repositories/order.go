@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"go-ecommerce-api/database"
 	"go-ecommerce-api/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository interface {
+	WithTx(tx *gorm.DB) OrderRepository
 	GetOrderByID(id uint) (*models.Order, error)
 	GetOrdersByUserID(userID uint) ([]models.Order, error)
 	GetAllOrders() ([]models.Order, error)
+	GetPendingOrdersBefore(before time.Time) ([]models.Order, error)
 	Create(order *models.Order) error
-	UpdateStatus(id uint, status string) error
+	UpdateStatus(id uint, change models.OrderStatusHistory) error
 	Update(id uint, updates map[string]interface{}) error
 }
 
@@ -31,6 +35,14 @@ func NewOrderRepository(db *gorm.DB, redis database.RedisClient) OrderRepository
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *orderRepository) WithTx(tx *gorm.DB) OrderRepository {
+	return &orderRepository{
+		DB:    tx,
+		Redis: r.Redis,
+	}
+}
+
 func (r *orderRepository) GetOrderByID(id uint) (*models.Order, error) {
 	ctx := context.Background()
 	redisKey := fmt.Sprintf("order:%d", id)
@@ -44,7 +56,9 @@ func (r *orderRepository) GetOrderByID(id uint) (*models.Order, error) {
 	}
 
 	var order models.Order
-	err = r.DB.Preload("Items.Product").Where("id = ?", id).First(&order).Error
+	err = r.DB.Preload("Items.Product").Preload("StatusHistory", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ?", id).First(&order).Error
 	if err != nil {
 		return nil, err
 	}
@@ -103,33 +117,64 @@ func (r *orderRepository) GetAllOrders() ([]models.Order, error) {
 	return orders, nil
 }
 
+// GetPendingOrdersBefore returns pending orders created before the given time, oldest first
+func (r *orderRepository) GetPendingOrdersBefore(before time.Time) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.DB.Preload("Items.Product").
+		Where("status = ? AND created_at < ?", models.OrderStatusPending, before).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
 func (r *orderRepository) Create(order *models.Order) error {
 	err := r.DB.Create(order).Error
 	if err != nil {
 		return err
 	}
 
+	// Don't cache the new order here: inside a transaction it may still be rolled back
 	ctx := context.Background()
-	redisKey := fmt.Sprintf("order:%d", order.ID)
-	orderJSON, _ := json.Marshal(order)
-	r.Redis.Set(ctx, redisKey, orderJSON)
-
+	r.Redis.Del(ctx, fmt.Sprintf("order:%d", order.ID))
 	r.Redis.Del(ctx, fmt.Sprintf("orders:user:%d", order.UserID))
 	r.Redis.Del(ctx, "orders:all")
 
 	return nil
 }
 
-func (r *orderRepository) UpdateStatus(id uint, status string) error {
-	err := r.DB.Model(&models.Order{}).Where("id = ?", id).Update("status", status).Error
+// UpdateStatus moves the order to change.ToStatus if the state machine allows it
+// and records the change in the order's status history.
+// Returns models.ErrInvalidOrderTransition for illegal moves.
+func (r *orderRepository) UpdateStatus(id uint, change models.OrderStatusHistory) error {
+	var order models.Order
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&order).Error; err != nil {
+			return err
+		}
+
+		if !models.CanTransitionOrderStatus(order.Status, change.ToStatus) {
+			return fmt.Errorf("%w: %s to %s", models.ErrInvalidOrderTransition, order.Status, change.ToStatus)
+		}
+
+		change.OrderID = id
+		change.FromStatus = order.Status
+		if err := tx.Model(&models.Order{}).Where("id = ?", id).Update("status", change.ToStatus).Error; err != nil {
+			return err
+		}
+
+		// A coupon used on an order that won't be fulfilled can be used again
+		if change.ToStatus == models.OrderStatusCancelled || change.ToStatus == models.OrderStatusExpired {
+			if err := NewCouponRepository(tx).Release(id); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&change).Error
+	})
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
-	var order models.Order
-	r.DB.Where("id = ?", id).First(&order)
-
 	r.Redis.Del(ctx, fmt.Sprintf("order:%d", id))
 	r.Redis.Del(ctx, fmt.Sprintf("orders:user:%d", order.UserID))
 	r.Redis.Del(ctx, "orders:all")
@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"go-ecommerce-api/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentRepository struct {
@@ -14,18 +16,23 @@ func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *PaymentRepository) WithTx(tx *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: tx}
+}
+
 // Create a new payment
 func (r *PaymentRepository) Create(payment *models.Payment) error {
 	return r.db.Create(payment).Error
 }
 
 // Save payment intent details to database
-func (r *PaymentRepository) SavePaymentIntent(orderID uint, paymentIntentID string, amount float64, currency string, status string) (*models.Payment, error) {
+func (r *PaymentRepository) SavePaymentIntent(orderID uint, paymentIntentID string, amount models.Money, status string) (*models.Payment, error) {
 	payment := &models.Payment{
 		OrderID:         orderID,
 		PaymentIntentID: paymentIntentID,
 		Amount:          amount,
-		Currency:        currency,
+		RefundedAmount:  models.NewMoney(0, amount.Currency),
 		Status:          status,
 	}
 	err := r.db.Create(payment).Error
@@ -53,22 +60,65 @@ func (r *PaymentRepository) GetPaymentByIntentID(paymentIntentID string) (*model
 	return &payment, err
 }
 
-// Get payment by order ID
+// Get the order's current payment: the one that took the customer's money if
+// there is one, otherwise the latest
 func (r *PaymentRepository) GetPaymentByOrderID(orderID uint) (*models.Payment, error) {
 	var payment models.Payment
-	err := r.db.Where("order_id = ?", orderID).First(&payment).Error
+	err := currentPayment(r.db, orderID).Take(&payment).Error
+	return &payment, err
+}
+
+// Get the order's current payment and lock it until the transaction ends
+func (r *PaymentRepository) GetPaymentByOrderIDForUpdate(orderID uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := currentPayment(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), orderID).Take(&payment).Error
+	return &payment, err
+}
+
+// Get the order's open payment, whose intent can still be paid
+func (r *PaymentRepository) GetOpenPaymentByOrderID(orderID uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("order_id = ? AND status IN ?", orderID, models.OpenPaymentStatuses).
+		Order("id DESC").
+		Take(&payment).Error
+	return &payment, err
+}
+
+// currentPayment orders the order's payments so the one that took the money,
+// or else the latest, comes first
+func currentPayment(db *gorm.DB, orderID uint) *gorm.DB {
+	return db.Where("order_id = ?", orderID).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN status IN ? THEN 0 ELSE 1 END, id DESC",
+			Vars: []interface{}{models.CapturedPaymentStatuses},
+		}})
+}
+
+// Get payment by payment intent ID and lock it until the transaction ends
+func (r *PaymentRepository) GetPaymentByIntentIDForUpdate(paymentIntentID string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("payment_intent_id = ?", paymentIntentID).First(&payment).Error
 	return &payment, err
 }
 
 // Get all payments for a user
 func (r *PaymentRepository) GetPaymentsByUserID(userID uint) ([]models.Payment, error) {
 	var payments []models.Payment
-	err := r.db.Joins("JOIN orders ON orders.id = payments.order_id").
+	err := r.db.Preload("Refunds.Items").Joins("JOIN orders ON orders.id = payments.order_id").
 		Where("orders.user_id = ?", userID).
 		Find(&payments).Error
 	return payments, err
 }
 
+// Get payments in one of the given statuses that haven't changed since before
+func (r *PaymentRepository) GetStalePayments(statuses []string, before time.Time) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("status IN ? AND updated_at < ?", statuses, before).
+		Order("updated_at ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
 // Get payment by ID
 func (r *PaymentRepository) GetByID(id uint) (*models.Payment, error) {
 	var payment models.Payment
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+
+	"gorm.io/gorm"
+)
+
+type RefundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *RefundRepository) WithTx(tx *gorm.DB) *RefundRepository {
+	return &RefundRepository{db: tx}
+}
+
+// Create a refund together with its items
+func (r *RefundRepository) Create(refund *models.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+// Update a refund's columns
+func (r *RefundRepository) Update(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&models.Refund{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Get refund by the provider's refund ID
+func (r *RefundRepository) GetByProviderRefundID(providerRefundID string) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.db.Where("provider_refund_id = ?", providerRefundID).First(&refund).Error
+	return &refund, err
+}
+
+// Get all refunds for an order
+func (r *RefundRepository) GetByOrderID(orderID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.Preload("Items").Where("order_id = ?", orderID).Order("created_at ASC").Find(&refunds).Error
+	return refunds, err
+}
+
+// GetUnconfirmed returns the oldest pending refund of amount for the payment
+// that the provider hasn't confirmed
+func (r *RefundRepository) GetUnconfirmed(paymentID uint, amount int64) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.db.Where("payment_id = ? AND provider_refund_id IS NULL AND status = ? AND amount_minor = ?", paymentID, "pending", amount).
+		Order("id ASC").
+		First(&refund).Error
+	return &refund, err
+}
+
+// UnconfirmedAmount is the total of the payment's pending refunds that the
+// provider hasn't confirmed, which aren't in the payment's refunded amount yet
+func (r *RefundRepository) UnconfirmedAmount(paymentID uint) (int64, error) {
+	var amount int64
+	err := r.db.Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount_minor), 0)").
+		Where("payment_id = ? AND provider_refund_id IS NULL AND status = ?", paymentID, "pending").
+		Scan(&amount).Error
+	return amount, err
+}
+
+// Quantity already refunded per order item of an order
+func (r *RefundRepository) RefundedQuantities(orderID uint) (map[uint]int, error) {
+	var rows []struct {
+		OrderItemID uint
+		Quantity    int
+	}
+	err := r.db.Model(&models.RefundItem{}).
+		Select("refund_items.order_item_id, SUM(refund_items.quantity) AS quantity").
+		Joins("JOIN refunds ON refunds.id = refund_items.refund_id").
+		Where("refunds.order_id = ? AND refunds.status <> ?", orderID, "failed").
+		Group("refund_items.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		quantities[row.OrderItemID] = row.Quantity
+	}
+	return quantities, nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *SessionRepository) WithTx(tx *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: tx}
+}
+
+// Create a session
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// Get session by ID
+func (r *SessionRepository) GetByID(id string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("id = ?", id).First(&session).Error
+	return &session, err
+}
+
+// Rotate swaps the session's refresh token for a new one and extends the
+// session, but only if oldJTI is still the current token of an active
+// session. Reports whether it did, so two requests can't both rotate the
+// same token.
+func (r *SessionRepository) Rotate(id string, oldJTI string, newJTI string, expiresAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_jti = ? AND revoked_at IS NULL AND expires_at > ?", id, oldJTI, time.Now()).
+		Updates(map[string]interface{}{
+			"refresh_jti": newJTI,
+			"expires_at":  expiresAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Revoke the session. Revoking it again is a no-op.
+func (r *SessionRepository) Revoke(id string) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser revokes the user's active sessions and returns their IDs
+func (r *SessionRepository) RevokeAllForUser(userID uint) ([]string, error) {
+	var ids []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Session{}).
+			Where("id IN ?", ids).
+			Update("revoked_at", time.Now()).Error
+	})
+	return ids, err
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+
+	"gorm.io/gorm"
+)
+
+type ShippingMethodRepository struct {
+	db *gorm.DB
+}
+
+func NewShippingMethodRepository(db *gorm.DB) *ShippingMethodRepository {
+	return &ShippingMethodRepository{db: db}
+}
+
+// Create a shipping method with its rates
+func (r *ShippingMethodRepository) Create(method *models.ShippingMethod) error {
+	return r.db.Create(method).Error
+}
+
+// Update replaces every editable field of the method and all of its rates
+func (r *ShippingMethodRepository) Update(id uint, method *models.ShippingMethod) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ShippingMethod{}).Where("id = ?", id).
+			Select("*").Omit("id", "created_at", "deleted_at", "Rates").
+			Updates(method)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("method_id = ?", id).Delete(&models.ShippingRate{}).Error; err != nil {
+			return err
+		}
+		for i := range method.Rates {
+			method.Rates[i].ID = 0
+			method.Rates[i].MethodID = id
+		}
+		if len(method.Rates) == 0 {
+			return nil
+		}
+		return tx.Create(&method.Rates).Error
+	})
+}
+
+// Delete a shipping method and its rates. Orders keep the method's code.
+func (r *ShippingMethodRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("method_id = ?", id).Delete(&models.ShippingRate{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.ShippingMethod{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Get shipping method by ID with its rates
+func (r *ShippingMethodRepository) GetByID(id uint) (*models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	err := r.db.Preload("Rates").Where("id = ?", id).First(&method).Error
+	return &method, err
+}
+
+// Get shipping method by its code
+func (r *ShippingMethodRepository) GetByCode(code string) (*models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	err := r.db.Where("code = ?", code).First(&method).Error
+	return &method, err
+}
+
+// Get all shipping methods with their rates
+func (r *ShippingMethodRepository) List() ([]models.ShippingMethod, error) {
+	var methods []models.ShippingMethod
+	err := r.db.Preload("Rates").Order("id ASC").Find(&methods).Error
+	return methods, err
+}
+
+// Get the methods shoppers can choose, with their rates
+func (r *ShippingMethodRepository) ListEnabled() ([]models.ShippingMethod, error) {
+	var methods []models.ShippingMethod
+	err := r.db.Preload("Rates").Where("disabled = ?", false).Order("id ASC").Find(&methods).Error
+	return methods, err
+}
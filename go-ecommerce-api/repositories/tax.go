@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+
+	"gorm.io/gorm"
+)
+
+type TaxRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRuleRepository(db *gorm.DB) *TaxRuleRepository {
+	return &TaxRuleRepository{db: db}
+}
+
+// Create a tax rule
+func (r *TaxRuleRepository) Create(rule *models.TaxRule) error {
+	return r.db.Create(rule).Error
+}
+
+// Update replaces every editable field of the rule, so fields can be cleared
+func (r *TaxRuleRepository) Update(id uint, rule *models.TaxRule) error {
+	result := r.db.Model(&models.TaxRule{}).Where("id = ?", id).
+		Select("*").Omit("id", "created_at", "deleted_at").
+		Updates(rule)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete a tax rule
+func (r *TaxRuleRepository) Delete(id uint) error {
+	result := r.db.Where("id = ?", id).Delete(&models.TaxRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Get all tax rules
+func (r *TaxRuleRepository) List() ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	err := r.db.Order("country ASC, state ASC, category ASC").Find(&rules).Error
+	return rules, err
+}
+
+// Get the rules that could apply to a destination: its country's and the global ones
+func (r *TaxRuleRepository) ForCountry(country string) ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	err := r.db.Where("country = ? OR country = ''", country).Find(&rules).Error
+	return rules, err
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs a group of repository calls in one database transaction.
+// Work that can't be rolled back by the database (e.g. a Stripe call) registers
+// a compensation with Tx.OnRollback, which runs if the transaction fails.
+type UnitOfWork interface {
+	Do(fn func(tx *Tx) error) error
+}
+
+// Tx is the transaction handed to a unit of work. Bind repositories to it with WithTx(tx.DB).
+type Tx struct {
+	DB            *gorm.DB
+	compensations []func() error
+}
+
+// OnRollback registers fn to undo an external side effect if the unit of work fails
+func (t *Tx) OnRollback(fn func() error) {
+	t.compensations = append(t.compensations, fn)
+}
+
+func (t *Tx) compensate() {
+	// Undo in reverse order, like deferred calls
+	for i := len(t.compensations) - 1; i >= 0; i-- {
+		if err := t.compensations[i](); err != nil {
+			log.Printf("[error] compensation failed, got error %v", err)
+		}
+	}
+}
+
+type unitOfWork struct {
+	DB *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{DB: db}
+}
+
+func (u *unitOfWork) Do(fn func(tx *Tx) error) (err error) {
+	tx := &Tx{}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.compensate()
+			panic(r)
+		}
+	}()
+
+	err = u.DB.Transaction(func(db *gorm.DB) error {
+		tx.DB = db
+		return fn(tx)
+	})
+	if err != nil {
+		tx.compensate()
+	}
+
+	return err
+}
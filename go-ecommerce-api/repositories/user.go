@@ -7,17 +7,23 @@ import (
 
 	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserRepositories interface {
+	WithTx(tx *gorm.DB) UserRepositories
 	GetAll() ([]models.User, error)
 	GetByID(id uint) (*models.User, error)
 	Create(user *models.User) error
 	Update(user *models.User, id uint) error
 	Delete(id uint) error
 	GetUserByEmail(e string) (*models.User, error)
+	IncrementTokenVersion(id uint) error
+	UpdatePassword(id uint, hashedPassword string) error
+	SetEmailVerified(id uint, verifiedAt *time.Time) error
+	UpdateRole(id uint, role string) error
 }
 
 type userRepositories struct {
@@ -32,6 +38,11 @@ func NewUserRepository(db *gorm.DB, redis database.RedisClient) UserRepositories
 	}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *userRepositories) WithTx(tx *gorm.DB) UserRepositories {
+	return &userRepositories{DB: tx, Redis: r.Redis}
+}
+
 func (r *userRepositories) GetAll() ([]models.User, error) {
 
 	ctx := context.Background()
@@ -87,31 +98,14 @@ func (r *userRepositories) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// GetUserByEmail always reads the database: logins need the password hash,
+// which cached copies leave out, and the account's current state
 func (r *userRepositories) GetUserByEmail(e string) (*models.User, error) {
-	// Redis
-	ctx := context.Background()
-	redisKey := fmt.Sprintf("user:%s", e)
-
-	val, err := r.Redis.Get(ctx, redisKey)
-
-	if err == nil && val != "" {
-		var user models.User
-		if err := json.Unmarshal([]byte(val), &user); err == nil {
-			return &user, nil
-		}
-	}
-
-	// if data is not cached then fetching db
 	var user models.User
-	err = r.DB.Where("email = ?", e).First(&user).Error
+	err := r.DB.Where("email = ?", e).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
-
-	// Cache the result
-	userJSON, _ := json.Marshal(user)
-	r.Redis.Set(ctx, redisKey, userJSON)
-
 	return &user, nil
 }
 
@@ -132,22 +126,47 @@ func (r *userRepositories) Create(user *models.User) error {
 }
 
 func (r *userRepositories) Update(user *models.User, id uint) error {
-	err := r.DB.Model(&models.User{}).Where("id = ?", id).Updates(user).Error
+	defer r.invalidate(r.cacheKeys(id))
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Updates(user).Error
+}
 
-	// Invalidate cache
-	ctx := context.Background()
-	r.Redis.Del(ctx, fmt.Sprintf("user:%d", id))
-	r.Redis.Del(ctx, "user:all")
-	return err
+func (r *userRepositories) Delete(id uint) error {
+	defer r.invalidate(r.cacheKeys(id))
+	return r.DB.Where("id = ?", id).Delete(&models.User{}).Error
+}
 
+// IncrementTokenVersion invalidates every token issued to the user so far
+func (r *userRepositories) IncrementTokenVersion(id uint) error {
+	defer r.invalidate(r.cacheKeys(id))
+	return r.DB.Model(&models.User{}).Where("id = ?", id).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
 }
 
-func (r *userRepositories) Delete(id uint) error {
-	err := r.DB.Where("id = ?", id).Delete(&models.User{}).Error
+func (r *userRepositories) UpdatePassword(id uint, hashedPassword string) error {
+	defer r.invalidate(r.cacheKeys(id))
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Update("password", hashedPassword).Error
+}
 
-	// Invalidate cache
+func (r *userRepositories) UpdateRole(id uint, role string) error {
+	defer r.invalidate(r.cacheKeys(id))
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// SetEmailVerified records when the user proved they own their email, or
+// clears it if verifiedAt is nil
+func (r *userRepositories) SetEmailVerified(id uint, verifiedAt *time.Time) error {
+	defer r.invalidate(r.cacheKeys(id))
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Update("email_verified_at", verifiedAt).Error
+}
+
+// cacheKeys lists the cache entries holding the user
+func (r *userRepositories) cacheKeys(id uint) []string {
+	return []string{fmt.Sprintf("user:%d", id), "user:all"}
+}
+
+func (r *userRepositories) invalidate(keys []string) {
 	ctx := context.Background()
-	r.Redis.Del(ctx, fmt.Sprintf("user:%d", id))
-	r.Redis.Del(ctx, "user:all")
-	return err
+	for _, key := range keys {
+		r.Redis.Del(ctx, key)
+	}
 }
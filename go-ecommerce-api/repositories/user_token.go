@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *UserTokenRepository) WithTx(tx *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{db: tx}
+}
+
+// Issue saves a new token, dropping the user's unused tokens for the same
+// purpose so only the latest link works
+func (r *UserTokenRepository) Issue(token *models.UserToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// Consume marks an unused, unexpired token as used and returns it. Returns
+// gorm.ErrRecordNotFound if there is no such token.
+func (r *UserTokenRepository) Consume(tokenHash string, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, time.Now()).
+			First(&token).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		token.UsedAt = &now
+		return tx.Model(&token).Update("used_at", now).Error
+	})
+	return &token, err
+}
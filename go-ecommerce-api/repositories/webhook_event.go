@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"go-ecommerce-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookEventRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookEventRepository(db *gorm.DB) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+// Record stores a newly received event. If the provider already delivered an
+// event with the same ID, nothing is written, event is loaded with the stored
+// row and false is returned.
+func (r *WebhookEventRepository) Record(event *models.WebhookEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	err := r.db.Where("provider = ? AND event_id = ?", event.Provider, event.EventID).First(event).Error
+	return false, err
+}
+
+// Claim moves a failed event, or one whose processing started before
+// staleBefore and never finished, back to processing for another attempt.
+// Returns false if the event can't be claimed, e.g. because another attempt
+// claimed it first.
+func (r *WebhookEventRepository) Claim(id uint, staleBefore time.Time) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.WebhookEvent{}).
+		Where("id = ? AND (status = ? OR (status = ? AND (processing_started_at IS NULL OR processing_started_at < ?)))",
+			id, models.WebhookEventFailed, models.WebhookEventProcessing, staleBefore).
+		Updates(map[string]interface{}{
+			"status":                models.WebhookEventProcessing,
+			"attempts":              gorm.Expr("attempts + 1"),
+			"processing_started_at": &now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Mark event as processed
+func (r *WebhookEventRepository) MarkProcessed(id uint) error {
+	now := time.Now()
+	return r.db.Model(&models.WebhookEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.WebhookEventProcessed,
+		"error":        "",
+		"processed_at": &now,
+	}).Error
+}
+
+// Mark event as failed with the error it failed with
+func (r *WebhookEventRepository) MarkFailed(id uint, reason string) error {
+	return r.db.Model(&models.WebhookEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status": models.WebhookEventFailed,
+		"error":  reason,
+	}).Error
+}
+
+// Get event by ID
+func (r *WebhookEventRepository) GetByID(id uint) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	err := r.db.First(&event, id).Error
+	return &event, err
+}
+
+// List events, newest first, optionally only those with the given status
+func (r *WebhookEventRepository) List(status string) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	query := r.db.Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&events).Error
+	return events, err
+}
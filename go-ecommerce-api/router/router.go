@@ -1,13 +1,9 @@
 package router
 
 import (
-	"go-ecommerce-api/database"
 	"go-ecommerce-api/handlers"
 	"go-ecommerce-api/middleware"
-	"go-ecommerce-api/repositories"
-	"go-ecommerce-api/services"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -16,45 +12,22 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetUpRouter(db database.Database) *gin.Engine {
-
-	redis := database.NewRedisClient()
-
-	// Get Stripe keys from environment (TODO: Move to config)
-	stripeKey := os.Getenv("STRIPE_SECRET_KEY")
-	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
-
-	// User & Auth
-	userRepo := repositories.NewUserRepository(db.GetDB(), redis)
-	userServ := services.NewUserServices(userRepo)
-	userHandle := handlers.NewUserHandlers(userServ)
-
-	// Product
-	productRepo := repositories.NewProductRepository(db.GetDB(), redis)
-	productServ := services.NewProductServices(productRepo)
-	productHandle := handlers.NewProductHandler(productServ)
-
-	// Cart
-	cartRepo := repositories.NewCartRepository(db.GetDB(), redis)
-	cartServ := services.NewCartServices(cartRepo)
-	cartHandle := handlers.NewCartHandler(cartServ)
-
-	// Order
-	orderRepo := repositories.NewOrderRepository(db.GetDB(), redis)
-	orderServ := services.NewOrderServices(orderRepo)
-
-	// Payment
-	paymentRepo := repositories.NewPaymentRepository(db.GetDB())
-	paymentServ := services.NewPaymentService(paymentRepo, orderRepo, stripeKey, webhookSecret)
-	paymentHandle := handlers.NewPaymentHandler(paymentServ)
-
-	// Order handler needs payment service for checkout
-	orderHandle := handlers.NewOrderHandler(orderServ, cartServ, paymentServ)
-
-	// Wishlist
-	wishlistRepo := repositories.NewWishlistRepository(db.GetDB(), redis)
-	wishlistServ := services.NewWishlistServices(wishlistRepo)
-	wishlistHandle := handlers.NewWishlistHandler(wishlistServ)
+func SetUpRouter(svc *Services) *gin.Engine {
+
+	authHandle := handlers.NewAuthHandler(svc.Auth, svc.Account, svc.MFA)
+	mfaHandle := handlers.NewMFAHandler(svc.MFA)
+	userHandle := handlers.NewUserHandlers(svc.User)
+	productHandle := handlers.NewProductHandler(svc.Product)
+	addressHandle := handlers.NewAddressHandler(svc.Address)
+	cartHandle := handlers.NewCartHandler(svc.Cart)
+	couponHandle := handlers.NewCouponHandler(svc.Coupon)
+	taxHandle := handlers.NewTaxHandler(svc.Tax)
+	shippingHandle := handlers.NewShippingHandler(svc.Shipping)
+	orderHandle := handlers.NewOrderHandler(svc.Order, svc.Checkout)
+	paymentHandle := handlers.NewPaymentHandler(svc.Payment)
+	refundHandle := handlers.NewRefundHandler(svc.Refund)
+	webhookHandle := handlers.NewWebhookHandler(svc.Webhook)
+	wishlistHandle := handlers.NewWishlistHandler(svc.Wishlist)
 
 	// ROUTER
 	router := gin.Default()
@@ -63,8 +36,8 @@ func SetUpRouter(db database.Database) *gin.Engine {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:8081", "http://127.0.0.1:3000", "http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", middleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.IdempotentReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -77,12 +50,20 @@ func SetUpRouter(db database.Database) *gin.Engine {
 	//PING
 	router.GET("/ping", Health)
 
+	requireAuth := middleware.RequireAuth(svc.Auth)
+
 	// AUTH ROUTES
 	authRoute := router.Group("/auth")
-	authRoute.Use(middleware.UserServContext(userServ))
-	authRoute.POST("/login", handlers.Login)
-	authRoute.POST("/register", handlers.Register)
-	authRoute.GET("/refresh", handlers.RefreshToken)
+	authRoute.POST("/login", authHandle.Login)
+	authRoute.POST("/register", authHandle.Register)
+	authRoute.GET("/refresh", authHandle.RefreshToken)
+	authRoute.POST("/mfa/verify", authHandle.VerifyMFA)
+	authRoute.POST("/logout", requireAuth, authHandle.Logout)
+	authRoute.POST("/logout-all", requireAuth, authHandle.LogoutAll)
+	authRoute.POST("/forgot-password", authHandle.ForgotPassword)
+	authRoute.POST("/reset-password", authHandle.ResetPassword)
+	authRoute.POST("/verify-email", authHandle.VerifyEmail)
+	authRoute.POST("/resend-verification", requireAuth, authHandle.ResendVerification)
 
 	// PUBLIC PRODUCT ROUTES
 	productRoute := router.Group("/products")
@@ -94,13 +75,24 @@ func SetUpRouter(db database.Database) *gin.Engine {
 
 	// PROTECTED ROUTES (require authentication)
 	base := router.Group("/")
-	base.Use(middleware.RequireAuth())
+	base.Use(requireAuth)
 
 	// USER ROUTE
 	userRoute := base.Group("user")
 	userRoute.GET("", userHandle.GetByID)
 	userRoute.DELETE("", userHandle.Delete)
 	userRoute.PUT("", userHandle.Update)
+	userRoute.GET("/addresses", addressHandle.GetAddresses)
+	userRoute.POST("/addresses", addressHandle.CreateAddress)
+	userRoute.GET("/addresses/:id", addressHandle.GetAddress)
+	userRoute.PUT("/addresses/:id", addressHandle.UpdateAddress)
+	userRoute.DELETE("/addresses/:id", addressHandle.DeleteAddress)
+	userRoute.PUT("/addresses/:id/default", addressHandle.SetDefaultAddress)
+	userRoute.GET("/mfa", mfaHandle.GetMFAStatus)
+	userRoute.POST("/mfa/totp", mfaHandle.BeginTOTPEnrolment)
+	userRoute.POST("/mfa/totp/confirm", mfaHandle.ConfirmTOTPEnrolment)
+	userRoute.DELETE("/mfa/totp", mfaHandle.DisableTOTP)
+	userRoute.POST("/mfa/recovery-codes", mfaHandle.RegenerateRecoveryCodes)
 
 	// CART ROUTES
 	cartRoute := base.Group("cart")
@@ -109,13 +101,21 @@ func SetUpRouter(db database.Database) *gin.Engine {
 	cartRoute.POST("/items", cartHandle.AddToCart)
 	cartRoute.PUT("/items/:id", cartHandle.UpdateCartItem)
 	cartRoute.DELETE("/items/:id", cartHandle.RemoveFromCart)
+	cartRoute.POST("/coupon", cartHandle.ApplyCoupon)
+	cartRoute.DELETE("/coupon", cartHandle.RemoveCoupon)
+	cartRoute.GET("/shipping-options", cartHandle.GetShippingOptions)
+	cartRoute.POST("/validate", cartHandle.ValidateCart)
+
+	// Retried requests with the same Idempotency-Key run only once
+	idempotency := middleware.Idempotency(svc.Redis)
 
 	// ORDER ROUTES
 	orderRoute := base.Group("orders")
 	orderRoute.GET("", orderHandle.GetUserOrders)
-	orderRoute.POST("", orderHandle.CreateOrder)
-	orderRoute.POST("/checkout", orderHandle.Checkout) // Integrated checkout
+	orderRoute.POST("", idempotency, orderHandle.CreateOrder)
+	orderRoute.POST("/checkout", idempotency, orderHandle.Checkout) // Integrated checkout
 	orderRoute.GET("/:id", orderHandle.GetOrderByID)
+	orderRoute.POST("/:id/cancel", orderHandle.CancelOrder)
 
 	// WISHLIST ROUTES
 	wishlistRoute := base.Group("wishlist")
@@ -126,6 +126,7 @@ func SetUpRouter(db database.Database) *gin.Engine {
 
 	// PAYMENT ROUTES
 	paymentRoute := base.Group("payments")
+	paymentRoute.Use(idempotency)
 	paymentRoute.POST("/create-intent", paymentHandle.CreatePaymentIntent)
 	paymentRoute.POST("/confirm/:id", paymentHandle.ConfirmPayment)
 	paymentRoute.POST("/cancel/:id", paymentHandle.CancelPayment)
@@ -135,13 +136,14 @@ func SetUpRouter(db database.Database) *gin.Engine {
 	paymentRoute.GET("/history", paymentHandle.GetUserPayments)
 
 	// Webhook endpoint (outside auth middleware)
-	router.POST("/webhooks/stripe", paymentHandle.HandleWebhook)
+	router.POST("/webhooks/stripe", webhookHandle.HandleWebhook)
 
 	// ADMIN ROUTES
 	adminRoute := router.Group("/admin")
-	adminRoute.Use(middleware.RequireAuth(), middleware.RequireAdmin())
+	adminRoute.Use(requireAuth, middleware.RequireAdmin())
 
 	adminRoute.GET("/users", userHandle.GetAllAdmin)
+	adminRoute.PUT("/users/:id/role", userHandle.SetRoleAdmin)
 
 	// Admin Product Routes
 	adminProductRoute := adminRoute.Group("/products")
@@ -154,6 +156,36 @@ func SetUpRouter(db database.Database) *gin.Engine {
 	adminOrderRoute := adminRoute.Group("/orders")
 	adminOrderRoute.GET("", orderHandle.GetAllOrders)
 	adminOrderRoute.PUT("/:id/status", orderHandle.UpdateOrderStatus)
+	adminOrderRoute.GET("/:id/refunds", refundHandle.GetOrderRefunds)
+	adminOrderRoute.POST("/:id/refunds", refundHandle.CreateRefund)
+
+	// Admin Coupon Routes
+	adminCouponRoute := adminRoute.Group("/coupons")
+	adminCouponRoute.GET("", couponHandle.GetCoupons)
+	adminCouponRoute.POST("", couponHandle.CreateCoupon)
+	adminCouponRoute.GET("/:id", couponHandle.GetCoupon)
+	adminCouponRoute.PUT("/:id", couponHandle.UpdateCoupon)
+	adminCouponRoute.DELETE("/:id", couponHandle.DeleteCoupon)
+
+	// Admin Tax Routes
+	adminTaxRoute := adminRoute.Group("/tax-rules")
+	adminTaxRoute.GET("", taxHandle.GetTaxRules)
+	adminTaxRoute.POST("", taxHandle.CreateTaxRule)
+	adminTaxRoute.PUT("/:id", taxHandle.UpdateTaxRule)
+	adminTaxRoute.DELETE("/:id", taxHandle.DeleteTaxRule)
+
+	// Admin Shipping Routes
+	adminShippingRoute := adminRoute.Group("/shipping-methods")
+	adminShippingRoute.GET("", shippingHandle.GetShippingMethods)
+	adminShippingRoute.POST("", shippingHandle.CreateShippingMethod)
+	adminShippingRoute.GET("/:id", shippingHandle.GetShippingMethod)
+	adminShippingRoute.PUT("/:id", shippingHandle.UpdateShippingMethod)
+	adminShippingRoute.DELETE("/:id", shippingHandle.DeleteShippingMethod)
+
+	// Admin Webhook Routes
+	adminWebhookRoute := adminRoute.Group("/webhooks")
+	adminWebhookRoute.GET("", webhookHandle.GetWebhookEvents)
+	adminWebhookRoute.POST("/:id/replay", webhookHandle.ReplayWebhookEvent)
 
 	return router
 
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"fmt"
+	"go-ecommerce-api/config"
+	"go-ecommerce-api/database"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/services"
+)
+
+// Services holds the application services, shared by the HTTP server and the
+// command line jobs
+type Services struct {
+	Redis     database.RedisClient
+	User      services.UserServices
+	Auth      *services.AuthServices
+	Account   *services.AccountServices
+	MFA       *services.MFAServices
+	Address   *services.AddressServices
+	Product   *services.ProductServices
+	Cart      *services.CartServices
+	Coupon    *services.CouponServices
+	Tax       *services.TaxServices
+	Shipping  *services.ShippingServices
+	Order     *services.OrderServices
+	Checkout  *services.CheckoutServices
+	Payment   *services.PaymentService
+	Refund    *services.RefundServices
+	Webhook   *services.WebhookServices
+	Wishlist  *services.WishlistServices
+	Reconcile *services.ReconcileServices
+	Expiry    *services.OrderExpiryServices
+}
+
+func NewServices(db database.Database, cfg *config.Config) (*Services, error) {
+
+	redis := database.NewRedisClient()
+
+	// User & Auth
+	userRepo := repositories.NewUserRepository(db.GetDB(), redis)
+	userServ := services.NewUserServices(userRepo)
+	sessionRepo := repositories.NewSessionRepository(db.GetDB())
+	loginGuard := services.NewLoginGuard(redis, cfg)
+	mfaRepo := repositories.NewMFARepository(db.GetDB())
+	authServ := services.NewAuthServices(userServ, sessionRepo, mfaRepo, redis, loginGuard)
+
+	// Two-factor authentication
+	uow := repositories.NewUnitOfWork(db.GetDB())
+	mfaServ := services.NewMFAServices(uow, userServ, mfaRepo, authServ, loginGuard, redis, cfg.MFAIssuer)
+
+	// Password resets and email verification
+	mailer, err := services.NewMailer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mail sender: %w", err)
+	}
+	userTokenRepo := repositories.NewUserTokenRepository(db.GetDB())
+	accountServ := services.NewAccountServices(userServ, authServ, userTokenRepo, mailer, cfg.AppURL, cfg.PasswordResetTTL, cfg.EmailVerificationTTL)
+
+	// Address book
+	addressRepo := repositories.NewAddressRepository(db.GetDB())
+	addressServ := services.NewAddressServices(addressRepo)
+
+	// Product
+	productRepo := repositories.NewProductRepository(db.GetDB(), redis)
+	productServ := services.NewProductServices(productRepo)
+
+	// Coupons
+	couponRepo := repositories.NewCouponRepository(db.GetDB())
+	couponServ := services.NewCouponServices(couponRepo)
+
+	// Tax
+	taxRuleRepo := repositories.NewTaxRuleRepository(db.GetDB())
+	taxServ := services.NewTaxServices(taxRuleRepo)
+
+	// Shipping
+	shippingRepo := repositories.NewShippingMethodRepository(db.GetDB())
+	shippingServ := services.NewShippingServices(shippingRepo)
+
+	// Pricing of carts, shared by the cart and checkout
+	pricingServ := services.NewPricingServices(couponServ, taxServ, shippingServ)
+
+	// Cart
+	cartRepo := repositories.NewCartRepository(db.GetDB(), redis)
+	cartServ := services.NewCartServices(cartRepo, couponServ, pricingServ)
+
+	// Inventory
+	inventoryRepo := repositories.NewInventoryRepository(db.GetDB(), redis)
+
+	// Order
+	orderRepo := repositories.NewOrderRepository(db.GetDB(), redis)
+
+	// Payment
+	paymentProvider, err := services.NewPaymentProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize payment provider: %w", err)
+	}
+	paymentRepo := repositories.NewPaymentRepository(db.GetDB())
+
+	// Refund
+	refundRepo := repositories.NewRefundRepository(db.GetDB())
+	refundServ := services.NewRefundServices(uow, refundRepo, paymentRepo, orderRepo, paymentProvider)
+
+	paymentServ := services.NewPaymentService(uow, paymentRepo, orderRepo, inventoryRepo, paymentProvider, refundServ)
+
+	// Webhook
+	webhookRepo := repositories.NewWebhookEventRepository(db.GetDB())
+	webhookServ := services.NewWebhookServices(webhookRepo, paymentServ, paymentProvider)
+
+	// Reconciliation of payments whose webhooks never arrived
+	reconcileServ := services.NewReconcileServices(paymentRepo, paymentServ)
+
+	// Orders need payments and refunds to cancel, and checkout spans cart,
+	// order, inventory and payment in one unit of work
+	orderServ := services.NewOrderServices(orderRepo, paymentRepo, inventoryRepo, paymentServ, refundServ)
+	checkoutServ := services.NewCheckoutServices(uow, orderRepo, cartRepo, inventoryRepo, paymentServ, couponServ, pricingServ, addressServ)
+
+	// Expiry of orders that were never paid
+	expiryServ := services.NewOrderExpiryServices(orderRepo, paymentRepo, inventoryRepo, cartRepo, paymentServ, cfg.OrderExpiryRestoreCart)
+
+	// Wishlist
+	wishlistRepo := repositories.NewWishlistRepository(db.GetDB(), redis)
+	wishlistServ := services.NewWishlistServices(wishlistRepo)
+
+	return &Services{
+		Redis:     redis,
+		User:      userServ,
+		Auth:      authServ,
+		Account:   accountServ,
+		MFA:       mfaServ,
+		Address:   addressServ,
+		Product:   productServ,
+		Cart:      cartServ,
+		Coupon:    couponServ,
+		Tax:       taxServ,
+		Shipping:  shippingServ,
+		Order:     orderServ,
+		Checkout:  checkoutServ,
+		Payment:   paymentServ,
+		Refund:    refundServ,
+		Webhook:   webhookServ,
+		Wishlist:  wishlistServ,
+		Reconcile: reconcileServ,
+		Expiry:    expiryServ,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidUserToken     = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+)
+
+// AccountServices handles account recovery and email verification through
+// single-use links emailed to the user
+type AccountServices struct {
+	Users                UserServices
+	Auth                 *AuthServices
+	Tokens               *repositories.UserTokenRepository
+	Mailer               Mailer
+	AppURL               string
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+}
+
+func NewAccountServices(users UserServices, auth *AuthServices, tokens *repositories.UserTokenRepository, mailer Mailer, appURL string, passwordResetTTL time.Duration, emailVerificationTTL time.Duration) *AccountServices {
+	return &AccountServices{
+		Users:                users,
+		Auth:                 auth,
+		Tokens:               tokens,
+		Mailer:               mailer,
+		AppURL:               appURL,
+		PasswordResetTTL:     passwordResetTTL,
+		EmailVerificationTTL: emailVerificationTTL,
+	}
+}
+
+// RequestPasswordReset emails a reset link to the account with the email.
+// The work is done in the background, so neither the response nor how long it
+// takes tells whether an email is registered.
+func (s *AccountServices) RequestPasswordReset(email string) {
+	go func() {
+		if err := s.sendPasswordReset(email); err != nil {
+			log.Printf("[error] password reset request failed, got error %v", err)
+		}
+	}()
+}
+
+// sendPasswordReset issues and emails a reset link. Unknown and disabled
+// accounts are silently skipped.
+func (s *AccountServices) sendPasswordReset(email string) error {
+	user, err := s.Users.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil || user.Disabled {
+		return nil
+	}
+
+	token, err := s.issueToken(user.ID, models.UserTokenPasswordReset, s.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to choose a new password. It expires in %s.\n\n%s\n\nIf you didn't ask for this, you can ignore this email.",
+			user.Name, s.PasswordResetTTL, s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password with a token from RequestPasswordReset.
+// Every token issued to the user so far stops working and all their sessions
+// are logged out. Following the link also proves the email is theirs.
+func (s *AccountServices) ResetPassword(token string, password string) error {
+	userToken, err := s.consumeToken(token, models.UserTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.Users.UpdatePassword(userToken.UserID, hashedPassword); err != nil {
+		return err
+	}
+	if err := s.Users.IncrementTokenVersion(userToken.UserID); err != nil {
+		return err
+	}
+	if err := s.Auth.LogoutAll(userToken.UserID); err != nil {
+		return err
+	}
+
+	user, err := s.Users.GetByID(userToken.UserID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		return s.Users.SetEmailVerified(user.ID, &now)
+	}
+	return nil
+}
+
+// SendVerificationEmail emails the user a link to verify their email
+func (s *AccountServices) SendVerificationEmail(user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueToken(user.ID, models.UserTokenEmailVerification, s.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	s.send(Email{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm this is your email address. The link expires in %s.\n\n%s",
+			user.Name, s.EmailVerificationTTL, s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// ResendVerificationEmail sends the user a new verification link; earlier
+// links stop working
+func (s *AccountServices) ResendVerificationEmail(userID uint) error {
+	user, err := s.Users.GetByID(userID)
+	if err != nil {
+		return notFound(err)
+	}
+	return s.SendVerificationEmail(user)
+}
+
+// VerifyEmail marks the user's email as verified with a token from
+// SendVerificationEmail
+func (s *AccountServices) VerifyEmail(token string) error {
+	userToken, err := s.consumeToken(token, models.UserTokenEmailVerification)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.Users.SetEmailVerified(userToken.UserID, &now)
+}
+
+// issueToken stores the hash of a new token and returns the token
+func (s *AccountServices) issueToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.NewTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Tokens.Issue(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return token, err
+}
+
+func (s *AccountServices) consumeToken(token string, purpose string) (*models.UserToken, error) {
+	userToken, err := s.Tokens.Consume(utils.HashToken(token), purpose)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidUserToken
+	}
+	return userToken, err
+}
+
+func (s *AccountServices) link(path string, token string) string {
+	return s.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// send delivers the email in the background, so how long the mail server
+// takes doesn't show in response times
+func (s *AccountServices) send(email Email) {
+	go func() {
+		if err := s.Mailer.Send(email); err != nil {
+			log.Printf("[error] failed to send %q email, got error %v", email.Subject, err)
+		}
+	}()
+}
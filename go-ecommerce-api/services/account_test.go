@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/utils"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	session := env.startSession(t, alice)
+
+	env.Account.RequestPasswordReset(alice.Email)
+	email := env.Mail.next(t)
+	if email.To != alice.Email {
+		t.Fatalf("reset link went to %s, want %s", email.To, alice.Email)
+	}
+	token := linkToken(t, email)
+
+	// Only the token's hash is stored
+	var stored models.UserToken
+	env.DB.Where("user_id = ?", alice.ID).First(&stored)
+	if stored.TokenHash != utils.HashToken(token) {
+		t.Errorf("stored %q, want the token's hash", stored.TokenHash)
+	}
+
+	if err := env.Account.ResetPassword(token, "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Account.ResetPassword(token, "another password"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("second reset got error %v, want %v", err, ErrInvalidUserToken)
+	}
+
+	if _, _, err := env.Auth.Login(alice.Email, "correct horse battery", "127.0.0.1"); err != nil {
+		t.Errorf("login with the new password got error %v", err)
+	}
+	if _, err := env.authenticate(session.AccessToken); err == nil {
+		t.Error("session from before the reset is still accepted")
+	}
+	if user := env.user(t, alice.ID); user.EmailVerifiedAt == nil {
+		t.Error("following the reset link didn't verify the email")
+	}
+}
+
+func TestPasswordResetDoesNotWaitForTheAccount(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+
+	// Nobody reads the mail yet, so sending it blocks
+	account := *env.Account
+	mail := make(mailbox)
+	account.Mailer = mail
+
+	done := make(chan struct{})
+	go func() {
+		account.RequestPasswordReset(alice.Email)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request waited for the email to be sent")
+	}
+	if email := mail.next(t); email.To != alice.Email {
+		t.Errorf("reset link went to %s, want %s", email.To, alice.Email)
+	}
+}
+
+func TestPasswordResetSkipsUnknownAccounts(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.DB.Model(alice).Update("disabled", true)
+
+	for _, email := range []string{"nobody@example.com", alice.Email} {
+		if err := env.Account.sendPasswordReset(email); err != nil {
+			t.Errorf("reset for %s got error %v", email, err)
+		}
+	}
+
+	var issued int64
+	env.DB.Model(&models.UserToken{}).Count(&issued)
+	if issued != 0 || len(env.Mail) != 0 {
+		t.Errorf("issued %d tokens and sent %d emails, want none", issued, len(env.Mail))
+	}
+}
+
+func TestUserTokenIsRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		// returns the token to try and how to use it
+		setup func(t *testing.T, env *testEnv, user *models.User) (string, func(string) error)
+	}{
+		{"superseded by a newer link", func(t *testing.T, env *testEnv, user *models.User) (string, func(string) error) {
+			env.Account.RequestPasswordReset(user.Email)
+			first := linkToken(t, env.Mail.next(t))
+			env.Account.RequestPasswordReset(user.Email)
+			env.Mail.next(t)
+			return first, env.resetPassword
+		}},
+		{"expired", func(t *testing.T, env *testEnv, user *models.User) (string, func(string) error) {
+			env.Account.RequestPasswordReset(user.Email)
+			token := linkToken(t, env.Mail.next(t))
+			env.DB.Model(&models.UserToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute))
+			return token, env.resetPassword
+		}},
+		{"issued for another purpose", func(t *testing.T, env *testEnv, user *models.User) (string, func(string) error) {
+			env.Account.RequestPasswordReset(user.Email)
+			return linkToken(t, env.Mail.next(t)), env.Account.VerifyEmail
+		}},
+		{"stored hash used as the token", func(t *testing.T, env *testEnv, user *models.User) (string, func(string) error) {
+			env.Account.RequestPasswordReset(user.Email)
+			env.Mail.next(t)
+			var stored models.UserToken
+			env.DB.Where("user_id = ?", user.ID).First(&stored)
+			return stored.TokenHash, env.resetPassword
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			alice := env.createUser(t, "alice")
+
+			token, use := tt.setup(t, env, alice)
+			if err := use(token); !errors.Is(err, ErrInvalidUserToken) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidUserToken)
+			}
+		})
+	}
+}
+
+func TestVerifyEmailTokenIsSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+
+	if err := env.Account.SendVerificationEmail(alice); err != nil {
+		t.Fatal(err)
+	}
+	token := linkToken(t, env.Mail.next(t))
+
+	if err := env.Account.VerifyEmail(token); err != nil {
+		t.Fatal(err)
+	}
+	if user := env.user(t, alice.ID); user.EmailVerifiedAt == nil {
+		t.Error("email is not verified")
+	}
+	if err := env.Account.VerifyEmail(token); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("second verification got error %v, want %v", err, ErrInvalidUserToken)
+	}
+	if err := env.Account.ResendVerificationEmail(alice.ID); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Errorf("resend got error %v, want %v", err, ErrEmailAlreadyVerified)
+	}
+}
+
+func (e *testEnv) resetPassword(token string) error {
+	return e.Account.ResetPassword(token, "correct horse battery")
+}
+
+func (e *testEnv) user(t *testing.T, userID uint) *models.User {
+	t.Helper()
+	var user models.User
+	if err := e.DB.First(&user, userID).Error; err != nil {
+		t.Fatalf("loading user %d: %v", userID, err)
+	}
+	return &user
+}
+
+// linkToken returns the token in the link of an account email
+func linkToken(t *testing.T, email Email) string {
+	t.Helper()
+	for _, field := range strings.Fields(email.Body) {
+		link, err := url.Parse(field)
+		if err == nil && link.Query().Get("token") != "" {
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link in email %q", email.Body)
+	return ""
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+
+	"gorm.io/gorm"
+)
+
+var ErrAddressRequired = errors.New("no address given and no default address saved")
+
+type AddressServices struct {
+	Repo *repositories.AddressRepository
+}
+
+func NewAddressServices(repo *repositories.AddressRepository) *AddressServices {
+	return &AddressServices{
+		Repo: repo,
+	}
+}
+
+func (s *AddressServices) GetAll(userID uint) ([]models.Address, error) {
+	return s.Repo.ListByUser(userID)
+}
+
+// GetByID returns one of the user's addresses
+func (s *AddressServices) GetByID(userID uint, id uint) (*models.Address, error) {
+	address, err := s.Repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := requireOwner(address.UserID, userID); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// Create validates and saves a new address. The user's first address becomes
+// their default.
+func (s *AddressServices) Create(userID uint, address *models.Address) error {
+	address.Normalize()
+	if err := address.Validate(); err != nil {
+		return err
+	}
+
+	if !address.IsDefault {
+		_, err := s.Repo.GetDefault(userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			address.IsDefault = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	address.ID = 0
+	address.UserID = userID
+	return s.Repo.Create(address)
+}
+
+// Update replaces one of the user's addresses
+func (s *AddressServices) Update(userID uint, id uint, address *models.Address) error {
+	if _, err := s.GetByID(userID, id); err != nil {
+		return err
+	}
+
+	address.Normalize()
+	if err := address.Validate(); err != nil {
+		return err
+	}
+
+	address.UserID = userID
+	return notFound(s.Repo.Update(id, address))
+}
+
+// Delete removes one of the user's addresses. If it was the default, the
+// newest remaining address takes over.
+func (s *AddressServices) Delete(userID uint, id uint) error {
+	address, err := s.GetByID(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(id); err != nil {
+		return notFound(err)
+	}
+	if !address.IsDefault {
+		return nil
+	}
+
+	remaining, err := s.Repo.ListByUser(userID)
+	if err != nil || len(remaining) == 0 {
+		return err
+	}
+	return s.Repo.SetDefault(userID, remaining[0].ID)
+}
+
+// SetDefault makes one of the user's addresses their default
+func (s *AddressServices) SetDefault(userID uint, id uint) error {
+	if _, err := s.GetByID(userID, id); err != nil {
+		return err
+	}
+	return s.Repo.SetDefault(userID, id)
+}
+
+// Resolve returns the user's address with the given ID, or their default
+// address if id is 0
+func (s *AddressServices) Resolve(userID uint, id uint) (*models.Address, error) {
+	if id != 0 {
+		return s.GetByID(userID, id)
+	}
+
+	address, err := s.Repo.GetDefault(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAddressRequired
+	}
+	return address, err
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ecommerce-api/database"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionRevoked      = errors.New("session revoked or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token already used")
+	ErrAccountUnavailable  = errors.New("account deleted or disabled")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+)
+
+// dummyPasswordHash is checked against when no account has the email, so
+// unknown emails take as long to reject as wrong passwords
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword("not a real password")
+	if err != nil {
+		log.Printf("[error] failed to hash dummy password, got error %v", err)
+	}
+	return hash
+})
+
+const (
+	sessionStateActive  = "active"
+	sessionStateRevoked = "revoked"
+
+	// An active state is only cached briefly, so a revocation that races
+	// with a cache fill can't be hidden for long
+	sessionActiveCacheTTL = time.Minute
+)
+
+// AuthServices issues tokens for sessions kept in the database. Refresh
+// tokens are single use; revoked sessions are cached in Redis so every
+// authenticated request can reject their access tokens cheaply.
+type AuthServices struct {
+	Users    UserServices
+	Sessions *repositories.SessionRepository
+	MFA      *repositories.MFARepository
+	Redis    database.RedisClient
+	Guard    *LoginGuard
+}
+
+func NewAuthServices(users UserServices, sessions *repositories.SessionRepository, mfa *repositories.MFARepository, redis database.RedisClient, guard *LoginGuard) *AuthServices {
+	return &AuthServices{
+		Users:    users,
+		Sessions: sessions,
+		MFA:      mfa,
+		Redis:    redis,
+		Guard:    guard,
+	}
+}
+
+// Login checks the user's email and password, and reports whether the user
+// also has to give a second factor. Unknown emails and wrong passwords both
+// give ErrInvalidCredentials after the same bcrypt work, and count towards
+// throttling; throttled attempts give *TooManyAttemptsError without checking
+// the password. The email's failures are only cleared once the login is
+// complete, so for users with a second factor that is left to
+// MFAServices.CompleteChallenge.
+func (s *AuthServices) Login(email string, password string, ip string) (*models.User, bool, error) {
+	if err := s.Guard.Check(email, ip); err != nil {
+		return nil, false, err
+	}
+
+	user, err := s.Users.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.CheckPassword(password, dummyPasswordHash())
+		s.Guard.Fail(email, ip)
+		return nil, false, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := utils.CheckPassword(password, user.Password); err != nil {
+		s.Guard.Fail(email, ip)
+		return nil, false, ErrInvalidCredentials
+	}
+
+	mfaRequired, err := s.MFA.HasConfirmedTOTP(user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !mfaRequired {
+		s.Guard.Succeed(email)
+	}
+	return user, mfaRequired, nil
+}
+
+// StartSession opens a new session for the user and returns its first tokens.
+// mfa tells whether the user gave a second factor. Disabled accounts get
+// ErrAccountUnavailable.
+func (s *AuthServices) StartSession(user *models.User, userAgent string, ip string, mfa bool) (*utils.TokenPair, error) {
+	if user.DeletedAt != nil || user.Disabled {
+		return nil, ErrAccountUnavailable
+	}
+
+	sessionID, err := utils.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+	refreshID, err := utils.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		RefreshJTI: refreshID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		MFA:        mfa,
+		ExpiresAt:  time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := s.Sessions.Create(session); err != nil {
+		return nil, err
+	}
+	return utils.GenerateTokenPair(user, sessionID, refreshID, mfa)
+}
+
+// Refresh exchanges a refresh token for a new pair. Each refresh token works
+// once: presenting one that was already exchanged means it was copied, so the
+// whole session is revoked and ErrRefreshTokenReused returned.
+func (s *AuthServices) Refresh(refreshToken string) (*utils.TokenPair, error) {
+	claims, err := utils.ValidateRefreshToken(refreshToken)
+	if err != nil || claims.SessionID == "" || claims.ID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Tokens carry the user as they were when issued; the role and the
+	// account's state come from the database
+	user, err := s.currentUser(claims.UserID, claims.TokenVersion)
+	if errors.Is(err, ErrAccountUnavailable) || errors.Is(err, ErrSessionRevoked) {
+		if revokeErr := s.Logout(claims.SessionID); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refreshID, err := utils.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.Sessions.Rotate(claims.SessionID, claims.ID, refreshID, time.Now().Add(utils.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, s.refreshRejected(claims)
+	}
+
+	return utils.GenerateTokenPair(user, claims.SessionID, refreshID, claims.MFA)
+}
+
+// refreshRejected works out why a refresh token couldn't be rotated
+func (s *AuthServices) refreshRejected(claims *utils.RefreshClaims) error {
+	session, err := s.Sessions.GetByID(claims.SessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	if !session.Active() {
+		return ErrSessionRevoked
+	}
+
+	log.Printf("[warn] refresh token reused for session %s of user %d, revoking the session", session.ID, session.UserID)
+	if err := s.Logout(session.ID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the session
+func (s *AuthServices) Logout(sessionID string) error {
+	if err := s.Sessions.Revoke(sessionID); err != nil {
+		return err
+	}
+	s.cacheSessionState(sessionID, sessionStateRevoked, utils.AccessTokenTTL)
+	return nil
+}
+
+// LogoutAll revokes every session of the user
+func (s *AuthServices) LogoutAll(userID uint) error {
+	ids, err := s.Sessions.RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.cacheSessionState(id, sessionStateRevoked, utils.AccessTokenTTL)
+	}
+	return nil
+}
+
+// LogoutAllTx is LogoutAll within a unit of work. The sessions are cached as
+// revoked straight away, and uncached again if the unit of work fails.
+func (s *AuthServices) LogoutAllTx(tx *repositories.Tx, userID uint) error {
+	ids, err := s.Sessions.WithTx(tx.DB).RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.cacheSessionState(id, sessionStateRevoked, utils.AccessTokenTTL)
+	}
+	tx.OnRollback(func() error {
+		for _, id := range ids {
+			if err := s.Redis.Del(context.Background(), sessionCacheKey(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return nil
+}
+
+// Authenticate checks an access token against its session and the current
+// state of its user, and returns the user
+func (s *AuthServices) Authenticate(claims *utils.Claims) (*models.User, error) {
+	active, err := s.IsSessionActive(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSessionRevoked
+	}
+	return s.currentUser(claims.UserID, claims.TokenVersion)
+}
+
+// currentUser loads the user a token was issued to. Deleted and disabled
+// accounts give ErrAccountUnavailable, and tokens issued before the user's
+// token version was bumped give ErrSessionRevoked.
+func (s *AuthServices) currentUser(userID uint, tokenVersion int) (*models.User, error) {
+	user, err := s.Users.GetByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil || user.Disabled {
+		return nil, ErrAccountUnavailable
+	}
+	if user.TokenVersion != tokenVersion {
+		return nil, ErrSessionRevoked
+	}
+	return user, nil
+}
+
+// IsSessionActive reports whether access tokens of the session are still
+// accepted. Revocations are cached for as long as an access token lives.
+func (s *AuthServices) IsSessionActive(sessionID string) (bool, error) {
+	ctx := context.Background()
+	if state, err := s.Redis.Get(ctx, sessionCacheKey(sessionID)); err == nil {
+		return state == sessionStateActive, nil
+	}
+
+	session, err := s.Sessions.GetByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !session.Active() {
+		s.cacheSessionState(sessionID, sessionStateRevoked, utils.AccessTokenTTL)
+		return false, nil
+	}
+	s.cacheSessionState(sessionID, sessionStateActive, sessionActiveCacheTTL)
+	return true, nil
+}
+
+func (s *AuthServices) cacheSessionState(sessionID string, state string, ttl time.Duration) {
+	if err := s.Redis.SetWithTTL(context.Background(), sessionCacheKey(sessionID), state, ttl); err != nil {
+		log.Printf("[error] failed to cache session %s state, got error %v", sessionID, err)
+	}
+}
+
+func sessionCacheKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
+}
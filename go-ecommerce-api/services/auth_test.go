@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/utils"
+	"sync"
+	"testing"
+)
+
+func TestRefreshRotatesTokenAndDetectsReuse(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+
+	first := env.startSession(t, alice)
+	second, err := env.Auth.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same refresh token")
+	}
+	if _, err := env.authenticate(second.AccessToken); err != nil {
+		t.Fatalf("new access token got error %v", err)
+	}
+
+	// Someone replays the token that was already exchanged
+	if _, err := env.Auth.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused refresh token got error %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	// The whole session is gone, for the legitimate holder too
+	if _, err := env.Auth.Refresh(second.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("refresh after reuse got error %v, want %v", err, ErrSessionRevoked)
+	}
+	if _, err := env.authenticate(second.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("access token after reuse got error %v, want %v", err, ErrSessionRevoked)
+	}
+}
+
+func TestConcurrentRefreshesRotateOnce(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	pair := env.startSession(t, alice)
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	errs := make([]error, attempts)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = env.Auth.Refresh(pair.RefreshToken)
+		}()
+	}
+	wg.Wait()
+
+	rotated := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			rotated++
+		case !errors.Is(err, ErrRefreshTokenReused) && !errors.Is(err, ErrSessionRevoked):
+			t.Errorf("got error %v, want the token rejected as reused", err)
+		}
+	}
+	if rotated != 1 {
+		t.Errorf("token was exchanged %d times, want once", rotated)
+	}
+}
+
+func TestRefreshReadsCurrentUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(env *testEnv, user *models.User) error
+		wantErr  error
+		wantRole string
+	}{
+		{"unchanged", func(env *testEnv, user *models.User) error { return nil }, nil, "admin"},
+		{"demoted", func(env *testEnv, user *models.User) error {
+			return env.Auth.Users.SetRole(user.ID, "user")
+		}, ErrSessionRevoked, ""},
+		{"tokens invalidated", func(env *testEnv, user *models.User) error {
+			return env.Auth.Users.IncrementTokenVersion(user.ID)
+		}, ErrSessionRevoked, ""},
+		{"disabled", func(env *testEnv, user *models.User) error {
+			return env.DB.Model(user).Update("disabled", true).Error
+		}, ErrAccountUnavailable, ""},
+		{"deleted", func(env *testEnv, user *models.User) error {
+			return env.Auth.Users.Delete(user.ID)
+		}, ErrAccountUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			admin := env.createUser(t, "admin")
+			env.DB.Model(admin).Update("role", "admin")
+			admin.Role = "admin"
+
+			pair := env.startSession(t, admin)
+			if err := tt.change(env, admin); err != nil {
+				t.Fatal(err)
+			}
+
+			refreshed, err := env.Auth.Refresh(pair.RefreshToken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("refresh got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				// Rejected tokens end their session
+				if _, err := env.authenticate(pair.AccessToken); err == nil {
+					t.Error("access token is still accepted")
+				}
+				return
+			}
+
+			claims, err := utils.ValidateAccessToken(refreshed.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Role != tt.wantRole {
+				t.Errorf("new token has role %q, want %q", claims.Role, tt.wantRole)
+			}
+		})
+	}
+}
+
+// startSession logs the user in and returns their first tokens
+func (e *testEnv) startSession(t *testing.T, user *models.User) *utils.TokenPair {
+	t.Helper()
+	pair, err := e.Auth.StartSession(user, "test", "127.0.0.1", false)
+	if err != nil {
+		t.Fatalf("starting session for %s: %v", user.Name, err)
+	}
+	return pair
+}
+
+// authenticate checks an access token the way middleware.RequireAuth does
+func (e *testEnv) authenticate(accessToken string) (*models.User, error) {
+	claims, err := utils.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return e.Auth.Authenticate(claims)
+}
@@ -1,17 +1,22 @@
 package services
 
 import (
+	"fmt"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/repositories"
 )
 
 type CartServices struct {
-	Repo repositories.CartRepository
+	Repo    repositories.CartRepository
+	Coupons *CouponServices
+	Pricing *PricingServices
 }
 
-func NewCartServices(repo repositories.CartRepository) *CartServices {
+func NewCartServices(repo repositories.CartRepository, coupons *CouponServices, pricing *PricingServices) *CartServices {
 	return &CartServices{
-		Repo: repo,
+		Repo:    repo,
+		Coupons: coupons,
+		Pricing: pricing,
 	}
 }
 
@@ -19,18 +24,155 @@ func (s *CartServices) GetCartByUserID(userID uint) (*models.Cart, error) {
 	return s.Repo.GetCartByUserID(userID)
 }
 
+// GetCartSummary prices the user's cart for shipping to destination with the
+// chosen shipping method, or the cheapest one if shippingMethodID is 0
+func (s *CartServices) GetCartSummary(userID uint, destination models.Destination, shippingMethodID uint) (*models.CartSummary, error) {
+	cart, err := s.Repo.GetCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Pricing.Estimate(userID, cart, destination, shippingMethodID)
+}
+
+// GetShippingOptions lists the shipping methods that can deliver the user's
+// cart to destination, with their prices
+func (s *CartServices) GetShippingOptions(userID uint, destination models.Destination) ([]models.ShippingOption, error) {
+	cart, err := s.Repo.GetCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Pricing.Shipping.Options(cart, destination)
+}
+
+// ValidateCart checks the user's cart against the current products: deleted
+// products, quantities above stock and prices that changed since the items
+// were added. It also drops the cached cart so it shows current prices.
+func (s *CartServices) ValidateCart(userID uint) (*models.CartValidation, error) {
+	cart, err := s.Repo.RefreshCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	return validateCart(cart), nil
+}
+
+// ApplyCoupon puts a coupon on the user's cart if the cart qualifies for it
+// and returns the cart priced as GetCartSummary does
+func (s *CartServices) ApplyCoupon(userID uint, code string, destination models.Destination, shippingMethodID uint) (*models.CartSummary, error) {
+	cart, err := s.Repo.GetCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon, err := s.Coupons.Resolve(userID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.CouponCode = coupon.Code
+	quote, err := s.Pricing.Price(cart, coupon, destination, shippingMethodID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.SetCoupon(cart.ID, coupon.Code); err != nil {
+		return nil, err
+	}
+	return &models.CartSummary{Cart: *cart, PriceQuote: *quote}, nil
+}
+
+// RemoveCoupon takes the coupon off the user's cart
+func (s *CartServices) RemoveCoupon(userID uint) error {
+	cart, err := s.Repo.GetCartByUserID(userID)
+	if err != nil {
+		return err
+	}
+	return s.Repo.SetCoupon(cart.ID, "")
+}
+
 func (s *CartServices) AddItem(cartID uint, productID uint, quantity int) (*models.CartItem, error) {
 	return s.Repo.AddItem(cartID, productID, quantity)
 }
 
-func (s *CartServices) UpdateItemQuantity(id uint, quantity int) error {
+func (s *CartServices) UpdateItemQuantity(userID uint, id uint, quantity int) error {
+	if err := s.requireItemOwner(userID, id); err != nil {
+		return err
+	}
 	return s.Repo.UpdateItemQuantity(id, quantity)
 }
 
-func (s *CartServices) RemoveItem(id uint) error {
+func (s *CartServices) RemoveItem(userID uint, id uint) error {
+	if err := s.requireItemOwner(userID, id); err != nil {
+		return err
+	}
 	return s.Repo.RemoveItem(id)
 }
 
 func (s *CartServices) ClearCart(cartID uint) error {
 	return s.Repo.ClearCart(cartID)
 }
+
+// requireItemOwner checks that the cart item is in the user's own cart
+func (s *CartServices) requireItemOwner(userID uint, itemID uint) error {
+	item, err := s.Repo.GetCartItemByID(itemID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	cart, err := s.Repo.GetCartByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if item.CartID != cart.ID {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// validateCart checks each line of a freshly loaded cart against its product
+func validateCart(cart *models.Cart) *models.CartValidation {
+	warnings := []models.CartWarning{}
+	for _, item := range cart.Items {
+		product := item.Product
+		if product.ID == 0 || product.DeletedAt != nil {
+			warnings = append(warnings, models.CartWarning{
+				Code:       models.CartWarningUnavailable,
+				CartItemID: item.ID,
+				ProductID:  item.ProductID,
+				Message:    "This product is no longer available",
+			})
+			continue
+		}
+
+		if product.Stock < item.Quantity {
+			available := max(product.Stock, 0)
+			warnings = append(warnings, models.CartWarning{
+				Code:       models.CartWarningInsufficientStock,
+				CartItemID: item.ID,
+				ProductID:  item.ProductID,
+				Name:       product.Name,
+				Message:    fmt.Sprintf("Only %d left in stock", available),
+				Requested:  item.Quantity,
+				Available:  &available,
+			})
+		}
+
+		// Items added before prices were recorded have no price to compare
+		if !item.PriceAtAdd.IsZero() && item.PriceAtAdd != product.Price {
+			oldPrice, newPrice := item.PriceAtAdd, product.Price
+			warnings = append(warnings, models.CartWarning{
+				Code:       models.CartWarningPriceChanged,
+				CartItemID: item.ID,
+				ProductID:  item.ProductID,
+				Name:       product.Name,
+				Message:    fmt.Sprintf("Price changed from %s to %s", oldPrice, newPrice),
+				OldPrice:   &oldPrice,
+				NewPrice:   &newPrice,
+			})
+		}
+	}
+
+	return &models.CartValidation{
+		Valid:    len(warnings) == 0,
+		Warnings: warnings,
+	}
+}
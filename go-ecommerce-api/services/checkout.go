@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+)
+
+var ErrEmptyCart = errors.New("cart is empty")
+
+type CheckoutServices struct {
+	UnitOfWork repositories.UnitOfWork
+	OrderRepo  repositories.OrderRepository
+	CartRepo   repositories.CartRepository
+	Inventory  repositories.InventoryRepository
+	Payments   *PaymentService
+	Coupons    *CouponServices
+	Pricing    *PricingServices
+	Addresses  *AddressServices
+}
+
+func NewCheckoutServices(
+	uow repositories.UnitOfWork,
+	orderRepo repositories.OrderRepository,
+	cartRepo repositories.CartRepository,
+	inventory repositories.InventoryRepository,
+	payments *PaymentService,
+	coupons *CouponServices,
+	pricing *PricingServices,
+	addresses *AddressServices,
+) *CheckoutServices {
+	return &CheckoutServices{
+		UnitOfWork: uow,
+		OrderRepo:  orderRepo,
+		CartRepo:   cartRepo,
+		Inventory:  inventory,
+		Payments:   payments,
+		Coupons:    coupons,
+		Pricing:    pricing,
+		Addresses:  addresses,
+	}
+}
+
+// CheckoutDetails is what the customer picks at checkout. Address IDs refer
+// to the user's address book: no shipping address means their default one,
+// and no billing address means the shipping address.
+type CheckoutDetails struct {
+	ShippingMethodID        uint
+	ShippingAddressID       uint
+	BillingAddressID        uint
+	AcknowledgePriceChanges bool // order at current prices even if they changed since the items were added
+}
+
+type CheckoutResult struct {
+	Order         *models.Order
+	PaymentIntent *PaymentIntent
+	Payment       *models.Payment
+}
+
+// CreateOrder turns the user's cart into an order without starting a
+// payment. It is priced exactly as Checkout would price it.
+func (s *CheckoutServices) CreateOrder(userID uint, details CheckoutDetails) (*models.Order, error) {
+	shippingAddress, billingAddress, err := s.resolveAddresses(userID, details)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *models.Order
+
+	err = s.UnitOfWork.Do(func(tx *repositories.Tx) error {
+		var err error
+		order, err = s.placeOrder(tx, userID, details, shippingAddress, billingAddress)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Checkout creates the order, its payment intent and payment record and clears
+// the cart as a single unit. If any step fails nothing is kept and the intent is cancelled.
+// Tax and shipping are charged for the chosen method to the shipping address.
+func (s *CheckoutServices) Checkout(userID uint, details CheckoutDetails) (*CheckoutResult, error) {
+	shippingAddress, billingAddress, err := s.resolveAddresses(userID, details)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CheckoutResult{}
+
+	err = s.UnitOfWork.Do(func(tx *repositories.Tx) error {
+		// Order and stock first, so an out-of-stock cart never reaches Stripe
+		order, err := s.placeOrder(tx, userID, details, shippingAddress, billingAddress)
+		if err != nil {
+			return err
+		}
+
+		pi, payment, err := s.Payments.CreatePaymentIntentTx(tx, order)
+		if err != nil {
+			return err
+		}
+
+		order.PaymentIntentID = pi.ID
+		if err := s.OrderRepo.WithTx(tx.DB).Update(order.ID, map[string]interface{}{
+			"payment_intent_id": pi.ID,
+		}); err != nil {
+			return err
+		}
+
+		result.Order = order
+		result.PaymentIntent = pi
+		result.Payment = payment
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// resolveAddresses looks up the order's shipping and billing addresses in the
+// user's address book
+func (s *CheckoutServices) resolveAddresses(userID uint, details CheckoutDetails) (*models.Address, *models.Address, error) {
+	shipping, err := s.Addresses.Resolve(userID, details.ShippingAddressID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("shipping address: %w", err)
+	}
+	if details.BillingAddressID == 0 || details.BillingAddressID == shipping.ID {
+		return shipping, shipping, nil
+	}
+
+	billing, err := s.Addresses.GetByID(userID, details.BillingAddressID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("billing address: %w", err)
+	}
+	return shipping, billing, nil
+}
+
+// placeOrder prices the user's cart, saves it as an order, reserves its stock,
+// redeems its coupon and empties the cart within tx. The cart is checked
+// against the current products first and returns *models.CartChangedError
+// if anything blocks the order or prices changed without acknowledgement.
+func (s *CheckoutServices) placeOrder(tx *repositories.Tx, userID uint, details CheckoutDetails, shippingAddress *models.Address, billingAddress *models.Address) (*models.Order, error) {
+	cart, err := s.CartRepo.WithTx(tx.DB).RefreshCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	validation := validateCart(cart)
+	for _, warning := range validation.Warnings {
+		if warning.Blocking() || !details.AcknowledgePriceChanges {
+			return nil, &models.CartChangedError{Warnings: validation.Warnings}
+		}
+	}
+
+	quote, coupon, err := s.Pricing.QuoteTx(tx, userID, cart, shippingAddress.Destination(), details.ShippingMethodID)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		UserID:           userID,
+		Status:           models.OrderStatusPending,
+		Subtotal:         quote.Subtotal,
+		Discount:         quote.DiscountAmount(),
+		ShippingDiscount: quote.ShippingDiscount(),
+		Shipping:         quote.Shipping,
+		ShippingMethod:   quote.ShippingMethod.Code,
+		ShippingAddress:  shippingAddress.PostalAddress,
+		BillingAddress:   billingAddress.PostalAddress,
+		Tax:              quote.Tax,
+		TaxIncluded:      quote.TaxIncluded,
+		Total:            quote.Total,
+		CouponCode:       cart.CouponCode,
+		Items:            orderItemsFromQuote(quote),
+		PaymentStatus:    "pending",
+		StatusHistory:    initialStatusHistory(userID),
+	}
+
+	if err := s.OrderRepo.WithTx(tx.DB).Create(order); err != nil {
+		return nil, err
+	}
+
+	if err := s.Inventory.WithTx(tx.DB).Reserve(order.ID, order.Items); err != nil {
+		return nil, err
+	}
+
+	if coupon != nil {
+		if err := s.Coupons.RedeemTx(tx, coupon, order); err != nil {
+			return nil, err
+		}
+		if err := s.CartRepo.WithTx(tx.DB).SetCoupon(cart.ID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.CartRepo.WithTx(tx.DB).ClearCart(cart.ID); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// orderItemsFromQuote copies the quoted cart lines to order items
+func orderItemsFromQuote(quote *models.PriceQuote) []models.OrderItem {
+	orderItems := make([]models.OrderItem, len(quote.Lines))
+	for i, line := range quote.Lines {
+		orderItems[i] = models.OrderItem{
+			ProductID:    line.ProductID,
+			Quantity:     line.Quantity,
+			Price:        line.UnitPrice,
+			Discount:     line.Discount,
+			Tax:          line.Tax,
+			TaxRate:      line.TaxRate,
+			TaxInclusive: line.TaxInclusive,
+		}
+	}
+	return orderItems
+}
+
+// initialStatusHistory records that the customer placed the order
+func initialStatusHistory(userID uint) []models.OrderStatusHistory {
+	return []models.OrderStatusHistory{{
+		ToStatus:  models.OrderStatusPending,
+		ChangedBy: &userID,
+		Source:    models.StatusSourceCustomer,
+	}}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentCheckoutsDoNotOversell(t *testing.T) {
+	env := newTestEnv(t)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	const buyers = 10
+	users := make([]*models.User, buyers)
+	for i := range users {
+		users[i] = env.createUser(t, fmt.Sprintf("buyer%d", i))
+		env.createAddress(t, users[i])
+		env.addToCart(t, users[i], phone, 1)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, buyers)
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = env.Checkout.Checkout(user.ID, CheckoutDetails{ShippingMethodID: env.shippingMethodID})
+		}()
+	}
+	wg.Wait()
+
+	placed := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			placed++
+		case !isOutOfStock(err):
+			t.Errorf("buyer%d got error %v, want out of stock", i, err)
+		}
+	}
+	if placed != 5 {
+		t.Errorf("%d orders were placed, want 5", placed)
+	}
+	if stock := env.productStock(t, phone.ID); stock != 0 {
+		t.Errorf("stock is %d, want 0", stock)
+	}
+
+	var reserved int64
+	env.DB.Model(&models.StockReservation{}).Where("product_id = ?", phone.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&reserved)
+	if reserved != 5 {
+		t.Errorf("%d units are reserved, want 5", reserved)
+	}
+}
+
+// isOutOfStock reports whether a checkout failed for lack of stock, either
+// when the cart was validated or when its stock was reserved
+func isOutOfStock(err error) bool {
+	var shortage *models.InsufficientStockError
+	if errors.As(err, &shortage) {
+		return true
+	}
+	var changed *models.CartChangedError
+	if errors.As(err, &changed) {
+		for _, warning := range changed.Warnings {
+			if warning.Code != models.CartWarningInsufficientStock {
+				return false
+			}
+		}
+		return len(changed.Warnings) > 0
+	}
+	return false
+}
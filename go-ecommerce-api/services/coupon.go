@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCouponUnavailable = errors.New("coupon is not available")
+	ErrCouponCodeTaken   = errors.New("coupon code already exists")
+)
+
+type CouponServices struct {
+	Repo *repositories.CouponRepository
+}
+
+func NewCouponServices(repo *repositories.CouponRepository) *CouponServices {
+	return &CouponServices{
+		Repo: repo,
+	}
+}
+
+func (s *CouponServices) GetAll() ([]models.Coupon, error) {
+	return s.Repo.List()
+}
+
+func (s *CouponServices) GetByID(id uint) (*models.Coupon, error) {
+	coupon, err := s.Repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return coupon, nil
+}
+
+// Create validates and saves a new coupon
+func (s *CouponServices) Create(coupon *models.Coupon) error {
+	coupon.Code = models.NormalizeCouponCode(coupon.Code)
+	if err := coupon.Validate(); err != nil {
+		return err
+	}
+	if err := s.requireUniqueCode(0, coupon.Code); err != nil {
+		return err
+	}
+
+	coupon.ID = 0
+	coupon.TimesRedeemed = 0
+	return s.Repo.Create(coupon)
+}
+
+// Update replaces the coupon's settings. Its redemption count is kept.
+func (s *CouponServices) Update(id uint, coupon *models.Coupon) error {
+	coupon.Code = models.NormalizeCouponCode(coupon.Code)
+	if err := coupon.Validate(); err != nil {
+		return err
+	}
+	if err := s.requireUniqueCode(id, coupon.Code); err != nil {
+		return err
+	}
+
+	return notFound(s.Repo.Update(id, coupon))
+}
+
+func (s *CouponServices) Delete(id uint) error {
+	return notFound(s.Repo.Delete(id))
+}
+
+// Resolve returns the coupon for code if the user can redeem it right now
+func (s *CouponServices) Resolve(userID uint, code string) (*models.Coupon, error) {
+	return s.usableCoupon(s.Repo, userID, code, false)
+}
+
+// ResolveTx is Resolve within a unit of work. The coupon stays locked until
+// the transaction ends, so concurrent checkouts can't exceed its limits.
+func (s *CouponServices) ResolveTx(tx *repositories.Tx, userID uint, code string) (*models.Coupon, error) {
+	return s.usableCoupon(s.Repo.WithTx(tx.DB), userID, code, true)
+}
+
+// RedeemTx records the coupon's use on a newly placed order. The use is
+// released again if the order is cancelled or expires.
+func (s *CouponServices) RedeemTx(tx *repositories.Tx, coupon *models.Coupon, order *models.Order) error {
+	return s.Repo.WithTx(tx.DB).Redeem(&models.CouponRedemption{
+		CouponID: coupon.ID,
+		UserID:   order.UserID,
+		OrderID:  order.ID,
+		Code:     coupon.Code,
+		Discount: order.Discount,
+	})
+}
+
+func (s *CouponServices) usableCoupon(repo *repositories.CouponRepository, userID uint, code string, lock bool) (*models.Coupon, error) {
+	code = models.NormalizeCouponCode(code)
+
+	var coupon *models.Coupon
+	var err error
+	if lock {
+		coupon, err = repo.GetByCodeForUpdate(code)
+	} else {
+		coupon, err = repo.GetByCode(code)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: unknown code %s", ErrCouponUnavailable, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case coupon.Disabled:
+		return nil, fmt.Errorf("%w: %s is no longer active", ErrCouponUnavailable, code)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return nil, fmt.Errorf("%w: %s is not valid yet", ErrCouponUnavailable, code)
+	case coupon.ExpiresAt != nil && !now.Before(*coupon.ExpiresAt):
+		return nil, fmt.Errorf("%w: %s has expired", ErrCouponUnavailable, code)
+	case coupon.MaxRedemptions > 0 && coupon.TimesRedeemed >= coupon.MaxRedemptions:
+		return nil, fmt.Errorf("%w: %s has been used up", ErrCouponUnavailable, code)
+	}
+
+	if coupon.MaxRedemptionsPerUser > 0 {
+		used, err := repo.CountUserRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(coupon.MaxRedemptionsPerUser) {
+			return nil, fmt.Errorf("%w: you have already used %s", ErrCouponUnavailable, code)
+		}
+	}
+
+	return coupon, nil
+}
+
+// requireUniqueCode checks that no other coupon uses code
+func (s *CouponServices) requireUniqueCode(id uint, code string) error {
+	existing, err := s.Repo.GetByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return ErrCouponCodeTaken
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentCheckoutsRespectCouponLimit(t *testing.T) {
+	env := newTestEnv(t)
+	phone := env.createProduct(t, "Phone", 19999, 20)
+	coupon := env.createCoupon(t, "SAVE10", 3)
+
+	const buyers = 8
+	users := make([]*models.User, buyers)
+	for i := range users {
+		users[i] = env.createUser(t, fmt.Sprintf("buyer%d", i))
+		address := env.createAddress(t, users[i])
+		env.addToCart(t, users[i], phone, 1)
+		if _, err := env.Cart.ApplyCoupon(users[i].ID, coupon.Code, address.Destination(), env.shippingMethodID); err != nil {
+			t.Fatalf("applying coupon for buyer%d: %v", i, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, buyers)
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = env.Checkout.Checkout(user.ID, CheckoutDetails{ShippingMethodID: env.shippingMethodID})
+		}()
+	}
+	wg.Wait()
+
+	placed := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			placed++
+		case !errors.Is(err, ErrCouponUnavailable):
+			t.Errorf("buyer%d got error %v, want %v", i, err, ErrCouponUnavailable)
+		}
+	}
+	if placed != 3 {
+		t.Errorf("%d orders were placed, want 3", placed)
+	}
+
+	var redemptions int64
+	env.DB.Model(&models.CouponRedemption{}).Where("coupon_id = ?", coupon.ID).Count(&redemptions)
+	if times := env.couponRedeemed(t, coupon.ID); times != 3 || redemptions != 3 {
+		t.Errorf("coupon was redeemed %d times with %d redemptions, want 3", times, redemptions)
+	}
+}
+
+func TestCouponIsReleasedWhenOrderEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(env *testEnv, order *models.Order) error
+	}{
+		{"cancelled by customer", func(env *testEnv, order *models.Order) error {
+			_, err := env.Orders.CancelOrder(order.UserID, order.ID, "Changed my mind")
+			return err
+		}},
+		{"cancelled by admin", func(env *testEnv, order *models.Order) error {
+			return env.Orders.UpdateStatus(order.ID, models.OrderStatusCancelled, 1)
+		}},
+		{"expired", func(env *testEnv, order *models.Order) error {
+			env.DB.Model(&models.Order{}).Where("id = ?", order.ID).UpdateColumn("created_at", time.Now().Add(-2*time.Hour))
+			expired, err := env.Expiry.ExpireOrders(time.Hour)
+			if err == nil && expired != 1 {
+				err = fmt.Errorf("expired %d orders, want 1", expired)
+			}
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			phone := env.createProduct(t, "Phone", 19999, 5)
+			coupon := env.createCoupon(t, "ONCE", 1)
+
+			alice := env.createUser(t, "alice")
+			address := env.createAddress(t, alice)
+			env.addToCart(t, alice, phone, 1)
+			if _, err := env.Cart.ApplyCoupon(alice.ID, coupon.Code, address.Destination(), env.shippingMethodID); err != nil {
+				t.Fatal(err)
+			}
+			result := env.checkout(t, alice)
+			if times := env.couponRedeemed(t, coupon.ID); times != 1 {
+				t.Fatalf("coupon was redeemed %d times after checkout, want 1", times)
+			}
+
+			if err := tt.end(env, result.Order); err != nil {
+				t.Fatal(err)
+			}
+			if times := env.couponRedeemed(t, coupon.ID); times != 0 {
+				t.Errorf("coupon was redeemed %d times, want 0", times)
+			}
+
+			// The released use is available to someone else
+			bob := env.createUser(t, "bob")
+			address = env.createAddress(t, bob)
+			env.addToCart(t, bob, phone, 1)
+			if _, err := env.Cart.ApplyCoupon(bob.ID, coupon.Code, address.Destination(), env.shippingMethodID); err != nil {
+				t.Errorf("applying released coupon got error %v", err)
+			}
+		})
+	}
+}
+
+// createCoupon adds a 10% coupon that can be redeemed limit times in total
+func (e *testEnv) createCoupon(t *testing.T, code string, limit int) *models.Coupon {
+	t.Helper()
+	coupon := &models.Coupon{Code: code, Type: models.CouponPercentage, PercentOff: 10, MaxRedemptions: limit}
+	if err := e.DB.Create(coupon).Error; err != nil {
+		t.Fatalf("creating coupon %s: %v", code, err)
+	}
+	return coupon
+}
+
+func (e *testEnv) couponRedeemed(t *testing.T, couponID uint) int {
+	t.Helper()
+	var coupon models.Coupon
+	if err := e.DB.First(&coupon, couponID).Error; err != nil {
+		t.Fatalf("loading coupon %d: %v", couponID, err)
+	}
+	return coupon.TimesRedeemed
+}
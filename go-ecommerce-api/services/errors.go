@@ -0,0 +1,27 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a requested resource doesn't exist
+var ErrNotFound = errors.New("resource not found")
+
+// notFound translates gorm's missing-record error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// requireOwner hides resources that belong to someone else behind ErrNotFound,
+// so a user can't tell another user's IDs apart from IDs that don't exist
+func requireOwner(ownerID uint, userID uint) error {
+	if ownerID != userID {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-ecommerce-api/config"
+	"go-ecommerce-api/database"
+	"go-ecommerce-api/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+// TooManyAttemptsError is returned when logins for an email or from an IP are
+// throttled
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginGuard throttles password guessing with failure counters in Redis.
+// After a few failures for an email each attempt has to wait, twice as long
+// every time, and too many failures for an email or from an IP lock it out.
+// Counters exist for any email, registered or not, so throttling doesn't
+// reveal which accounts exist. If Redis is down, logins aren't throttled.
+type LoginGuard struct {
+	Redis         database.RedisClient
+	BackoffAfter  int
+	BackoffBase   time.Duration
+	MaxFailures   int
+	IPMaxFailures int
+	Lockout       time.Duration
+}
+
+func NewLoginGuard(redis database.RedisClient, cfg *config.Config) *LoginGuard {
+	return &LoginGuard{
+		Redis:         redis,
+		BackoffAfter:  cfg.LoginBackoffAfter,
+		BackoffBase:   cfg.LoginBackoffBase,
+		MaxFailures:   cfg.LoginMaxFailures,
+		IPMaxFailures: cfg.LoginIPMaxFailures,
+		Lockout:       cfg.LoginLockout,
+	}
+}
+
+// Check returns a *TooManyAttemptsError if the email or the IP has to wait
+// before trying again
+func (g *LoginGuard) Check(email string, ip string) error {
+	ctx := context.Background()
+	var wait time.Duration
+	for _, key := range []string{g.lockKey("email", emailKey(email)), g.lockKey("ip", ip)} {
+		ttl, err := g.Redis.TTL(ctx, key)
+		if err != nil {
+			log.Printf("[error] login throttle check failed, got error %v", err)
+			return nil
+		}
+		wait = max(wait, ttl)
+	}
+
+	if wait > 0 {
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Fail records a failed login and blocks the next attempts as needed
+func (g *LoginGuard) Fail(email string, ip string) {
+	ctx := context.Background()
+
+	failures, err := g.Redis.IncrWithTTL(ctx, g.failureKey("email", emailKey(email)), g.Lockout)
+	if err != nil {
+		log.Printf("[error] failed to count login failure, got error %v", err)
+		return
+	}
+	switch {
+	case g.MaxFailures > 0 && failures >= int64(g.MaxFailures):
+		g.block("email", emailKey(email), g.Lockout)
+	case g.BackoffAfter > 0 && failures >= int64(g.BackoffAfter):
+		delay := g.BackoffBase << min(failures-int64(g.BackoffAfter), 20)
+		g.block("email", emailKey(email), min(delay, g.Lockout))
+	}
+
+	ipFailures, err := g.Redis.IncrWithTTL(ctx, g.failureKey("ip", ip), g.Lockout)
+	if err != nil {
+		log.Printf("[error] failed to count login failure, got error %v", err)
+		return
+	}
+	if g.IPMaxFailures > 0 && ipFailures >= int64(g.IPMaxFailures) {
+		g.block("ip", ip, g.Lockout)
+	}
+}
+
+// Succeed clears the email's failures. The IP's stay, so one valid account
+// can't be used to reset the counter while guessing others.
+func (g *LoginGuard) Succeed(email string) {
+	ctx := context.Background()
+	g.Redis.Del(ctx, g.failureKey("email", emailKey(email)))
+	g.Redis.Del(ctx, g.lockKey("email", emailKey(email)))
+}
+
+func (g *LoginGuard) block(kind string, id string, d time.Duration) {
+	if err := g.Redis.SetWithTTL(context.Background(), g.lockKey(kind, id), 1, d); err != nil {
+		log.Printf("[error] failed to throttle logins, got error %v", err)
+	}
+}
+
+func (g *LoginGuard) failureKey(kind string, id string) string {
+	return fmt.Sprintf("login:failures:%s:%s", kind, id)
+}
+
+func (g *LoginGuard) lockKey(kind string, id string) string {
+	return fmt.Sprintf("login:locked:%s:%s", kind, id)
+}
+
+// emailKey identifies an email in Redis without storing the address
+func emailKey(email string) string {
+	return utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
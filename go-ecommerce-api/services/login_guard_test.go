@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/utils"
+	"testing"
+	"time"
+)
+
+func TestLoginGuardBacksOffThenLocksOut(t *testing.T) {
+	env := newTestEnv(t)
+	guard := env.Guard
+
+	// Backoff starts at the third failure and doubles; the fifth locks out
+	wants := []time.Duration{0, 0, time.Second, 2 * time.Second, guard.Lockout}
+	for i, want := range wants {
+		guard.Fail("alice@example.com", "10.0.0.1")
+
+		err := guard.Check("alice@example.com", "10.0.0.2")
+		var throttled *TooManyAttemptsError
+		if want == 0 {
+			if err != nil {
+				t.Fatalf("after %d failures got error %v, want none", i+1, err)
+			}
+			continue
+		}
+		if !errors.As(err, &throttled) {
+			t.Fatalf("after %d failures got error %v, want *TooManyAttemptsError", i+1, err)
+		}
+		if throttled.RetryAfter > want || throttled.RetryAfter < want-time.Second {
+			t.Errorf("after %d failures retry after %s, want %s", i+1, throttled.RetryAfter, want)
+		}
+	}
+
+	// Other emails aren't affected
+	if err := guard.Check("bob@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("another email got error %v", err)
+	}
+
+	guard.Succeed("Alice@Example.com ")
+	if err := guard.Check("alice@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("after a success got error %v, want none", err)
+	}
+}
+
+func TestLoginGuardLocksOutIP(t *testing.T) {
+	env := newTestEnv(t)
+	guard := env.Guard
+
+	// Spread over many emails so no single one is throttled
+	for i := 0; i < guard.IPMaxFailures; i++ {
+		guard.Fail(fmt.Sprintf("user%d@example.com", i), "10.0.0.1")
+	}
+
+	var throttled *TooManyAttemptsError
+	if err := guard.Check("new@example.com", "10.0.0.1"); !errors.As(err, &throttled) {
+		t.Errorf("IP got error %v, want *TooManyAttemptsError", err)
+	}
+	if err := guard.Check("new@example.com", "10.0.0.2"); err != nil {
+		t.Errorf("another IP got error %v, want none", err)
+	}
+}
+
+func TestLoginDoesNotRevealAccounts(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	hash, err := utils.HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.DB.Model(alice).Update("password", hash)
+
+	for _, email := range []string{alice.Email, "nobody@example.com"} {
+		for i := 0; i < env.Guard.BackoffAfter-1; i++ {
+			if _, _, err := env.Auth.Login(email, "wrong password", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("%s got error %v, want %v", email, err, ErrInvalidCredentials)
+			}
+		}
+		// Unknown emails are throttled just like real ones
+		env.Auth.Login(email, "wrong password", "10.0.0.1")
+		var throttled *TooManyAttemptsError
+		if _, _, err := env.Auth.Login(email, "wrong password", "10.0.0.1"); !errors.As(err, &throttled) {
+			t.Errorf("%s got error %v, want *TooManyAttemptsError", email, err)
+		}
+	}
+
+	// While throttled even the right password is turned away
+	if _, _, err := env.Auth.Login(alice.Email, "correct horse battery", "10.0.0.1"); err == nil {
+		t.Error("throttled login succeeded")
+	}
+}
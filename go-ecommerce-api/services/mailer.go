@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+	"go-ecommerce-api/config"
+)
+
+// Email is a plain text message to one recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. The sender is chosen by config, so local setups can
+// read emails from the log instead of running a mail server.
+type Mailer interface {
+	Send(email Email) error
+}
+
+// NewMailer returns the mail sender selected by cfg.MailSender
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.MailSender {
+	case config.MailSenderSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail sender")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case config.MailSenderLog:
+		return NewLogMailer(cfg.MailFrom, cfg.MailLogFile), nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", cfg.MailSender)
+	}
+}
+
+// formatEmail renders the message in RFC 5322 form
+func formatEmail(from string, email Email) []byte {
+	return fmt.Appendf(nil, "From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		from, email.To, email.Subject, email.Body)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// LogMailer is a Mailer for local development. Emails are appended to a file
+// if one is given, or written to the log, so links in them can be copied out.
+type LogMailer struct {
+	mu   sync.Mutex
+	from string
+	path string
+}
+
+func NewLogMailer(from string, path string) *LogMailer {
+	return &LogMailer{
+		from: from,
+		path: path,
+	}
+}
+
+func (m *LogMailer) Send(email Email) error {
+	message := formatEmail(m.from, email)
+	if m.path == "" {
+		log.Printf("[mail]\n%s", message)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(message, '\n'))
+	return err
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPMailer sends emails through an SMTP server. The connection is upgraded
+// with STARTTLS when the server offers it; credentials are only sent over TLS
+// or to localhost.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port int, username string, password string, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: fmt.Sprintf("%s:%d", host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(email Email) error {
+	if strings.ContainsAny(email.To+email.Subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{email.To}, formatEmail(m.from, email)); err != nil {
+		return fmt.Errorf("sending email to %s: %w", email.To, err)
+	}
+	return nil
+}
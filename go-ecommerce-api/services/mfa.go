@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"go-ecommerce-api/database"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMFACode       = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge  = errors.New("invalid or expired two-factor challenge")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled        = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrolmentNotFound = errors.New("no two-factor enrolment in progress")
+)
+
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAServices manages TOTP second factors and recovery codes, and the second
+// step of logging in to an account that has one
+type MFAServices struct {
+	unitOfWork repositories.UnitOfWork
+	Users      UserServices
+	Repo       *repositories.MFARepository
+	Auth       *AuthServices
+	Guard      *LoginGuard
+	Redis      database.RedisClient
+	Issuer     string // shown in authenticator apps
+}
+
+func NewMFAServices(uow repositories.UnitOfWork, users UserServices, repo *repositories.MFARepository, auth *AuthServices, guard *LoginGuard, redis database.RedisClient, issuer string) *MFAServices {
+	return &MFAServices{
+		unitOfWork: uow,
+		Users:      users,
+		Repo:       repo,
+		Auth:       auth,
+		Guard:      guard,
+		Redis:      redis,
+		Issuer:     issuer,
+	}
+}
+
+// Status returns whether the user has a second factor and how many recovery
+// codes they have left
+func (s *MFAServices) Status(userID uint) (*models.MFAStatus, error) {
+	enabled, err := s.Repo.HasConfirmedTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := s.Repo.CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MFAStatus{TOTPEnabled: enabled, RecoveryCodesRemaining: int(remaining)}, nil
+}
+
+// BeginTOTPEnrolment creates a new secret for the user's authenticator app.
+// It only takes effect once confirmed with a code from the app.
+func (s *MFAServices) BeginTOTPEnrolment(userID uint) (*models.TOTPEnrolment, error) {
+	user, err := s.Users.GetByID(userID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	enabled, err := s.Repo.HasConfirmedTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.StartTOTP(&models.TOTPFactor{UserID: userID, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrolment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the pending factor with a code from the app and
+// returns the user's recovery codes, which are only ever shown here
+func (s *MFAServices) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	factor, err := s.Repo.GetTOTP(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFAEnrolmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := utils.MatchTOTP(factor.Secret, code, time.Now(), factor.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, records, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.ConfirmTOTP(userID, step, records); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFAEnrolmentNotFound
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the user's second factor. It takes a current code or a
+// recovery code, so a stolen session alone can't turn it off. Every session is
+// logged out with it, so none keeps the second factor it was started with.
+func (s *MFAServices) DisableTOTP(userID uint, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+
+	return s.unitOfWork.Do(func(tx *repositories.Tx) error {
+		if err := s.Repo.WithTx(tx.DB).DeleteTOTP(userID); err != nil {
+			return err
+		}
+		if err := s.Users.IncrementTokenVersionTx(tx, userID); err != nil {
+			return err
+		}
+		return s.Auth.LogoutAllTx(tx, userID)
+	})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a
+// current code or a recovery code
+func (s *MFAServices) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a code from the user's authenticator app, or one of their
+// recovery codes, which is then used up
+func (s *MFAServices) Verify(userID uint, code string) error {
+	factor, err := s.Repo.GetTOTP(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && factor.ConfirmedAt == nil) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	if step, ok := utils.MatchTOTP(factor.Secret, code, time.Now(), factor.LastUsedStep); ok {
+		used, err := s.Repo.UseTOTPStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.Repo.UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// Challenge returns the token that stands for a checked password until the
+// user gives their second factor
+func (s *MFAServices) Challenge(user *models.User) (string, error) {
+	challengeID, err := utils.NewTokenID()
+	if err != nil {
+		return "", err
+	}
+	return utils.GenerateMFAChallenge(user, challengeID)
+}
+
+// CompleteChallenge checks the second factor for a challenge from Challenge
+// and returns the user to start a session for. Wrong codes count towards the
+// same throttling as wrong passwords, and each challenge can be completed
+// only once.
+func (s *MFAServices) CompleteChallenge(challenge string, code string, ip string) (*models.User, error) {
+	claims, err := utils.ValidateMFAChallenge(challenge)
+	if err != nil || claims.ID == "" {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.Users.GetByID(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if err := s.Guard.Check(user.Email, ip); err != nil {
+		return nil, err
+	}
+	if err := s.Verify(user.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.Guard.Fail(user.Email, ip)
+		}
+		return nil, err
+	}
+
+	// Burn the challenge for as long as it could still be used
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl > 0 {
+		first, err := s.Redis.SetNX(context.Background(), fmt.Sprintf("mfa:challenge:%s", claims.ID), 1, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			return nil, ErrInvalidMFAChallenge
+		}
+	}
+
+	s.Guard.Succeed(user.Email)
+	return user, nil
+}
+
+// newRecoveryCodes returns fresh recovery codes and the records to store
+// for them
+func newRecoveryCodes(userID uint) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(raw)}
+	}
+	return codes, records, nil
+}
+
+// normalizeRecoveryCode accepts recovery codes with or without the dash and
+// in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/utils"
+	"testing"
+	"time"
+)
+
+func TestDisablingTOTPLogsOutEverySession(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+
+	enrolment, err := env.MFA.BeginTOTPEnrolment(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := utils.TOTPCode(enrolment.Secret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err := env.MFA.ConfirmTOTP(alice.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	phone := env.startSession(t, alice)
+	laptop := env.startSession(t, alice)
+	if _, err := env.authenticate(phone.AccessToken); err != nil {
+		t.Fatalf("access token got error %v", err)
+	}
+
+	if err := env.MFA.DisableTOTP(alice.ID, recoveryCodes[0]); err != nil {
+		t.Fatal(err)
+	}
+	status, err := env.MFA.Status(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.TOTPEnabled {
+		t.Error("two-factor authentication is still enabled")
+	}
+
+	for _, pair := range []*utils.TokenPair{phone, laptop} {
+		if _, err := env.authenticate(pair.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+			t.Errorf("old access token got error %v, want %v", err, ErrSessionRevoked)
+		}
+		if _, err := env.Auth.Refresh(pair.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+			t.Errorf("old refresh token got error %v, want %v", err, ErrSessionRevoked)
+		}
+	}
+
+	// Logging in again works without a second factor
+	if _, err := env.authenticate(env.startSession(t, env.user(t, alice.ID)).AccessToken); err != nil {
+		t.Errorf("new session got error %v", err)
+	}
+}
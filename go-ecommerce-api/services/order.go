@@ -1,22 +1,53 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidOrderStatus  = errors.New("invalid order status")
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
 )
 
 type OrderServices struct {
-	Repo repositories.OrderRepository
+	Repo        repositories.OrderRepository
+	PaymentRepo *repositories.PaymentRepository
+	Inventory   repositories.InventoryRepository
+	Payments    *PaymentService
+	Refunds     *RefundServices
 }
 
-func NewOrderServices(repo repositories.OrderRepository) *OrderServices {
+func NewOrderServices(
+	repo repositories.OrderRepository,
+	paymentRepo *repositories.PaymentRepository,
+	inventory repositories.InventoryRepository,
+	payments *PaymentService,
+	refunds *RefundServices,
+) *OrderServices {
 	return &OrderServices{
-		Repo: repo,
+		Repo:        repo,
+		PaymentRepo: paymentRepo,
+		Inventory:   inventory,
+		Payments:    payments,
+		Refunds:     refunds,
 	}
 }
 
-func (s *OrderServices) GetOrderByID(id uint) (*models.Order, error) {
-	return s.Repo.GetOrderByID(id)
+// GetOrderByID returns one of the user's orders
+func (s *OrderServices) GetOrderByID(userID uint, id uint) (*models.Order, error) {
+	order, err := s.Repo.GetOrderByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := requireOwner(order.UserID, userID); err != nil {
+		return nil, err
+	}
+	return order, nil
 }
 
 func (s *OrderServices) GetOrdersByUserID(userID uint) ([]models.Order, error) {
@@ -27,17 +58,97 @@ func (s *OrderServices) GetAllOrders() ([]models.Order, error) {
 	return s.Repo.GetAllOrders()
 }
 
-func (s *OrderServices) Create(order *models.Order) error {
-	return s.Repo.Create(order)
-}
+// UpdateStatus lets an admin move an order along the status state machine.
+// Cancelling or expiring an order settles its payment and gives its stock
+// back, just like a customer's cancellation.
+func (s *OrderServices) UpdateStatus(id uint, status string, adminID uint) error {
+	if !models.IsValidOrderStatus(status) {
+		return ErrInvalidOrderStatus
+	}
+
+	change := models.OrderStatusHistory{
+		ToStatus:  status,
+		ChangedBy: &adminID,
+		Source:    models.StatusSourceAdmin,
+	}
+
+	if status == models.OrderStatusCancelled || status == models.OrderStatusExpired {
+		order, err := s.Repo.GetOrderByID(id)
+		if err != nil {
+			return notFound(err)
+		}
+		if !models.CanTransitionOrderStatus(order.Status, status) {
+			return fmt.Errorf("%w: %s to %s", models.ErrInvalidOrderTransition, order.Status, status)
+		}
+		return s.cancel(order, change, "Order cancelled by admin")
+	}
 
-func (s *OrderServices) UpdateStatus(id uint, status string) error {
-	return s.Repo.UpdateStatus(id, status)
+	return notFound(s.Repo.UpdateStatus(id, change))
 }
 
-func (s *OrderServices) CreateOrderWithPayment(order *models.Order) (*models.Order, error) {
-	if err := s.Repo.Create(order); err != nil {
+// CancelOrder cancels a pending or processing order on behalf of its owner.
+// An unpaid intent is cancelled, a paid one is refunded in full, and the
+// order's stock goes back on sale.
+func (s *OrderServices) CancelOrder(userID uint, orderID uint, reason string) (*models.Order, error) {
+	order, err := s.GetOrderByID(userID, orderID)
+	if err != nil {
 		return nil, err
 	}
-	return order, nil
+	if !models.CanTransitionOrderStatus(order.Status, models.OrderStatusCancelled) {
+		return nil, ErrOrderNotCancellable
+	}
+
+	err = s.cancel(order, models.OrderStatusHistory{
+		ToStatus:  models.OrderStatusCancelled,
+		ChangedBy: &userID,
+		Source:    models.StatusSourceCustomer,
+		Note:      reason,
+	}, "Order cancelled by customer")
+	if errors.Is(err, models.ErrInvalidOrderTransition) {
+		return nil, ErrOrderNotCancellable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.GetOrderByID(orderID)
+}
+
+// cancel voids or refunds the order's payment so that Payment.Status and
+// Order.PaymentStatus both end up cancelled or refunded, gives the order's
+// stock back and applies change, which moves it to cancelled or expired.
+// refundReason is recorded on the refund of a paid order.
+func (s *OrderServices) cancel(order *models.Order, change models.OrderStatusHistory, refundReason string) error {
+	payment, err := s.PaymentRepo.GetPaymentByOrderID(order.ID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Created without a payment intent, nothing to void
+		if err := s.Repo.Update(order.ID, map[string]interface{}{
+			"payment_status": "cancelled",
+		}); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		switch payment.Status {
+		case "succeeded", "partially_refunded":
+			if _, err := s.Refunds.RefundOrder(order.ID, nil, refundReason); err != nil {
+				return err
+			}
+		case "refunded", "cancelled", "canceled":
+		case "disputed":
+			// The money is tied up in the chargeback until it is resolved
+			return ErrOrderNotCancellable
+		default:
+			// Voids the payment, releases the stock and applies change in one transaction
+			_, err := s.Payments.cancelPaymentIntent(payment.PaymentIntentID, &change)
+			return err
+		}
+	}
+
+	if err := s.Inventory.Release(order.ID); err != nil {
+		return err
+	}
+	return s.Repo.UpdateStatus(order.ID, change)
 }
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// errOrderNotExpirable is returned for orders that were paid, or may still be
+var errOrderNotExpirable = errors.New("order can not be expired")
+
+type OrderExpiryServices struct {
+	Repo        repositories.OrderRepository
+	PaymentRepo *repositories.PaymentRepository
+	Inventory   repositories.InventoryRepository
+	CartRepo    repositories.CartRepository
+	Payments    *PaymentService
+	RestoreCart bool // put the items of expired orders back in the user's cart
+}
+
+func NewOrderExpiryServices(
+	repo repositories.OrderRepository,
+	paymentRepo *repositories.PaymentRepository,
+	inventory repositories.InventoryRepository,
+	cartRepo repositories.CartRepository,
+	payments *PaymentService,
+	restoreCart bool,
+) *OrderExpiryServices {
+	return &OrderExpiryServices{
+		Repo:        repo,
+		PaymentRepo: paymentRepo,
+		Inventory:   inventory,
+		CartRepo:    cartRepo,
+		Payments:    payments,
+		RestoreCart: restoreCart,
+	}
+}
+
+// ExpireOrders expires the pending orders that weren't paid within olderThan
+// and returns how many were expired. An order that fails is logged and
+// retried on the next run.
+func (s *OrderExpiryServices) ExpireOrders(olderThan time.Duration) (int, error) {
+	orders, err := s.Repo.GetPendingOrdersBefore(time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range orders {
+		err := s.expireOrder(&orders[i])
+		if errors.Is(err, errOrderNotExpirable) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[error] failed to expire order %d, got error %v", orders[i].ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Run expires unpaid orders every interval until ctx is done
+func (s *OrderExpiryServices) Run(ctx context.Context, interval time.Duration, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireOrders(olderThan)
+			if err != nil {
+				log.Printf("[error] order expiry failed, got error %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("order expiry: expired %d unpaid orders", expired)
+			}
+		}
+	}
+}
+
+// expireOrder voids the order's payment intent, gives its stock back and
+// moves it to expired
+func (s *OrderExpiryServices) expireOrder(order *models.Order) error {
+	if err := s.voidPayment(order); err != nil {
+		return err
+	}
+
+	if err := s.Inventory.Release(order.ID); err != nil {
+		return err
+	}
+
+	err := s.Repo.UpdateStatus(order.ID, models.OrderStatusHistory{
+		ToStatus: models.OrderStatusExpired,
+		Source:   models.StatusSourceSystem,
+		Note:     "Not paid in time",
+	})
+	if err != nil {
+		// Paid or cancelled since it was loaded
+		if errors.Is(err, models.ErrInvalidOrderTransition) {
+			return errOrderNotExpirable
+		}
+		return err
+	}
+
+	if s.RestoreCart {
+		s.restoreCart(order)
+	}
+	return nil
+}
+
+// voidPayment cancels the order's payment intent. Orders whose payment has
+// succeeded or is still processing are left alone.
+func (s *OrderExpiryServices) voidPayment(order *models.Order) error {
+	payment, err := s.PaymentRepo.GetPaymentByOrderID(order.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Created without a payment intent, nothing to void
+		return s.Repo.Update(order.ID, map[string]interface{}{
+			"payment_status": "cancelled",
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	switch payment.Status {
+	case "cancelled", "canceled":
+		return nil
+	case IntentProcessing:
+		return errOrderNotExpirable
+	}
+	if isSettledPayment(payment.Status) {
+		return errOrderNotExpirable
+	}
+
+	// Fails if the customer paid after all, reconciliation picks that up
+	_, err = s.Payments.cancelPaymentIntent(payment.PaymentIntentID, nil)
+	return err
+}
+
+// restoreCart puts the expired order's items back in the user's cart so they
+// can check out again. Failures only cost the user a convenience, so they are
+// logged rather than returned.
+func (s *OrderExpiryServices) restoreCart(order *models.Order) {
+	cart, err := s.CartRepo.GetCartByUserID(order.UserID)
+	if err != nil {
+		log.Printf("[error] failed to restore cart for expired order %d, got error %v", order.ID, err)
+		return
+	}
+
+	for _, item := range order.Items {
+		// The product has been deleted since
+		if item.Product.ID == 0 {
+			continue
+		}
+		if _, err := s.CartRepo.AddItem(cart.ID, item.ProductID, item.Quantity); err != nil {
+			log.Printf("[error] failed to restore product %d for expired order %d, got error %v", item.ProductID, order.ID, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"testing"
+)
+
+func TestAdminCancelReleasesStockAndSettlesPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		paid        bool
+		status      string
+		wantPayment string
+	}{
+		{"cancel unpaid order", false, models.OrderStatusCancelled, "cancelled"},
+		{"expire unpaid order", false, models.OrderStatusExpired, "cancelled"},
+		{"cancel paid order", true, models.OrderStatusCancelled, "refunded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			admin := env.createUser(t, "admin")
+			alice := env.createUser(t, "alice")
+			env.createAddress(t, alice)
+			phone := env.createProduct(t, "Phone", 19999, 5)
+
+			env.addToCart(t, alice, phone, 2)
+			result := env.checkout(t, alice)
+			if tt.paid {
+				env.pay(t, result.PaymentIntent.ID)
+			}
+
+			if err := env.Orders.UpdateStatus(result.Order.ID, tt.status, admin.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			order := env.order(t, result.Order.ID)
+			if order.Status != tt.status || order.PaymentStatus != tt.wantPayment {
+				t.Errorf("order is %s with payment %s, want %s with payment %s", order.Status, order.PaymentStatus, tt.status, tt.wantPayment)
+			}
+			if stock := env.productStock(t, phone.ID); stock != 5 {
+				t.Errorf("stock is %d, want the 5 there were before checkout", stock)
+			}
+
+			var held int64
+			env.DB.Model(&models.StockReservation{}).
+				Where("order_id = ? AND status IN ?", order.ID, []string{models.ReservationReserved, models.ReservationCommitted}).
+				Count(&held)
+			if held != 0 {
+				t.Errorf("%d reservations still hold stock, want none", held)
+			}
+
+			intent, err := env.Provider.GetIntent(result.PaymentIntent.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.paid && intent.Status != IntentCanceled {
+				t.Errorf("intent is %s, want %s", intent.Status, IntentCanceled)
+			}
+			if _, _, err := env.Payments.CreatePaymentIntent(alice.ID, order.ID); err == nil {
+				t.Error("the order could still be paid")
+			}
+		})
+	}
+}
+
+func TestAdminCannotCancelShippedOrder(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.createUser(t, "admin")
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+	env.pay(t, result.PaymentIntent.ID)
+	if err := env.Orders.UpdateStatus(result.Order.ID, models.OrderStatusShipped, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.Orders.UpdateStatus(result.Order.ID, models.OrderStatusCancelled, admin.ID); !errors.Is(err, models.ErrInvalidOrderTransition) {
+		t.Errorf("got error %v, want %v", err, models.ErrInvalidOrderTransition)
+	}
+
+	var refunds int64
+	env.DB.Model(&models.Refund{}).Count(&refunds)
+	if refunds != 0 {
+		t.Errorf("%d refunds were issued, want none", refunds)
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"strconv"
+	"testing"
+)
+
+// TestCrossUserAccessIsBlocked has bob go after each of alice's orders,
+// payments, refunds, cart items and addresses. Every attempt must look like
+// the resource doesn't exist, and none may change it.
+func TestCrossUserAccessIsBlocked(t *testing.T) {
+	env := newTestEnv(t)
+
+	alice := env.createUser(t, "alice")
+	bob := env.createUser(t, "bob")
+	aliceAddress := env.createAddress(t, alice)
+	bobAddress := env.createAddress(t, bob)
+	phone := env.createProduct(t, "Phone", 19999, 10)
+	cable := env.createProduct(t, "Cable", 999, 10)
+
+	// A paid order, which cancelling would refund, and an unpaid one
+	env.addToCart(t, alice, phone, 1)
+	paid := env.checkout(t, alice)
+	env.pay(t, paid.PaymentIntent.ID)
+
+	env.addToCart(t, alice, cable, 2)
+	pending := env.checkout(t, alice)
+
+	// And something left in the cart
+	cartItem := env.addToCart(t, alice, cable, 3)
+
+	env.addToCart(t, bob, cable, 1)
+
+	tests := []struct {
+		name string
+		call func(userID uint) error
+	}{
+		{"get order", func(userID uint) error {
+			_, err := env.Orders.GetOrderByID(userID, pending.Order.ID)
+			return err
+		}},
+		{"cancel unpaid order", func(userID uint) error {
+			_, err := env.Orders.CancelOrder(userID, pending.Order.ID, "")
+			return err
+		}},
+		{"cancel paid order for a refund", func(userID uint) error {
+			_, err := env.Orders.CancelOrder(userID, paid.Order.ID, "")
+			return err
+		}},
+		{"get payment by order", func(userID uint) error {
+			_, err := env.Payments.GetPaymentByOrderID(userID, strconv.FormatUint(uint64(paid.Order.ID), 10))
+			return err
+		}},
+		{"create payment intent", func(userID uint) error {
+			_, _, err := env.Payments.CreatePaymentIntent(userID, pending.Order.ID)
+			return err
+		}},
+		{"get payment intent", func(userID uint) error {
+			_, err := env.Payments.GetPaymentIntent(userID, pending.PaymentIntent.ID)
+			return err
+		}},
+		{"confirm payment intent", func(userID uint) error {
+			_, err := env.Payments.ConfirmPaymentIntent(userID, pending.PaymentIntent.ID)
+			return err
+		}},
+		{"cancel payment intent", func(userID uint) error {
+			_, err := env.Payments.CancelPaymentIntent(userID, pending.PaymentIntent.ID)
+			return err
+		}},
+		{"confirm payment success", func(userID uint) error {
+			return env.Payments.ConfirmPaymentSuccess(userID, pending.Order.ID)
+		}},
+		{"update cart item", func(userID uint) error {
+			return env.Cart.UpdateItemQuantity(userID, cartItem.ID, 1)
+		}},
+		{"remove cart item", func(userID uint) error {
+			return env.Cart.RemoveItem(userID, cartItem.ID)
+		}},
+		{"get address", func(userID uint) error {
+			_, err := env.Addresses.GetByID(userID, aliceAddress.ID)
+			return err
+		}},
+		{"update address", func(userID uint) error {
+			update := *aliceAddress
+			update.Line1 = "2 Market St"
+			return env.Addresses.Update(userID, aliceAddress.ID, &update)
+		}},
+		{"delete address", func(userID uint) error {
+			return env.Addresses.Delete(userID, aliceAddress.ID)
+		}},
+		{"set default address", func(userID uint) error {
+			return env.Addresses.SetDefault(userID, aliceAddress.ID)
+		}},
+		{"ship to address", func(userID uint) error {
+			_, err := env.Checkout.Checkout(userID, CheckoutDetails{
+				ShippingMethodID:  env.shippingMethodID,
+				ShippingAddressID: aliceAddress.ID,
+			})
+			return err
+		}},
+		{"bill to address", func(userID uint) error {
+			_, err := env.Checkout.CreateOrder(userID, CheckoutDetails{
+				ShippingMethodID:  env.shippingMethodID,
+				ShippingAddressID: bobAddress.ID,
+				BillingAddressID:  aliceAddress.ID,
+			})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(bob.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("got error %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+
+	t.Run("alice's resources are untouched", func(t *testing.T) {
+		if order := env.order(t, pending.Order.ID); order.Status != models.OrderStatusPending {
+			t.Errorf("unpaid order is %s, want %s", order.Status, models.OrderStatusPending)
+		}
+		if order := env.order(t, paid.Order.ID); order.Status != models.OrderStatusProcessing || order.PaymentStatus != "succeeded" {
+			t.Errorf("paid order is %s with payment %s, want %s with payment succeeded", order.Status, order.PaymentStatus, models.OrderStatusProcessing)
+		}
+
+		intent, err := env.Provider.GetIntent(pending.PaymentIntent.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if intent.Status != IntentRequiresPaymentMethod {
+			t.Errorf("unpaid order's intent is %s, want %s", intent.Status, IntentRequiresPaymentMethod)
+		}
+
+		var refunds int64
+		env.DB.Model(&models.Refund{}).Count(&refunds)
+		if refunds != 0 {
+			t.Errorf("%d refunds were issued, want none", refunds)
+		}
+
+		cart, err := env.Cart.GetCartByUserID(alice.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cart.Items) != 1 || cart.Items[0].Quantity != 3 {
+			t.Errorf("cart has %+v, want the one item with quantity 3", cart.Items)
+		}
+
+		address, err := env.Addresses.GetByID(alice.ID, aliceAddress.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address.Line1 != aliceAddress.Line1 || !address.IsDefault {
+			t.Errorf("address is %q (default %v), want %q as the default", address.Line1, address.IsDefault, aliceAddress.Line1)
+		}
+
+		var bobOrders int64
+		env.DB.Model(&models.Order{}).Where("user_id = ?", bob.ID).Count(&bobOrders)
+		if bobOrders != 0 {
+			t.Errorf("bob placed %d orders with alice's addresses, want none", bobOrders)
+		}
+	})
+
+	t.Run("listings only show the user's own", func(t *testing.T) {
+		orders, err := env.Orders.GetOrdersByUserID(bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) != 0 {
+			t.Errorf("bob sees %d orders, want none", len(orders))
+		}
+
+		payments, err := env.Payments.GetUserPayments(bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(payments) != 0 {
+			t.Errorf("bob sees %d payments, want none", len(payments))
+		}
+
+		addresses, err := env.Addresses.GetAll(bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addresses) != 1 || addresses[0].ID != bobAddress.ID {
+			t.Errorf("bob sees addresses %+v, want only his own", addresses)
+		}
+	})
+
+	t.Run("alice can still reach her own", func(t *testing.T) {
+		if _, err := env.Orders.GetOrderByID(alice.ID, pending.Order.ID); err != nil {
+			t.Errorf("getting her order: %v", err)
+		}
+		if _, err := env.Payments.GetPaymentByOrderID(alice.ID, strconv.FormatUint(uint64(paid.Order.ID), 10)); err != nil {
+			t.Errorf("getting her payment: %v", err)
+		}
+		if _, err := env.Payments.GetPaymentIntent(alice.ID, pending.PaymentIntent.ID); err != nil {
+			t.Errorf("getting her payment intent: %v", err)
+		}
+		if err := env.Cart.UpdateItemQuantity(alice.ID, cartItem.ID, 4); err != nil {
+			t.Errorf("updating her cart item: %v", err)
+		}
+	})
+}
@@ -1,58 +1,147 @@
 package services
 
 import (
-	"encoding/json"
+	"errors"
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/repositories"
+	"log"
 	"strconv"
+	"strings"
 
-	"github.com/stripe/stripe-go/v84"
-	"github.com/stripe/stripe-go/v84/paymentintent"
-	"github.com/stripe/stripe-go/v84/webhook"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderAlreadyPaid = errors.New("order has already been paid")
+	ErrOrderNotPayable  = errors.New("order can no longer be paid")
+	ErrAmountMismatch   = errors.New("order total does not match its items")
+
+	ErrPaymentNotSucceeded = errors.New("payment has not succeeded at the provider")
 )
 
 type PaymentService struct {
+	unitOfWork    repositories.UnitOfWork
 	repo          *repositories.PaymentRepository
 	orderRepo     repositories.OrderRepository
-	stripeKey     string
-	webhookSecret string
+	inventoryRepo repositories.InventoryRepository
+	provider      PaymentProvider
+	refunds       *RefundServices
 }
 
 func NewPaymentService(
+	uow repositories.UnitOfWork,
 	repo *repositories.PaymentRepository,
 	orderRepo repositories.OrderRepository,
-	stripeKey string,
-	webhookSecret string,
+	inventoryRepo repositories.InventoryRepository,
+	provider PaymentProvider,
+	refunds *RefundServices,
 ) *PaymentService {
-	stripe.Key = stripeKey
 	return &PaymentService{
+		unitOfWork:    uow,
 		repo:          repo,
 		orderRepo:     orderRepo,
-		stripeKey:     stripeKey,
-		webhookSecret: webhookSecret,
+		inventoryRepo: inventoryRepo,
+		provider:      provider,
+		refunds:       refunds,
 	}
 }
 
-// Create payment intent
-func (s *PaymentService) CreatePaymentIntent(orderID uint, amount float64, currency string) (*stripe.PaymentIntent, *models.Payment, error) {
-	// Convert amount to cents for Stripe
-	amountInCents := int64(amount * 100)
+// Create payment intent for one of the user's orders. The amount charged is
+// always the order's own total, never one supplied by the client.
+func (s *PaymentService) CreatePaymentIntent(userID uint, orderID uint) (*PaymentIntent, *models.Payment, error) {
+	order, err := s.userOrder(userID, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	amount, err := payableAmount(order)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(amountInCents),
-		Currency: stripe.String(currency),
-		Metadata: map[string]string{
-			"order_id": strconv.FormatUint(uint64(orderID), 10),
-		},
+	// An order has one open payment at a time: while its intent can still be
+	// paid it is handed out again, so the order can't be charged twice
+	pi, payment, err := s.openPaymentIntent(orderID, amount)
+	if err != nil || pi != nil {
+		return pi, payment, err
 	}
 
-	pi, err := paymentintent.New(params)
+	pi, err = s.newPaymentIntent(orderID, amount)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Save to database
-	payment, err := s.repo.SavePaymentIntent(orderID, pi.ID, amount, currency, string(pi.Status))
+	payment, err = s.repo.SavePaymentIntent(orderID, pi.ID, amount, pi.Status)
+	if err != nil {
+		// Most likely a concurrent request opened a payment first
+		if _, cancelErr := s.provider.CancelIntent(pi.ID); cancelErr != nil {
+			log.Printf("[error] failed to cancel unsaved payment intent %s, got error %v", pi.ID, cancelErr)
+		}
+		return nil, nil, err
+	}
+
+	return pi, payment, nil
+}
+
+// openPaymentIntent returns the order's open payment and its intent if the
+// intent can still be paid for amount. An intent that can't, because it was
+// canceled or is for another amount, is closed so a new one can be created.
+// Returns nils if there is no usable intent.
+func (s *PaymentService) openPaymentIntent(orderID uint, amount models.Money) (*PaymentIntent, *models.Payment, error) {
+	payment, err := s.repo.GetOpenPaymentByOrderID(orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pi, err := s.provider.GetIntent(payment.PaymentIntentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case pi.Status == IntentSucceeded:
+		// The success event hasn't been applied yet
+		return nil, nil, ErrOrderAlreadyPaid
+	case pi.Status == IntentCanceled:
+	case pi.Amount != amount.Amount || !strings.EqualFold(pi.Currency, amount.Currency):
+		if _, err := s.provider.CancelIntent(pi.ID); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return pi, payment, nil
+	}
+
+	// The order's stock stays reserved for the intent replacing this one
+	if err := s.repo.UpdatePaymentStatus(pi.ID, "cancelled"); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, nil
+}
+
+// CreatePaymentIntentTx creates a payment intent for the order's total as part
+// of a unit of work. The payment record is written in the transaction and the
+// intent is cancelled at the provider if the unit of work rolls back.
+func (s *PaymentService) CreatePaymentIntentTx(tx *repositories.Tx, order *models.Order) (*PaymentIntent, *models.Payment, error) {
+	amount, err := payableAmount(order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pi, err := s.newPaymentIntent(order.ID, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx.OnRollback(func() error {
+		_, err := s.provider.CancelIntent(pi.ID)
+		return err
+	})
+
+	payment, err := s.repo.WithTx(tx.DB).SavePaymentIntent(order.ID, pi.ID, amount, pi.Status)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -60,105 +149,296 @@ func (s *PaymentService) CreatePaymentIntent(orderID uint, amount float64, curre
 	return pi, payment, nil
 }
 
+// newPaymentIntent creates an intent at the provider, which takes amounts in
+// the currency's minor unit just like Money
+func (s *PaymentService) newPaymentIntent(orderID uint, amount models.Money) (*PaymentIntent, error) {
+	metadata := map[string]string{
+		"order_id": strconv.FormatUint(uint64(orderID), 10),
+	}
+
+	return s.provider.CreateIntent(amount.Amount, amount.Currency, metadata)
+}
+
 // Confirm payment intent
-func (s *PaymentService) ConfirmPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
-	params := &stripe.PaymentIntentConfirmParams{}
-	return paymentintent.Confirm(paymentIntentID, params)
+func (s *PaymentService) ConfirmPaymentIntent(userID uint, paymentIntentID string) (*PaymentIntent, error) {
+	if _, err := s.userPayment(userID, paymentIntentID); err != nil {
+		return nil, err
+	}
+	return s.provider.ConfirmIntent(paymentIntentID)
 }
 
-// Cancel payment intent
-func (s *PaymentService) CancelPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
-	params := &stripe.PaymentIntentCancelParams{}
-	return paymentintent.Cancel(paymentIntentID, params)
+// Cancel one of the user's payment intents. The order can't be paid without
+// it, so it is cancelled along with the intent.
+func (s *PaymentService) CancelPaymentIntent(userID uint, paymentIntentID string) (*PaymentIntent, error) {
+	payment, err := s.userPayment(userID, paymentIntentID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.GetOrderByID(payment.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanTransitionOrderStatus(order.Status, models.OrderStatusCancelled) {
+		return nil, ErrOrderNotCancellable
+	}
+
+	return s.cancelPaymentIntent(paymentIntentID, &models.OrderStatusHistory{
+		ToStatus:  models.OrderStatusCancelled,
+		ChangedBy: &userID,
+		Source:    models.StatusSourceCustomer,
+		Note:      "Payment intent cancelled",
+	})
 }
 
-// Handle webhook events
-func (s *PaymentService) HandleWebhook(payload []byte, signature string) error {
-	event, err := webhook.ConstructEvent(payload, signature, s.webhookSecret)
+// cancelPaymentIntent cancels the intent and, in one transaction, marks its
+// payment cancelled, releases the stock reserved for its order and applies
+// change to the order. With a nil change the order's status is left to the
+// caller.
+func (s *PaymentService) cancelPaymentIntent(paymentIntentID string, change *models.OrderStatusHistory) (*PaymentIntent, error) {
+	pi, err := s.provider.CancelIntent(paymentIntentID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	switch event.Type {
-	case "payment_intent.succeeded":
-		var pi stripe.PaymentIntent
-		jsonData, err := json.Marshal(event.Data.Object)
+	err = s.unitOfWork.Do(func(tx *repositories.Tx) error {
+		payments := s.repo.WithTx(tx.DB)
+		payment, err := payments.GetPaymentByIntentIDForUpdate(paymentIntentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
-		err = json.Unmarshal(jsonData, &pi)
-		if err != nil {
+
+		if err := payments.UpdatePaymentStatus(paymentIntentID, "cancelled"); err != nil {
+			return err
+		}
+		orders := s.orderRepo.WithTx(tx.DB)
+		if err := orders.Update(payment.OrderID, map[string]interface{}{
+			"payment_status": "cancelled",
+		}); err != nil {
+			return err
+		}
+		if err := s.inventoryRepo.WithTx(tx.DB).Release(payment.OrderID); err != nil {
 			return err
 		}
 
-		// Update payment status
-		updates := map[string]interface{}{
-			"status": string(pi.Status),
+		if change == nil {
+			return nil
 		}
-		err = s.repo.UpdatePaymentDetails(pi.ID, updates)
-		if err != nil {
+		return orders.UpdateStatus(payment.OrderID, *change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pi, nil
+}
+
+// ProcessEvent applies a verified webhook event to payments and orders
+func (s *PaymentService) ProcessEvent(event *PaymentEvent) error {
+	switch event.Type {
+	case EventPaymentIntentSucceeded:
+		pi := event.Intent
+
+		// A replayed success must not undo a refund or dispute recorded since
+		payment, err := s.repo.GetPaymentByIntentID(pi.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && isSettledPayment(payment.Status) {
+			return nil
+		}
+
+		// Update payment status
+		updates := intentDetails(pi)
+		updates["status"] = "succeeded"
+		updates["failure_reason"] = ""
+		if err := s.repo.UpdatePaymentDetails(pi.ID, updates); err != nil {
 			return err
 		}
 
 		// Update order status and payment info
 		orderIDStr := pi.Metadata["order_id"]
 		if orderIDStr != "" {
-			orderID, _ := strconv.ParseUint(orderIDStr, 10, 32)
-			orderUpdates := map[string]interface{}{
-				"payment_status":    "succeeded",
-				"payment_intent_id": pi.ID,
-				"status":            "processing",
+			orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+			if err != nil {
+				// Redelivering the event won't fix its metadata
+				log.Printf("[error] payment intent %s has invalid order_id %q, got error %v", pi.ID, orderIDStr, err)
+				return nil
+			}
+			orderUpdates := intentDetails(pi)
+			orderUpdates["payment_status"] = "succeeded"
+			orderUpdates["payment_intent_id"] = pi.ID
+			if err := s.markOrderPaid(uint(orderID), orderUpdates); err != nil {
+				return err
 			}
-			s.orderRepo.Update(uint(orderID), orderUpdates)
 		}
 
-	case "payment_intent.payment_failed":
-		var pi stripe.PaymentIntent
-		jsonData, err := json.Marshal(event.Data.Object)
-		if err != nil {
+	case EventPaymentIntentProcessing, EventPaymentIntentRequiresAction:
+		_, err := s.updateOpenPayment(event.Intent, event.Intent.Status, nil)
+		return err
+
+	case EventPaymentIntentFailed:
+		pi := event.Intent
+
+		// The customer can still retry the intent, so the order keeps its
+		// reserved stock until the intent is cancelled or the order expires
+		_, err := s.updateOpenPayment(pi, "failed", map[string]interface{}{
+			"failure_reason": failureReason(pi),
+		})
+		return err
+
+	case EventPaymentIntentCanceled:
+		payment, err := s.updateOpenPayment(event.Intent, "cancelled", nil)
+		if err != nil || payment == nil {
 			return err
 		}
-		err = json.Unmarshal(jsonData, &pi)
-		if err != nil {
+		if err := s.inventoryRepo.Release(payment.OrderID); err != nil {
 			return err
 		}
 
-		updates := map[string]interface{}{
-			"status": "failed",
-		}
-		if pi.LastPaymentError != nil {
-			updates["failure_reason"] = pi.LastPaymentError.Code
-		}
-		err = s.repo.UpdatePaymentDetails(pi.ID, updates)
-		if err != nil {
+		// An order whose payment was voided can't be fulfilled
+		err = s.orderRepo.UpdateStatus(payment.OrderID, models.OrderStatusHistory{
+			ToStatus: models.OrderStatusCancelled,
+			Source:   models.StatusSourcePayment,
+			Note:     "Payment intent canceled",
+		})
+		if err != nil && !errors.Is(err, models.ErrInvalidOrderTransition) {
 			return err
 		}
 
-		// Update order payment status
-		orderIDStr := pi.Metadata["order_id"]
-		if orderIDStr != "" {
-			orderID, _ := strconv.ParseUint(orderIDStr, 10, 32)
-			s.orderRepo.Update(uint(orderID), map[string]interface{}{
-				"payment_status": "failed",
-			})
-		}
+	case EventChargeRefunded:
+		return s.refunds.SyncChargeRefunds(event.Charge)
+
+	case EventChargeDisputeCreated:
+		return s.markDisputed(event.Dispute)
 	}
 
 	return nil
 }
 
+// updateOpenPayment moves the intent's payment and its order to status. Events
+// can arrive out of order, so a payment that has already settled is left as
+// is. Returns nil if there is no open payment for the intent.
+func (s *PaymentService) updateOpenPayment(pi *PaymentIntent, status string, extra map[string]interface{}) (*models.Payment, error) {
+	payment, err := s.repo.GetPaymentByIntentID(pi.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isSettledPayment(payment.Status) {
+		return nil, nil
+	}
+
+	updates := intentDetails(pi)
+	for k, v := range extra {
+		updates[k] = v
+	}
+	updates["status"] = status
+	if err := s.repo.UpdatePaymentDetails(pi.ID, updates); err != nil {
+		return nil, err
+	}
+
+	orderUpdates := intentDetails(pi)
+	orderUpdates["payment_status"] = status
+	if err := s.orderRepo.Update(payment.OrderID, orderUpdates); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// markDisputed flags the payment and order of a charge the cardholder disputed
+func (s *PaymentService) markDisputed(dispute *ProviderDispute) error {
+	payment, err := s.repo.GetPaymentByIntentID(dispute.PaymentIntentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePaymentDetails(payment.PaymentIntentID, map[string]interface{}{
+		"status":         "disputed",
+		"transaction_id": dispute.ChargeID,
+		"failure_reason": "dispute: " + dispute.Reason,
+	}); err != nil {
+		return err
+	}
+
+	return s.orderRepo.Update(payment.OrderID, map[string]interface{}{
+		"payment_status": "disputed",
+		"transaction_id": dispute.ChargeID,
+	})
+}
+
+// intentDetails returns the payment method and transaction fields the intent
+// knows about, for updating both the payment and the order
+func intentDetails(pi *PaymentIntent) map[string]interface{} {
+	details := map[string]interface{}{}
+	if pi.PaymentMethod != "" {
+		details["payment_method"] = pi.PaymentMethod
+	}
+	if pi.ChargeID != "" {
+		details["transaction_id"] = pi.ChargeID
+	}
+	return details
+}
+
+// failureReason describes why the intent's last attempt failed
+func failureReason(pi *PaymentIntent) string {
+	switch {
+	case pi.FailureCode != "" && pi.FailureMessage != "":
+		return pi.FailureCode + ": " + pi.FailureMessage
+	case pi.FailureCode != "":
+		return pi.FailureCode
+	case pi.FailureMessage != "":
+		return pi.FailureMessage
+	}
+	return "payment failed"
+}
+
+// isSettledPayment reports whether the payment reached a state that later
+// intent events (processing, requires_action, failed, canceled) must not undo
+func isSettledPayment(status string) bool {
+	switch status {
+	case "succeeded", "partially_refunded", "refunded", "disputed", "cancelled", "canceled":
+		return true
+	}
+	return false
+}
+
 // Get payment details
-func (s *PaymentService) GetPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
-	return paymentintent.Get(paymentIntentID, nil)
+func (s *PaymentService) GetPaymentIntent(userID uint, paymentIntentID string) (*PaymentIntent, error) {
+	if _, err := s.userPayment(userID, paymentIntentID); err != nil {
+		return nil, err
+	}
+	return s.getPaymentIntent(paymentIntentID)
+}
+
+// getPaymentIntent fetches the intent's current state from the provider
+func (s *PaymentService) getPaymentIntent(paymentIntentID string) (*PaymentIntent, error) {
+	return s.provider.GetIntent(paymentIntentID)
 }
 
 // Get payment by order ID
-func (s *PaymentService) GetPaymentByOrderID(orderIDStr string) (*models.Payment, error) {
+func (s *PaymentService) GetPaymentByOrderID(userID uint, orderIDStr string) (*models.Payment, error) {
 	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
 	if err != nil {
+		return nil, ErrNotFound
+	}
+	if _, err := s.userOrder(userID, uint(orderID)); err != nil {
 		return nil, err
 	}
-	return s.repo.GetPaymentByOrderID(uint(orderID))
+
+	payment, err := s.repo.GetPaymentByOrderID(uint(orderID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return payment, nil
 }
 
 // Get user payments
@@ -166,26 +446,111 @@ func (s *PaymentService) GetUserPayments(userID uint) ([]models.Payment, error)
 	return s.repo.GetPaymentsByUserID(userID)
 }
 
-// Confirm payment success (for testing without webhooks)
-func (s *PaymentService) ConfirmPaymentSuccess(orderID uint) error {
-	// Update payment status
-	payment, err := s.repo.GetPaymentByOrderID(orderID)
+// ConfirmPaymentSuccess applies a successful payment of the user's pending
+// order without waiting for the webhook. The provider must report the order's
+// open intent as succeeded, so nothing is marked paid on the client's word.
+func (s *PaymentService) ConfirmPaymentSuccess(userID uint, orderID uint) error {
+	order, err := s.userOrder(userID, orderID)
 	if err != nil {
 		return err
 	}
+	if _, err := payableAmount(order); err != nil {
+		return err
+	}
 
-	updates := map[string]interface{}{
-		"status": "succeeded",
+	payment, err := s.repo.GetOpenPaymentByOrderID(orderID)
+	if err != nil {
+		return notFound(err)
 	}
-	err = s.repo.UpdatePaymentDetails(payment.PaymentIntentID, updates)
+
+	pi, err := s.provider.GetIntent(payment.PaymentIntentID)
 	if err != nil {
 		return err
 	}
+	if pi.Status != IntentSucceeded {
+		return ErrPaymentNotSucceeded
+	}
+	if pi.Amount != payment.Amount.Amount {
+		return ErrAmountMismatch
+	}
+
+	return s.ProcessEvent(&PaymentEvent{Type: EventPaymentIntentSucceeded, Intent: pi})
+}
+
+// markOrderPaid applies the payment updates to the order, moves it from
+// pending to processing and commits its reserved stock
+func (s *PaymentService) markOrderPaid(orderID uint, orderUpdates map[string]interface{}) error {
+	if err := s.orderRepo.Update(orderID, orderUpdates); err != nil {
+		return err
+	}
+
+	err := s.orderRepo.UpdateStatus(orderID, models.OrderStatusHistory{
+		ToStatus: models.OrderStatusProcessing,
+		Source:   models.StatusSourcePayment,
+	})
+	// A repeated success event finds the order already past pending
+	if err != nil && !errors.Is(err, models.ErrInvalidOrderTransition) {
+		return err
+	}
+
+	// Stock is now sold for good
+	return s.inventoryRepo.Commit(orderID)
+}
+
+// userOrder loads the order if it belongs to the user
+func (s *PaymentService) userOrder(userID uint, orderID uint) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := requireOwner(order.UserID, userID); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// userPayment loads the payment of an intent if its order belongs to the user
+func (s *PaymentService) userPayment(userID uint, paymentIntentID string) (*models.Payment, error) {
+	payment, err := s.repo.GetPaymentByIntentID(paymentIntentID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if _, err := s.userOrder(userID, payment.OrderID); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// payableAmount recomputes what the order costs from its line prices, shipping,
+// tax and discount, and refuses orders that can't or shouldn't be charged
+func payableAmount(order *models.Order) (models.Money, error) {
+	switch order.PaymentStatus {
+	case "succeeded", "partially_refunded", "refunded":
+		return models.Money{}, ErrOrderAlreadyPaid
+	}
+	if order.Status != models.OrderStatusPending {
+		return models.Money{}, ErrOrderNotPayable
+	}
+
+	amount, err := order.Shipping.Add(order.Tax)
+	if err != nil {
+		return models.Money{}, err
+	}
+	for _, item := range order.Items {
+		amount, err = amount.Add(item.Price.Mul(item.Quantity))
+		if err != nil {
+			return models.Money{}, err
+		}
+	}
+	if !order.Discount.IsZero() {
+		amount, err = amount.Sub(order.Discount)
+		if err != nil {
+			return models.Money{}, err
+		}
+	}
 
-	// Update order status
-	orderUpdates := map[string]interface{}{
-		"payment_status": "succeeded",
-		"status":         "processing",
+	if amount.Amount <= 0 || amount != order.Total {
+		return models.Money{}, ErrAmountMismatch
 	}
-	return s.orderRepo.Update(orderID, orderUpdates)
+	return amount, nil
 }
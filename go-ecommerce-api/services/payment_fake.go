@@ -0,0 +1,309 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ecommerce-api/config"
+	"strings"
+	"sync"
+)
+
+// FakeProvider is an in-memory PaymentProvider for local development and tests.
+// IDs are sequential (pi_fake_000001, re_fake_000001, ...) so runs are repeatable.
+// Confirming an intent always succeeds; use FailIntent to simulate a decline.
+//
+// Webhook payloads use Stripe's envelope ({"id", "type", "data": {"object"}}) and
+// are signed with a hex HMAC-SHA256 of the body. With an empty secret any
+// signature is accepted, so events can be posted with curl.
+type FakeProvider struct {
+	mu            sync.Mutex
+	webhookSecret string
+	intents       map[string]*PaymentIntent
+	refunds       map[string][]ProviderRefund
+	intentSeq     int
+	refundSeq     int
+	eventSeq      int
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{
+		webhookSecret: webhookSecret,
+		intents:       make(map[string]*PaymentIntent),
+		refunds:       make(map[string][]ProviderRefund),
+	}
+}
+
+type fakeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+func (f *FakeProvider) CreateIntent(amount int64, currency string, metadata map[string]string) (*PaymentIntent, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.intentSeq++
+	id := fmt.Sprintf("pi_fake_%06d", f.intentSeq)
+	intent := &PaymentIntent{
+		ID:           id,
+		ClientSecret: id + "_secret_fake",
+		Amount:       amount,
+		Currency:     strings.ToLower(currency),
+		Status:       IntentRequiresPaymentMethod,
+		Metadata:     metadata,
+	}
+	f.intents[id] = intent
+
+	return copyIntent(intent), nil
+}
+
+func (f *FakeProvider) ConfirmIntent(id string) (*PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status == IntentCanceled {
+		return nil, fmt.Errorf("payment intent %s is canceled", id)
+	}
+
+	intent.Status = IntentSucceeded
+	intent.FailureCode = ""
+	intent.FailureMessage = ""
+	intent.ChargeID = chargeID(intent.ID)
+	intent.PaymentMethod = "card"
+	return copyIntent(intent), nil
+}
+
+func (f *FakeProvider) CancelIntent(id string) (*PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status == IntentSucceeded {
+		return nil, fmt.Errorf("payment intent %s has already succeeded", id)
+	}
+
+	intent.Status = IntentCanceled
+	return copyIntent(intent), nil
+}
+
+func (f *FakeProvider) GetIntent(id string) (*PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+	return copyIntent(intent), nil
+}
+
+func (f *FakeProvider) Refund(paymentIntentID string, amount int64) (*ProviderRefund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, err := f.intent(paymentIntentID)
+	if err != nil {
+		return nil, err
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("payment intent %s has not succeeded", paymentIntentID)
+	}
+	if amount <= 0 || f.refundedAmount(paymentIntentID)+amount > intent.Amount {
+		return nil, fmt.Errorf("refund amount %d exceeds the refundable amount", amount)
+	}
+
+	f.refundSeq++
+	refund := ProviderRefund{
+		ID:              fmt.Sprintf("re_fake_%06d", f.refundSeq),
+		PaymentIntentID: paymentIntentID,
+		Amount:          amount,
+		Currency:        intent.Currency,
+		Status:          "succeeded",
+	}
+	f.refunds[paymentIntentID] = append(f.refunds[paymentIntentID], refund)
+
+	return &refund, nil
+}
+
+func (f *FakeProvider) Name() string {
+	return config.PaymentProviderFake
+}
+
+func (f *FakeProvider) VerifyWebhook(payload []byte, signature string) (*PaymentEvent, error) {
+	if f.webhookSecret != "" && !hmac.Equal([]byte(signature), []byte(f.sign(payload))) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidWebhook)
+	}
+	return f.ParseEvent(payload)
+}
+
+func (f *FakeProvider) ParseEvent(payload []byte) (*PaymentEvent, error) {
+	var event fakeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	paymentEvent := &PaymentEvent{
+		ID:   event.ID,
+		Type: event.Type,
+	}
+
+	if strings.HasPrefix(event.Type, "payment_intent.") {
+		var intent PaymentIntent
+		if err := json.Unmarshal(event.Data.Object, &intent); err != nil {
+			return nil, err
+		}
+		paymentEvent.Intent = &intent
+	}
+
+	if strings.HasPrefix(event.Type, "charge.dispute.") {
+		var dispute ProviderDispute
+		if err := json.Unmarshal(event.Data.Object, &dispute); err != nil {
+			return nil, err
+		}
+		paymentEvent.Dispute = &dispute
+	} else if strings.HasPrefix(event.Type, "charge.") {
+		var charge ProviderCharge
+		if err := json.Unmarshal(event.Data.Object, &charge); err != nil {
+			return nil, err
+		}
+		paymentEvent.Charge = &charge
+	}
+
+	return paymentEvent, nil
+}
+
+// FailIntent simulates a declined payment on the intent
+func (f *FakeProvider) FailIntent(id string, code string) (*PaymentIntent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, err := f.intent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	intent.Status = IntentRequiresPaymentMethod
+	intent.FailureCode = code
+	return copyIntent(intent), nil
+}
+
+// Event builds a signed webhook payload of the given type for the intent's
+// current state, ready to be passed to WebhookServices.HandleWebhook. charge.*
+// events carry the intent's charge and its refunds, and charge.dispute.* events
+// a dispute over the full amount.
+func (f *FakeProvider) Event(eventType string, intentID string) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, err := f.intent(intentID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var object []byte
+	if strings.HasPrefix(eventType, "charge.dispute.") {
+		object, err = json.Marshal(f.dispute(intent))
+	} else if strings.HasPrefix(eventType, "charge.") {
+		object, err = json.Marshal(f.charge(intent))
+	} else {
+		object, err = json.Marshal(intent)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	f.eventSeq++
+	event := fakeEvent{
+		ID:   fmt.Sprintf("evt_fake_%06d", f.eventSeq),
+		Type: eventType,
+	}
+	event.Data.Object = object
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, f.sign(payload), nil
+}
+
+func (f *FakeProvider) intent(id string) (*PaymentIntent, error) {
+	intent, ok := f.intents[id]
+	if !ok {
+		return nil, fmt.Errorf("no such payment intent: %s", id)
+	}
+	return intent, nil
+}
+
+// charge builds the single charge the fake makes for a succeeded intent
+func (f *FakeProvider) charge(intent *PaymentIntent) *ProviderCharge {
+	return &ProviderCharge{
+		ID:              chargeID(intent.ID),
+		PaymentIntentID: intent.ID,
+		Amount:          intent.Amount,
+		AmountRefunded:  f.refundedAmount(intent.ID),
+		Currency:        intent.Currency,
+		PaymentMethod:   intent.PaymentMethod,
+		Refunds:         f.refunds[intent.ID],
+	}
+}
+
+// dispute builds a chargeback over the intent's whole charge
+func (f *FakeProvider) dispute(intent *PaymentIntent) *ProviderDispute {
+	return &ProviderDispute{
+		ID:              strings.Replace(intent.ID, "pi_", "dp_", 1),
+		ChargeID:        chargeID(intent.ID),
+		PaymentIntentID: intent.ID,
+		Amount:          intent.Amount,
+		Currency:        intent.Currency,
+		Reason:          "fraudulent",
+		Status:          "needs_response",
+	}
+}
+
+func chargeID(intentID string) string {
+	return strings.Replace(intentID, "pi_", "ch_", 1)
+}
+
+func (f *FakeProvider) refundedAmount(intentID string) int64 {
+	var total int64
+	for _, refund := range f.refunds[intentID] {
+		total += refund.Amount
+	}
+	return total
+}
+
+func (f *FakeProvider) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.webhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func copyIntent(intent *PaymentIntent) *PaymentIntent {
+	c := *intent
+	if intent.Metadata != nil {
+		c.Metadata = make(map[string]string, len(intent.Metadata))
+		for k, v := range intent.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
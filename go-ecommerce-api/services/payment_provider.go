@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/config"
+)
+
+// Payment intent statuses (same values as Stripe)
+const (
+	IntentRequiresPaymentMethod = "requires_payment_method"
+	IntentRequiresConfirmation  = "requires_confirmation"
+	IntentRequiresAction        = "requires_action"
+	IntentProcessing            = "processing"
+	IntentSucceeded             = "succeeded"
+	IntentCanceled              = "canceled"
+)
+
+// Webhook event types (same values as Stripe)
+const (
+	EventPaymentIntentSucceeded      = "payment_intent.succeeded"
+	EventPaymentIntentFailed         = "payment_intent.payment_failed"
+	EventPaymentIntentCanceled       = "payment_intent.canceled"
+	EventPaymentIntentProcessing     = "payment_intent.processing"
+	EventPaymentIntentRequiresAction = "payment_intent.requires_action"
+	EventChargeRefunded              = "charge.refunded"
+	EventChargeDisputeCreated        = "charge.dispute.created"
+)
+
+// PaymentIntent is the provider-agnostic view of a payment intent.
+// Amount is in the currency's minor units (e.g. cents).
+type PaymentIntent struct {
+	ID             string            `json:"id"`
+	ClientSecret   string            `json:"client_secret,omitempty"`
+	Amount         int64             `json:"amount"`
+	Currency       string            `json:"currency"`
+	Status         string            `json:"status"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	FailureCode    string            `json:"failure_code,omitempty"`
+	FailureMessage string            `json:"failure_message,omitempty"`
+	ChargeID       string            `json:"charge_id,omitempty"`      // latest charge, the actual transaction
+	PaymentMethod  string            `json:"payment_method,omitempty"` // payment method type, e.g. card
+}
+
+// ProviderRefund is a refund as reported by the payment provider
+type ProviderRefund struct {
+	ID              string `json:"id"`
+	PaymentIntentID string `json:"payment_intent_id"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	Status          string `json:"status"`
+}
+
+// ProviderCharge is a charge made against a payment intent
+type ProviderCharge struct {
+	ID              string           `json:"id"`
+	PaymentIntentID string           `json:"payment_intent_id"`
+	Amount          int64            `json:"amount"`
+	AmountRefunded  int64            `json:"amount_refunded"`
+	Currency        string           `json:"currency"`
+	PaymentMethod   string           `json:"payment_method,omitempty"`
+	Refunds         []ProviderRefund `json:"refunds,omitempty"`
+}
+
+// ProviderDispute is a chargeback opened by the cardholder against a charge
+type ProviderDispute struct {
+	ID              string `json:"id"`
+	ChargeID        string `json:"charge_id"`
+	PaymentIntentID string `json:"payment_intent_id"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	Reason          string `json:"reason"`
+	Status          string `json:"status"`
+}
+
+// PaymentEvent is a verified webhook event
+type PaymentEvent struct {
+	ID      string
+	Type    string
+	Intent  *PaymentIntent   // set for payment_intent.* events
+	Charge  *ProviderCharge  // set for charge.* events
+	Dispute *ProviderDispute // set for charge.dispute.* events
+}
+
+// ErrInvalidWebhook is returned for webhook payloads whose signature doesn't check out
+var ErrInvalidWebhook = errors.New("invalid webhook signature")
+
+// PaymentProvider is a payment gateway that PaymentService talks to
+type PaymentProvider interface {
+	// Name identifies the provider in stored webhook events
+	Name() string
+	CreateIntent(amount int64, currency string, metadata map[string]string) (*PaymentIntent, error)
+	ConfirmIntent(id string) (*PaymentIntent, error)
+	CancelIntent(id string) (*PaymentIntent, error)
+	GetIntent(id string) (*PaymentIntent, error)
+	Refund(paymentIntentID string, amount int64) (*ProviderRefund, error)
+	VerifyWebhook(payload []byte, signature string) (*PaymentEvent, error)
+	// ParseEvent decodes a webhook payload that was verified when it arrived
+	ParseEvent(payload []byte) (*PaymentEvent, error)
+}
+
+// NewPaymentProvider returns the provider selected by cfg.PaymentProvider
+func NewPaymentProvider(cfg *config.Config) (PaymentProvider, error) {
+	switch cfg.PaymentProvider {
+	case config.PaymentProviderStripe:
+		return NewStripeProvider(cfg.StripeSecretKey, cfg.StripeWebhookSecret), nil
+	case config.PaymentProviderFake:
+		return NewFakeProvider(cfg.StripeWebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.PaymentProvider)
+	}
+}
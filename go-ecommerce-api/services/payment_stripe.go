@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-ecommerce-api/config"
+	"strings"
+
+	"github.com/stripe/stripe-go/v84"
+	"github.com/stripe/stripe-go/v84/webhook"
+)
+
+type stripeProvider struct {
+	client        *stripe.Client
+	webhookSecret string
+}
+
+// NewStripeProvider returns a PaymentProvider backed by the Stripe API
+func NewStripeProvider(secretKey, webhookSecret string) PaymentProvider {
+	return &stripeProvider{
+		client:        stripe.NewClient(secretKey),
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (p *stripeProvider) CreateIntent(amount int64, currency string, metadata map[string]string) (*PaymentIntent, error) {
+	params := &stripe.PaymentIntentCreateParams{
+		Amount:   stripe.Int64(amount),
+		Currency: stripe.String(strings.ToLower(currency)),
+		Metadata: metadata,
+	}
+
+	pi, err := p.client.V1PaymentIntents.Create(context.Background(), params)
+	if err != nil {
+		return nil, err
+	}
+	return fromStripeIntent(pi), nil
+}
+
+func (p *stripeProvider) ConfirmIntent(id string) (*PaymentIntent, error) {
+	pi, err := p.client.V1PaymentIntents.Confirm(context.Background(), id, &stripe.PaymentIntentConfirmParams{})
+	if err != nil {
+		return nil, err
+	}
+	return fromStripeIntent(pi), nil
+}
+
+func (p *stripeProvider) CancelIntent(id string) (*PaymentIntent, error) {
+	pi, err := p.client.V1PaymentIntents.Cancel(context.Background(), id, &stripe.PaymentIntentCancelParams{})
+	if err != nil {
+		return nil, err
+	}
+	return fromStripeIntent(pi), nil
+}
+
+func (p *stripeProvider) GetIntent(id string) (*PaymentIntent, error) {
+	pi, err := p.client.V1PaymentIntents.Retrieve(context.Background(), id, &stripe.PaymentIntentRetrieveParams{})
+	if err != nil {
+		return nil, err
+	}
+	return fromStripeIntent(pi), nil
+}
+
+func (p *stripeProvider) Refund(paymentIntentID string, amount int64) (*ProviderRefund, error) {
+	params := &stripe.RefundCreateParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(amount),
+	}
+
+	refund, err := p.client.V1Refunds.Create(context.Background(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProviderRefund{
+		ID:              refund.ID,
+		PaymentIntentID: paymentIntentID,
+		Amount:          refund.Amount,
+		Currency:        string(refund.Currency),
+		Status:          string(refund.Status),
+	}, nil
+}
+
+func (p *stripeProvider) Name() string {
+	return config.PaymentProviderStripe
+}
+
+func (p *stripeProvider) VerifyWebhook(payload []byte, signature string) (*PaymentEvent, error) {
+	event, err := webhook.ConstructEvent(payload, signature, p.webhookSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	return fromStripeEvent(event)
+}
+
+func (p *stripeProvider) ParseEvent(payload []byte) (*PaymentEvent, error) {
+	var event stripe.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return fromStripeEvent(event)
+}
+
+func fromStripeEvent(event stripe.Event) (*PaymentEvent, error) {
+	paymentEvent := &PaymentEvent{
+		ID:   event.ID,
+		Type: string(event.Type),
+	}
+
+	if strings.HasPrefix(paymentEvent.Type, "payment_intent.") {
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+			return nil, err
+		}
+		paymentEvent.Intent = fromStripeIntent(&pi)
+	}
+
+	if strings.HasPrefix(paymentEvent.Type, "charge.dispute.") {
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+			return nil, err
+		}
+		paymentEvent.Dispute = fromStripeDispute(&dispute)
+	} else if strings.HasPrefix(paymentEvent.Type, "charge.") {
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return nil, err
+		}
+		paymentEvent.Charge = fromStripeCharge(&charge)
+	}
+
+	return paymentEvent, nil
+}
+
+func fromStripeIntent(pi *stripe.PaymentIntent) *PaymentIntent {
+	intent := &PaymentIntent{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Amount:       pi.Amount,
+		Currency:     string(pi.Currency),
+		Status:       string(pi.Status),
+		Metadata:     pi.Metadata,
+	}
+	if pi.LastPaymentError != nil {
+		intent.FailureCode = string(pi.LastPaymentError.Code)
+		intent.FailureMessage = pi.LastPaymentError.Msg
+	}
+	if pi.LatestCharge != nil {
+		intent.ChargeID = pi.LatestCharge.ID
+	}
+
+	// Webhooks don't expand the payment method, fall back to the allowed types
+	if pi.PaymentMethod != nil && pi.PaymentMethod.Type != "" {
+		intent.PaymentMethod = string(pi.PaymentMethod.Type)
+	} else if len(pi.PaymentMethodTypes) == 1 {
+		intent.PaymentMethod = pi.PaymentMethodTypes[0]
+	}
+	return intent
+}
+
+func fromStripeCharge(ch *stripe.Charge) *ProviderCharge {
+	charge := &ProviderCharge{
+		ID:             ch.ID,
+		Amount:         ch.Amount,
+		AmountRefunded: ch.AmountRefunded,
+		Currency:       string(ch.Currency),
+	}
+	if ch.PaymentIntent != nil {
+		charge.PaymentIntentID = ch.PaymentIntent.ID
+	}
+	if ch.PaymentMethodDetails != nil {
+		charge.PaymentMethod = string(ch.PaymentMethodDetails.Type)
+	}
+
+	// Only present when the event includes the expanded refund list
+	if ch.Refunds != nil {
+		for _, refund := range ch.Refunds.Data {
+			charge.Refunds = append(charge.Refunds, ProviderRefund{
+				ID:              refund.ID,
+				PaymentIntentID: charge.PaymentIntentID,
+				Amount:          refund.Amount,
+				Currency:        string(refund.Currency),
+				Status:          string(refund.Status),
+			})
+		}
+	}
+
+	return charge
+}
+
+func fromStripeDispute(d *stripe.Dispute) *ProviderDispute {
+	dispute := &ProviderDispute{
+		ID:       d.ID,
+		Amount:   d.Amount,
+		Currency: string(d.Currency),
+		Reason:   string(d.Reason),
+		Status:   string(d.Status),
+	}
+	if d.Charge != nil {
+		dispute.ChargeID = d.Charge.ID
+	}
+	if d.PaymentIntent != nil {
+		dispute.PaymentIntentID = d.PaymentIntent.ID
+	}
+	return dispute
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"strconv"
+	"testing"
+)
+
+func TestFailedPaymentKeepsReservation(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	bob := env.createUser(t, "bob")
+	env.createAddress(t, alice)
+	env.createAddress(t, bob)
+	phone := env.createProduct(t, "Phone", 19999, 1)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+
+	if _, err := env.Provider.FailIntent(result.PaymentIntent.ID, "card_declined"); err != nil {
+		t.Fatal(err)
+	}
+	env.deliver(t, EventPaymentIntentFailed, result.PaymentIntent.ID)
+
+	order := env.order(t, result.Order.ID)
+	if order.Status != models.OrderStatusPending || order.PaymentStatus != "failed" {
+		t.Errorf("order is %s with payment %s, want %s with payment failed", order.Status, order.PaymentStatus, models.OrderStatusPending)
+	}
+	if stock := env.productStock(t, phone.ID); stock != 0 {
+		t.Errorf("stock is %d, want 0 while alice can still pay", stock)
+	}
+
+	// The last phone is still alice's
+	env.addToCart(t, bob, phone, 1)
+	if _, err := env.Checkout.Checkout(bob.ID, CheckoutDetails{ShippingMethodID: env.shippingMethodID}); !isOutOfStock(err) {
+		t.Errorf("bob's checkout got error %v, want out of stock", err)
+	}
+
+	// Alice retries with another card
+	env.pay(t, result.PaymentIntent.ID)
+
+	if order := env.order(t, result.Order.ID); order.Status != models.OrderStatusProcessing {
+		t.Errorf("order is %s, want %s", order.Status, models.OrderStatusProcessing)
+	}
+	if stock := env.productStock(t, phone.ID); stock != 0 {
+		t.Errorf("stock is %d, want 0", stock)
+	}
+}
+
+func TestCreatePaymentIntentReusesOpenIntent(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+
+	pi, _, err := env.Payments.CreatePaymentIntent(alice.ID, result.Order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pi.ID != result.PaymentIntent.ID {
+		t.Errorf("got intent %s, want the open intent %s", pi.ID, result.PaymentIntent.ID)
+	}
+
+	// Once the intent is canceled at the provider a new one replaces it
+	if _, err := env.Provider.CancelIntent(result.PaymentIntent.ID); err != nil {
+		t.Fatal(err)
+	}
+	pi, _, err = env.Payments.CreatePaymentIntent(alice.ID, result.Order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pi.ID == result.PaymentIntent.ID {
+		t.Errorf("got the canceled intent %s again", pi.ID)
+	}
+
+	var open int64
+	env.DB.Model(&models.Payment{}).Where("order_id = ? AND status IN ?", result.Order.ID, models.OpenPaymentStatuses).Count(&open)
+	if open != 1 {
+		t.Errorf("order has %d open payments, want 1", open)
+	}
+
+	payment, err := env.Payments.GetPaymentByOrderID(alice.ID, strconv.FormatUint(uint64(result.Order.ID), 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.PaymentIntentID != pi.ID {
+		t.Errorf("order's payment is for intent %s, want the latest %s", payment.PaymentIntentID, pi.ID)
+	}
+}
+
+func TestConfirmPaymentSuccessChecksProvider(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+
+	if err := env.Payments.ConfirmPaymentSuccess(alice.ID, result.Order.ID); !errors.Is(err, ErrPaymentNotSucceeded) {
+		t.Fatalf("unpaid intent got error %v, want %v", err, ErrPaymentNotSucceeded)
+	}
+	if order := env.order(t, result.Order.ID); order.Status != models.OrderStatusPending {
+		t.Fatalf("order is %s, want %s", order.Status, models.OrderStatusPending)
+	}
+
+	if _, err := env.Provider.ConfirmIntent(result.PaymentIntent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Payments.ConfirmPaymentSuccess(alice.ID, result.Order.ID); err != nil {
+		t.Fatalf("paid intent got error %v", err)
+	}
+	if order := env.order(t, result.Order.ID); order.Status != models.OrderStatusProcessing {
+		t.Errorf("order is %s, want %s", order.Status, models.OrderStatusProcessing)
+	}
+
+	if err := env.Payments.ConfirmPaymentSuccess(alice.ID, result.Order.ID); !errors.Is(err, ErrOrderAlreadyPaid) {
+		t.Errorf("paid order got error %v, want %v", err, ErrOrderAlreadyPaid)
+	}
+}
+
+func TestCancelledPaymentIntentCancelsOrder(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 1)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+
+	if _, err := env.Payments.CancelPaymentIntent(alice.ID, result.PaymentIntent.ID); err != nil {
+		t.Fatal(err)
+	}
+	// The provider's canceled event follows
+	env.deliver(t, EventPaymentIntentCanceled, result.PaymentIntent.ID)
+
+	order := env.order(t, result.Order.ID)
+	if order.Status != models.OrderStatusCancelled || order.PaymentStatus != "cancelled" {
+		t.Errorf("order is %s with payment %s, want %s with payment cancelled", order.Status, order.PaymentStatus, models.OrderStatusCancelled)
+	}
+	if stock := env.productStock(t, phone.ID); stock != 1 {
+		t.Errorf("stock is %d, want 1", stock)
+	}
+
+	// Its stock is back on sale, so the order can't be paid for any more
+	if _, _, err := env.Payments.CreatePaymentIntent(alice.ID, result.Order.ID); !errors.Is(err, ErrOrderNotPayable) {
+		t.Errorf("new intent got error %v, want %v", err, ErrOrderNotPayable)
+	}
+	if _, err := env.Payments.CancelPaymentIntent(alice.ID, result.PaymentIntent.ID); !errors.Is(err, ErrOrderNotCancellable) {
+		t.Errorf("cancelling again got error %v, want %v", err, ErrOrderNotCancellable)
+	}
+}
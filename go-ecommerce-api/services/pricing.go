@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+)
+
+// PricingServices quotes carts. The cart view, order creation and checkout all
+// price through it, so an order always costs what the cart said it would.
+type PricingServices struct {
+	Coupons  *CouponServices
+	Taxes    utils.TaxCalculator
+	Shipping *ShippingServices
+}
+
+func NewPricingServices(coupons *CouponServices, taxes utils.TaxCalculator, shipping *ShippingServices) *PricingServices {
+	return &PricingServices{
+		Coupons:  coupons,
+		Taxes:    taxes,
+		Shipping: shipping,
+	}
+}
+
+// Estimate prices the user's cart for display, shipped to destination with the
+// chosen shipping method or the cheapest one if shippingMethodID is 0. A coupon
+// that no longer applies is left out and CouponError says why.
+func (s *PricingServices) Estimate(userID uint, cart *models.Cart, destination models.Destination, shippingMethodID uint) (*models.CartSummary, error) {
+	if cart.CouponCode == "" {
+		return s.summarize(cart, nil, destination, shippingMethodID)
+	}
+
+	coupon, err := s.Coupons.Resolve(userID, cart.CouponCode)
+	if err == nil {
+		var summary *models.CartSummary
+		summary, err = s.summarize(cart, coupon, destination, shippingMethodID)
+		if err == nil {
+			return summary, nil
+		}
+	}
+	if !errors.Is(err, ErrCouponUnavailable) && !errors.Is(err, models.ErrCouponNotApplicable) {
+		return nil, err
+	}
+
+	summary, sumErr := s.summarize(cart, nil, destination, shippingMethodID)
+	if sumErr != nil {
+		return nil, sumErr
+	}
+	summary.CouponError = err.Error()
+	return summary, nil
+}
+
+// Price quotes the cart with the given coupon, which may be nil, like Estimate
+// does but failing if the coupon doesn't apply
+func (s *PricingServices) Price(cart *models.Cart, coupon *models.Coupon, destination models.Destination, shippingMethodID uint) (*models.PriceQuote, error) {
+	shipping, err := s.shippingOption(cart, destination, shippingMethodID)
+	if err != nil {
+		return nil, err
+	}
+	return s.quote(cart, coupon, shipping, destination)
+}
+
+// QuoteTx prices the user's cart for an order within tx. The shipping method
+// must be available and the cart's coupon, if any, must apply; the coupon is
+// locked until tx ends so its usage limits hold under concurrent checkouts.
+func (s *PricingServices) QuoteTx(tx *repositories.Tx, userID uint, cart *models.Cart, destination models.Destination, shippingMethodID uint) (*models.PriceQuote, *models.Coupon, error) {
+	var coupon *models.Coupon
+	if cart.CouponCode != "" {
+		var err error
+		coupon, err = s.Coupons.ResolveTx(tx, userID, cart.CouponCode)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	shipping, err := s.Shipping.Quote(shippingMethodID, cart, destination)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	quote, err := s.quote(cart, coupon, shipping, destination)
+	if err != nil {
+		return nil, nil, err
+	}
+	return quote, coupon, nil
+}
+
+func (s *PricingServices) summarize(cart *models.Cart, coupon *models.Coupon, destination models.Destination, shippingMethodID uint) (*models.CartSummary, error) {
+	quote, err := s.Price(cart, coupon, destination, shippingMethodID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CartSummary{Cart: *cart, PriceQuote: *quote}, nil
+}
+
+// shippingOption prices the chosen shipping method, or the cheapest available
+// one if methodID is 0. Returns nil for an empty cart or if no method can ship it.
+func (s *PricingServices) shippingOption(cart *models.Cart, destination models.Destination, methodID uint) (*models.ShippingOption, error) {
+	if len(cart.Items) == 0 {
+		return nil, nil
+	}
+	if methodID != 0 {
+		return s.Shipping.Quote(methodID, cart, destination)
+	}
+
+	options, err := s.Shipping.Options(cart, destination)
+	if err != nil || len(options) == 0 {
+		return nil, err
+	}
+	return &options[0], nil
+}
+
+// quote prices the cart with the coupon and shipping option, either of which may be nil
+func (s *PricingServices) quote(cart *models.Cart, coupon *models.Coupon, shipping *models.ShippingOption, destination models.Destination) (*models.PriceQuote, error) {
+	var price models.Money
+	if shipping != nil {
+		price = shipping.Price
+	}
+
+	quote, err := utils.CalculateCartTotals(cart, coupon, price, s.Taxes, destination)
+	if err != nil {
+		return nil, err
+	}
+	quote.ShippingMethod = shipping
+	return quote, nil
+}
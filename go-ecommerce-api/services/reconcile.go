@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"log"
+	"time"
+)
+
+// Reconciliation outcomes
+const (
+	ReconcileCorrected = "corrected"
+	ReconcileReported  = "reported" // needs a human, e.g. the amounts differ
+	ReconcileError     = "error"
+)
+
+// Discrepancy is a payment whose local status didn't match the provider's
+type Discrepancy struct {
+	PaymentID       uint   `json:"payment_id"`
+	OrderID         uint   `json:"order_id"`
+	PaymentIntentID string `json:"payment_intent_id"`
+	LocalStatus     string `json:"local_status"`
+	ProviderStatus  string `json:"provider_status"`
+	LocalAmount     int64  `json:"local_amount"`
+	ProviderAmount  int64  `json:"provider_amount"`
+	Outcome         string `json:"outcome"`
+	Error           string `json:"error,omitempty"`
+}
+
+// ReconcileReport summarizes one reconciliation run
+type ReconcileReport struct {
+	StartedAt     time.Time     `json:"started_at"`
+	FinishedAt    time.Time     `json:"finished_at"`
+	Checked       int           `json:"checked"`
+	Corrected     int           `json:"corrected"`
+	Failed        int           `json:"failed"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+type ReconcileServices struct {
+	PaymentRepo *repositories.PaymentRepository
+	Payments    *PaymentService
+}
+
+func NewReconcileServices(paymentRepo *repositories.PaymentRepository, payments *PaymentService) *ReconcileServices {
+	return &ReconcileServices{
+		PaymentRepo: paymentRepo,
+		Payments:    payments,
+	}
+}
+
+// Reconcile checks open payments that haven't changed for olderThan against
+// the provider. Payments the provider has moved on are corrected by applying
+// the webhook event that was missed.
+func (s *ReconcileServices) Reconcile(olderThan time.Duration) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		StartedAt:     time.Now(),
+		Discrepancies: []Discrepancy{},
+	}
+
+	payments, err := s.PaymentRepo.GetStalePayments(models.OpenPaymentStatuses, report.StartedAt.Add(-olderThan))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, payment := range payments {
+		report.Checked++
+
+		discrepancy := s.reconcilePayment(payment)
+		if discrepancy == nil {
+			continue
+		}
+
+		switch discrepancy.Outcome {
+		case ReconcileCorrected:
+			report.Corrected++
+		case ReconcileError:
+			report.Failed++
+		}
+		report.Discrepancies = append(report.Discrepancies, *discrepancy)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Run reconciles every interval until ctx is done
+func (s *ReconcileServices) Run(ctx context.Context, interval time.Duration, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Reconcile(olderThan)
+			if err != nil {
+				log.Printf("[error] payment reconciliation failed, got error %v", err)
+				continue
+			}
+			if len(report.Discrepancies) > 0 {
+				log.Printf("payment reconciliation: checked %d, corrected %d, failed %d, discrepancies %d",
+					report.Checked, report.Corrected, report.Failed, len(report.Discrepancies))
+			}
+		}
+	}
+}
+
+// reconcilePayment compares one payment with its intent and returns nil if they agree
+func (s *ReconcileServices) reconcilePayment(payment models.Payment) *Discrepancy {
+	discrepancy := &Discrepancy{
+		PaymentID:       payment.ID,
+		OrderID:         payment.OrderID,
+		PaymentIntentID: payment.PaymentIntentID,
+		LocalStatus:     payment.Status,
+		LocalAmount:     payment.Amount.Amount,
+	}
+
+	pi, err := s.Payments.getPaymentIntent(payment.PaymentIntentID)
+	if err != nil {
+		discrepancy.Outcome = ReconcileError
+		discrepancy.Error = err.Error()
+		return discrepancy
+	}
+	discrepancy.ProviderStatus = pi.Status
+	discrepancy.ProviderAmount = pi.Amount
+
+	if pi.Amount != payment.Amount.Amount {
+		// Never correct money amounts automatically
+		discrepancy.Outcome = ReconcileReported
+		return discrepancy
+	}
+
+	eventType, status := missedEvent(pi)
+	if status == "" || status == payment.Status {
+		return nil
+	}
+
+	err = s.Payments.ProcessEvent(&PaymentEvent{Type: eventType, Intent: pi})
+	if err != nil {
+		discrepancy.Outcome = ReconcileError
+		discrepancy.Error = err.Error()
+		return discrepancy
+	}
+
+	discrepancy.Outcome = ReconcileCorrected
+	return discrepancy
+}
+
+// missedEvent returns the local payment status matching the intent and the
+// webhook event that would have set it. An intent still waiting for the
+// customer matches any open status, so an empty status is returned.
+func missedEvent(pi *PaymentIntent) (string, string) {
+	switch pi.Status {
+	case IntentSucceeded:
+		return EventPaymentIntentSucceeded, "succeeded"
+	case IntentCanceled:
+		return EventPaymentIntentCanceled, "cancelled"
+	case IntentProcessing:
+		return EventPaymentIntentProcessing, IntentProcessing
+	case IntentRequiresAction:
+		return EventPaymentIntentRequiresAction, IntentRequiresAction
+	case IntentRequiresPaymentMethod:
+		if pi.FailureCode != "" || pi.FailureMessage != "" {
+			return EventPaymentIntentFailed, "failed"
+		}
+	}
+	return "", ""
+}
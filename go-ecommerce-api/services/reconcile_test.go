@@ -0,0 +1,110 @@
+package services
+
+import (
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"testing"
+	"time"
+)
+
+func TestReconcileAppliesMissedEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		// changes the intent at the provider without telling the shop
+		drift       func(env *testEnv, result *CheckoutResult) error
+		wantOutcome string
+		wantPayment string
+		wantOrder   string
+		wantStock   int
+	}{
+		{
+			name:        "in sync",
+			drift:       func(env *testEnv, result *CheckoutResult) error { return nil },
+			wantPayment: IntentRequiresPaymentMethod,
+			wantOrder:   models.OrderStatusPending,
+			wantStock:   3,
+		},
+		{
+			name: "missed success",
+			drift: func(env *testEnv, result *CheckoutResult) error {
+				_, err := env.Provider.ConfirmIntent(result.PaymentIntent.ID)
+				return err
+			},
+			wantOutcome: ReconcileCorrected,
+			wantPayment: "succeeded",
+			wantOrder:   models.OrderStatusProcessing,
+			wantStock:   3,
+		},
+		{
+			name: "missed cancel",
+			drift: func(env *testEnv, result *CheckoutResult) error {
+				_, err := env.Provider.CancelIntent(result.PaymentIntent.ID)
+				return err
+			},
+			wantOutcome: ReconcileCorrected,
+			wantPayment: "cancelled",
+			wantOrder:   models.OrderStatusCancelled,
+			wantStock:   5,
+		},
+		{
+			name: "amount mismatch",
+			drift: func(env *testEnv, result *CheckoutResult) error {
+				if _, err := env.Provider.ConfirmIntent(result.PaymentIntent.ID); err != nil {
+					return err
+				}
+				return env.DB.Model(&models.Payment{}).Where("payment_intent_id = ?", result.PaymentIntent.ID).
+					UpdateColumn("amount_minor", result.PaymentIntent.Amount-1).Error
+			},
+			wantOutcome: ReconcileReported,
+			wantPayment: IntentRequiresPaymentMethod,
+			wantOrder:   models.OrderStatusPending,
+			wantStock:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			alice := env.createUser(t, "alice")
+			env.createAddress(t, alice)
+			phone := env.createProduct(t, "Phone", 19999, 5)
+
+			env.addToCart(t, alice, phone, 2)
+			result := env.checkout(t, alice)
+			if err := tt.drift(env, result); err != nil {
+				t.Fatal(err)
+			}
+
+			// Only payments left alone for a while are checked
+			env.DB.Model(&models.Payment{}).Where("order_id = ?", result.Order.ID).
+				UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+
+			reconcile := NewReconcileServices(repositories.NewPaymentRepository(env.DB), env.Payments)
+			report, err := reconcile.Reconcile(time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Checked != 1 {
+				t.Errorf("checked %d payments, want 1", report.Checked)
+			}
+			switch {
+			case tt.wantOutcome == "" && len(report.Discrepancies) != 0:
+				t.Errorf("got discrepancies %+v, want none", report.Discrepancies)
+			case tt.wantOutcome != "" && (len(report.Discrepancies) != 1 || report.Discrepancies[0].Outcome != tt.wantOutcome):
+				t.Errorf("got discrepancies %+v, want one %s", report.Discrepancies, tt.wantOutcome)
+			}
+
+			var payment models.Payment
+			env.DB.Where("payment_intent_id = ?", result.PaymentIntent.ID).First(&payment)
+			if payment.Status != tt.wantPayment {
+				t.Errorf("payment is %s, want %s", payment.Status, tt.wantPayment)
+			}
+			if order := env.order(t, result.Order.ID); order.Status != tt.wantOrder {
+				t.Errorf("order is %s, want %s", order.Status, tt.wantOrder)
+			}
+			if stock := env.productStock(t, phone.ID); stock != tt.wantStock {
+				t.Errorf("stock is %d, want %d", stock, tt.wantStock)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotRefundable = errors.New("payment is not refundable")
+	ErrInvalidRefund = errors.New("invalid refund")
+)
+
+// RefundLine asks for a quantity of an order item to be refunded
+type RefundLine struct {
+	OrderItemID uint
+	Quantity    int
+}
+
+type RefundServices struct {
+	UnitOfWork  repositories.UnitOfWork
+	Repo        *repositories.RefundRepository
+	PaymentRepo *repositories.PaymentRepository
+	OrderRepo   repositories.OrderRepository
+	Provider    PaymentProvider
+}
+
+func NewRefundServices(
+	uow repositories.UnitOfWork,
+	repo *repositories.RefundRepository,
+	paymentRepo *repositories.PaymentRepository,
+	orderRepo repositories.OrderRepository,
+	provider PaymentProvider,
+) *RefundServices {
+	return &RefundServices{
+		UnitOfWork:  uow,
+		Repo:        repo,
+		PaymentRepo: paymentRepo,
+		OrderRepo:   orderRepo,
+		Provider:    provider,
+	}
+}
+
+// RefundOrder refunds the given order lines, or everything not yet refunded
+// (including shipping and tax) when lines is empty.
+//
+// The refund is recorded as pending before the provider is asked for the
+// money, so money that goes back always has a local record. If confirming it
+// fails afterwards, the charge.refunded event for it picks the pending refund
+// up.
+func (s *RefundServices) RefundOrder(orderID uint, lines []RefundLine, reason string) (*models.Refund, error) {
+	order, err := s.OrderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	refund, payment, err := s.createPendingRefund(order, lines, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	providerRefund, err := s.Provider.Refund(payment.PaymentIntentID, refund.Amount.Amount)
+	if err != nil {
+		if markErr := s.Repo.Update(refund.ID, map[string]interface{}{"status": "failed"}); markErr != nil {
+			log.Printf("[error] failed to mark refund %d failed, got error %v", refund.ID, markErr)
+		}
+		return nil, err
+	}
+
+	err = s.UnitOfWork.Do(func(tx *repositories.Tx) error {
+		current, err := s.PaymentRepo.WithTx(tx.DB).GetPaymentByIntentIDForUpdate(payment.PaymentIntentID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Repo.WithTx(tx.DB).Update(refund.ID, map[string]interface{}{
+			"provider_refund_id": providerRefund.ID,
+			"status":             providerRefund.Status,
+		}); err != nil {
+			return err
+		}
+
+		return s.applyRefundedAmount(tx, current, current.RefundedAmount.Amount+refund.Amount.Amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refund.ProviderRefundID = &providerRefund.ID
+	refund.Status = providerRefund.Status
+	return refund, nil
+}
+
+// createPendingRefund validates the refund against the order's payment and
+// records it, with its items, as pending
+func (s *RefundServices) createPendingRefund(order *models.Order, lines []RefundLine, reason string) (*models.Refund, *models.Payment, error) {
+	var refund *models.Refund
+	var payment *models.Payment
+	err := s.UnitOfWork.Do(func(tx *repositories.Tx) error {
+		// Lock the payment so concurrent refunds can't exceed the amount paid
+		var err error
+		payment, err = s.PaymentRepo.WithTx(tx.DB).GetPaymentByOrderIDForUpdate(order.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotRefundable
+		}
+		if err != nil {
+			return err
+		}
+		if payment.Status != "succeeded" && payment.Status != "partially_refunded" {
+			return ErrNotRefundable
+		}
+
+		refunded, err := s.Repo.WithTx(tx.DB).RefundedQuantities(order.ID)
+		if err != nil {
+			return err
+		}
+		unconfirmed, err := s.Repo.WithTx(tx.DB).UnconfirmedAmount(payment.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining, err := payment.Amount.Sub(payment.RefundedAmount)
+		if err != nil {
+			return err
+		}
+		remaining.Amount -= unconfirmed
+		items, amount, err := refundItems(order, lines, refunded)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			amount = remaining
+		}
+		if amount.Currency != remaining.Currency {
+			return fmt.Errorf("%w: refund in %s for a payment in %s", ErrInvalidRefund, amount.Currency, remaining.Currency)
+		}
+		if amount.Amount <= 0 || amount.Amount > remaining.Amount {
+			return fmt.Errorf("%w: amount exceeds the refundable balance", ErrInvalidRefund)
+		}
+
+		refund = &models.Refund{
+			PaymentID: payment.ID,
+			OrderID:   order.ID,
+			Amount:    amount,
+			Status:    "pending",
+			Reason:    reason,
+			Items:     items,
+		}
+		return s.Repo.WithTx(tx.DB).Create(refund)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return refund, payment, nil
+}
+
+// GetRefundsByOrderID lists the refunds issued for an order
+func (s *RefundServices) GetRefundsByOrderID(orderID uint) ([]models.Refund, error) {
+	return s.Repo.GetByOrderID(orderID)
+}
+
+// SyncChargeRefunds records refunds reported by the provider (e.g. issued from
+// the Stripe dashboard) and brings the payment's refunded amount in line.
+// Charges without a local payment are ignored.
+func (s *RefundServices) SyncChargeRefunds(charge *ProviderCharge) error {
+	return s.UnitOfWork.Do(func(tx *repositories.Tx) error {
+		payment, err := s.PaymentRepo.WithTx(tx.DB).GetPaymentByIntentIDForUpdate(charge.PaymentIntentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not a payment of ours, nothing to sync
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, providerRefund := range charge.Refunds {
+			_, err := s.Repo.WithTx(tx.DB).GetByProviderRefundID(providerRefund.ID)
+			if err == nil {
+				continue // already recorded when we issued it
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			// One we issued but couldn't confirm after the provider made it
+			pending, err := s.Repo.WithTx(tx.DB).GetUnconfirmed(payment.ID, providerRefund.Amount)
+			if err == nil {
+				if err := s.Repo.WithTx(tx.DB).Update(pending.ID, map[string]interface{}{
+					"provider_refund_id": providerRefund.ID,
+					"status":             providerRefund.Status,
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			providerRefundID := providerRefund.ID
+			if err := s.Repo.WithTx(tx.DB).Create(&models.Refund{
+				PaymentID:        payment.ID,
+				OrderID:          payment.OrderID,
+				ProviderRefundID: &providerRefundID,
+				Amount:           models.NewMoney(providerRefund.Amount, providerRefund.Currency),
+				Status:           providerRefund.Status,
+				Reason:           "Refunded at payment provider",
+			}); err != nil {
+				return err
+			}
+		}
+
+		return s.applyRefundedAmount(tx, payment, charge.AmountRefunded)
+	})
+}
+
+// applyRefundedAmount stores the total refunded and moves the payment and order
+// to partially_refunded or refunded accordingly
+func (s *RefundServices) applyRefundedAmount(tx *repositories.Tx, payment *models.Payment, refunded int64) error {
+	status := "partially_refunded"
+	if refunded >= payment.Amount.Amount {
+		status = "refunded"
+	}
+
+	if err := s.PaymentRepo.WithTx(tx.DB).UpdatePaymentDetails(payment.PaymentIntentID, map[string]interface{}{
+		"refunded_amount_minor":    refunded,
+		"refunded_amount_currency": payment.Amount.Currency,
+		"status":                   status,
+	}); err != nil {
+		return err
+	}
+
+	return s.OrderRepo.WithTx(tx.DB).Update(payment.OrderID, map[string]interface{}{
+		"payment_status": status,
+	})
+}
+
+// refundItems validates the requested lines against what is left to refund and
+// prices them. With no lines, every remaining quantity is included.
+func refundItems(order *models.Order, lines []RefundLine, refunded map[uint]int) ([]models.RefundItem, models.Money, error) {
+	var items []models.RefundItem
+	var amount models.Money
+
+	if len(lines) == 0 {
+		for _, orderItem := range order.Items {
+			quantity := orderItem.Quantity - refunded[orderItem.ID]
+			if quantity > 0 {
+				lineAmount, err := refundLineAmount(orderItem, refunded[orderItem.ID], quantity)
+				if err != nil {
+					return nil, amount, err
+				}
+				items = append(items, models.RefundItem{
+					OrderItemID: orderItem.ID,
+					Quantity:    quantity,
+					Amount:      lineAmount,
+				})
+			}
+		}
+		return items, amount, nil
+	}
+
+	byID := make(map[uint]models.OrderItem, len(order.Items))
+	for _, orderItem := range order.Items {
+		byID[orderItem.ID] = orderItem
+	}
+
+	requested := make(map[uint]int)
+	for _, line := range lines {
+		orderItem, ok := byID[line.OrderItemID]
+		if !ok {
+			return nil, amount, fmt.Errorf("%w: order item %d is not part of order %d", ErrInvalidRefund, line.OrderItemID, order.ID)
+		}
+
+		before := refunded[line.OrderItemID] + requested[line.OrderItemID]
+		requested[line.OrderItemID] += line.Quantity
+		if requested[line.OrderItemID]+refunded[line.OrderItemID] > orderItem.Quantity {
+			return nil, amount, fmt.Errorf("%w: quantity for order item %d exceeds the refundable quantity", ErrInvalidRefund, line.OrderItemID)
+		}
+
+		lineAmount, err := refundLineAmount(orderItem, before, line.Quantity)
+		if err != nil {
+			return nil, amount, err
+		}
+		amount, err = amount.Add(lineAmount)
+		if err != nil {
+			return nil, amount, err
+		}
+		items = append(items, models.RefundItem{
+			OrderItemID: line.OrderItemID,
+			Quantity:    line.Quantity,
+			Amount:      lineAmount,
+		})
+	}
+
+	return items, amount, nil
+}
+
+// refundLineAmount is what refunding quantity more of the order item returns
+// to the customer, after before were refunded already. Each unit gets its
+// share of the line's net total, discount and tax included, rounded like
+// pricing rounds; shares are taken cumulatively so refunding the whole line,
+// however it is split up, returns exactly what was paid for it.
+func refundLineAmount(orderItem models.OrderItem, before int, quantity int) (models.Money, error) {
+	total, err := orderItem.Total()
+	if err != nil {
+		return models.Money{}, err
+	}
+	share := func(n int) models.Money {
+		return total.MulRate(float64(n) / float64(orderItem.Quantity))
+	}
+	return share(before + quantity).Sub(share(before))
+}
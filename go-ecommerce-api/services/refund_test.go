@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"testing"
+)
+
+func TestRefundLineAmountAddsUpToLineTotal(t *testing.T) {
+	usd := func(amount int64) models.Money { return models.NewMoney(amount, "USD") }
+
+	tests := []struct {
+		name   string
+		item   models.OrderItem
+		splits []int // quantities refunded one after another
+		want   []int64
+	}{
+		{
+			name:   "tax added on top",
+			item:   models.OrderItem{Quantity: 3, Price: usd(1000), Tax: usd(240)},
+			splits: []int{1, 1, 1},
+			want:   []int64{1080, 1080, 1080},
+		},
+		{
+			name:   "discount and tax shared unevenly",
+			item:   models.OrderItem{Quantity: 3, Price: usd(333), Discount: usd(100), Tax: usd(72)},
+			splits: []int{1, 1, 1},
+			want:   []int64{324, 323, 324},
+		},
+		{
+			name:   "partial then the rest",
+			item:   models.OrderItem{Quantity: 3, Price: usd(333), Discount: usd(100), Tax: usd(72)},
+			splits: []int{2, 1},
+			want:   []int64{647, 324},
+		},
+		{
+			name:   "tax included in the price",
+			item:   models.OrderItem{Quantity: 2, Price: usd(1200), Tax: usd(400), TaxInclusive: true},
+			splits: []int{1, 1},
+			want:   []int64{1200, 1200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, err := tt.item.Total()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			before := 0
+			var refunded int64
+			for i, quantity := range tt.splits {
+				amount, err := refundLineAmount(tt.item, before, quantity)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if amount.Amount != tt.want[i] {
+					t.Errorf("refund %d of %d units is %d, want %d", i+1, quantity, amount.Amount, tt.want[i])
+				}
+				before += quantity
+				refunded += amount.Amount
+			}
+
+			if refunded != total.Amount {
+				t.Errorf("refunded %d in total, want the line total %d", refunded, total.Amount)
+			}
+		})
+	}
+}
+
+func TestRefundedChargeWithoutLocalPaymentIsIgnored(t *testing.T) {
+	env := newTestEnv(t)
+
+	// A charge made at the provider outside the shop, e.g. from its dashboard
+	intent, err := env.Provider.CreateIntent(5000, "USD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Provider.ConfirmIntent(intent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Provider.Refund(intent.ID, 5000); err != nil {
+		t.Fatal(err)
+	}
+
+	env.deliver(t, EventChargeRefunded, intent.ID)
+
+	var refunds int64
+	env.DB.Model(&models.Refund{}).Count(&refunds)
+	if refunds != 0 {
+		t.Errorf("%d refunds were recorded, want none", refunds)
+	}
+}
+
+// refundHookProvider is the fake provider with its Refund replaced
+type refundHookProvider struct {
+	*FakeProvider
+	refund func(paymentIntentID string, amount int64) (*ProviderRefund, error)
+}
+
+func (p *refundHookProvider) Refund(paymentIntentID string, amount int64) (*ProviderRefund, error) {
+	return p.refund(paymentIntentID, amount)
+}
+
+func TestRefundOrderKeepsRecordWhenConfirmingFails(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+	env.pay(t, result.PaymentIntent.ID)
+
+	// The provider refunds, then the database goes away before the refund is
+	// confirmed locally
+	provider := &refundHookProvider{FakeProvider: env.Provider}
+	provider.refund = func(paymentIntentID string, amount int64) (*ProviderRefund, error) {
+		refund, err := env.Provider.Refund(paymentIntentID, amount)
+		env.DB.Model(&models.Payment{}).Where("payment_intent_id = ?", paymentIntentID).Update("payment_intent_id", "pi_unreachable")
+		return refund, err
+	}
+	refunds := NewRefundServices(repositories.NewUnitOfWork(env.DB), repositories.NewRefundRepository(env.DB), repositories.NewPaymentRepository(env.DB), repositories.NewOrderRepository(env.DB, env.Redis), provider)
+
+	if _, err := refunds.RefundOrder(result.Order.ID, nil, "Damaged"); err == nil {
+		t.Fatal("refund succeeded, want the confirmation to fail")
+	}
+
+	var recorded []models.Refund
+	env.DB.Find(&recorded)
+	if len(recorded) != 1 || recorded[0].Status != "pending" || recorded[0].ProviderRefundID != nil {
+		t.Fatalf("got refunds %+v, want one pending without a provider refund", recorded)
+	}
+
+	// The provider's charge.refunded event confirms the pending refund
+	env.DB.Model(&models.Payment{}).Where("payment_intent_id = ?", "pi_unreachable").Update("payment_intent_id", result.PaymentIntent.ID)
+	env.deliver(t, EventChargeRefunded, result.PaymentIntent.ID)
+
+	recorded = nil
+	env.DB.Find(&recorded)
+	if len(recorded) != 1 || recorded[0].Status != "succeeded" || recorded[0].ProviderRefundID == nil {
+		t.Errorf("got refunds %+v, want the one confirmed", recorded)
+	}
+	if order := env.order(t, result.Order.ID); order.PaymentStatus != "refunded" {
+		t.Errorf("order payment is %s, want refunded", order.PaymentStatus)
+	}
+}
+
+func TestRefundOrderMarksRejectedRefundFailed(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+	env.pay(t, result.PaymentIntent.ID)
+
+	provider := &refundHookProvider{FakeProvider: env.Provider}
+	provider.refund = func(string, int64) (*ProviderRefund, error) {
+		return nil, errors.New("card expired")
+	}
+	refunds := NewRefundServices(repositories.NewUnitOfWork(env.DB), repositories.NewRefundRepository(env.DB), repositories.NewPaymentRepository(env.DB), repositories.NewOrderRepository(env.DB, env.Redis), provider)
+
+	if _, err := refunds.RefundOrder(result.Order.ID, nil, "Damaged"); err == nil {
+		t.Fatal("refund succeeded, want the provider's error")
+	}
+
+	var recorded []models.Refund
+	env.DB.Find(&recorded)
+	if len(recorded) != 1 || recorded[0].Status != "failed" {
+		t.Fatalf("got refunds %+v, want one failed", recorded)
+	}
+
+	// A failed refund doesn't hold back the balance
+	refund, err := env.Refunds.RefundOrder(result.Order.ID, nil, "Damaged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := env.order(t, result.Order.ID).Total
+	if refund.Amount != total || refund.Status != "succeeded" {
+		t.Errorf("got refund of %s (%s), want %s succeeded", refund.Amount, refund.Status, total)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShippingUnavailable = errors.New("shipping method is not available")
+	ErrShippingCodeTaken   = errors.New("shipping method code already exists")
+)
+
+type ShippingServices struct {
+	Repo *repositories.ShippingMethodRepository
+}
+
+func NewShippingServices(repo *repositories.ShippingMethodRepository) *ShippingServices {
+	return &ShippingServices{
+		Repo: repo,
+	}
+}
+
+func (s *ShippingServices) GetAll() ([]models.ShippingMethod, error) {
+	return s.Repo.List()
+}
+
+func (s *ShippingServices) GetByID(id uint) (*models.ShippingMethod, error) {
+	method, err := s.Repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return method, nil
+}
+
+// Create validates and saves a new shipping method with its rates
+func (s *ShippingServices) Create(method *models.ShippingMethod) error {
+	normalizeShippingMethod(method)
+	if err := method.Validate(); err != nil {
+		return err
+	}
+	if err := s.requireUniqueCode(0, method.Code); err != nil {
+		return err
+	}
+
+	method.ID = 0
+	for i := range method.Rates {
+		method.Rates[i].ID = 0
+	}
+	return s.Repo.Create(method)
+}
+
+// Update replaces the shipping method's settings and rates
+func (s *ShippingServices) Update(id uint, method *models.ShippingMethod) error {
+	normalizeShippingMethod(method)
+	if err := method.Validate(); err != nil {
+		return err
+	}
+	if err := s.requireUniqueCode(id, method.Code); err != nil {
+		return err
+	}
+
+	return notFound(s.Repo.Update(id, method))
+}
+
+func (s *ShippingServices) Delete(id uint) error {
+	return notFound(s.Repo.Delete(id))
+}
+
+// Options prices every enabled method that can ship the cart to destination,
+// cheapest first
+func (s *ShippingServices) Options(cart *models.Cart, destination models.Destination) ([]models.ShippingOption, error) {
+	subtotal, err := utils.CartSubtotal(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := s.Repo.ListEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	destination = destination.Normalize()
+	weight := utils.CartWeight(cart)
+
+	options := []models.ShippingOption{}
+	for i := range methods {
+		if option, ok := methods[i].Quote(destination, weight, subtotal); ok {
+			options = append(options, option)
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Price.Amount < options[j].Price.Amount
+	})
+	return options, nil
+}
+
+// Quote prices the chosen shipping method for the cart. Returns
+// ErrShippingUnavailable if the method doesn't exist, is disabled or has no
+// rate for the cart and destination.
+func (s *ShippingServices) Quote(methodID uint, cart *models.Cart, destination models.Destination) (*models.ShippingOption, error) {
+	method, err := s.Repo.GetByID(methodID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: unknown method %d", ErrShippingUnavailable, methodID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if method.Disabled {
+		return nil, fmt.Errorf("%w: %s is no longer offered", ErrShippingUnavailable, method.Name)
+	}
+
+	subtotal, err := utils.CartSubtotal(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	option, ok := method.Quote(destination.Normalize(), utils.CartWeight(cart), subtotal)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s can't deliver this cart to the destination", ErrShippingUnavailable, method.Name)
+	}
+	return &option, nil
+}
+
+// requireUniqueCode checks that no other shipping method uses code
+func (s *ShippingServices) requireUniqueCode(id uint, code string) error {
+	existing, err := s.Repo.GetByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return ErrShippingCodeTaken
+	}
+	return nil
+}
+
+func normalizeShippingMethod(method *models.ShippingMethod) {
+	method.Code = models.NormalizeShippingCode(method.Code)
+	for i := range method.Rates {
+		countries := method.Rates[i].Countries
+		for j, country := range countries {
+			countries[j] = models.Destination{Country: country}.Normalize().Country
+		}
+	}
+}
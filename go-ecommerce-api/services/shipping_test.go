@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"go-ecommerce-api/models"
+	"testing"
+)
+
+func TestCheckoutRejectsShippingMethodNotValidForAddress(t *testing.T) {
+	env := newTestEnv(t)
+	domestic := env.createShippingMethod(t, "domestic", false, models.ShippingRate{
+		Countries: []string{"US"},
+		Price:     models.NewMoney(499, models.DefaultCurrency),
+	})
+	retired := env.createShippingMethod(t, "retired", true, models.ShippingRate{
+		Price: models.NewMoney(99, models.DefaultCurrency),
+	})
+
+	alice := env.createUser(t, "alice")
+	address := env.createAddress(t, alice)
+	env.DB.Model(address).Updates(map[string]interface{}{"country": "DE", "state": ""})
+	phone := env.createProduct(t, "Phone", 1999, 5)
+	env.addToCart(t, alice, phone, 1)
+
+	options, err := env.Cart.GetShippingOptions(alice.ID, models.Destination{Country: "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options) != 1 || options[0].MethodID != env.shippingMethodID {
+		t.Errorf("got options %+v, want only the seeded standard method", options)
+	}
+
+	for _, method := range []*models.ShippingMethod{domestic, retired} {
+		_, err := env.Checkout.Checkout(alice.ID, CheckoutDetails{ShippingMethodID: method.ID})
+		if !errors.Is(err, ErrShippingUnavailable) {
+			t.Errorf("checkout with %s got error %v, want %v", method.Code, err, ErrShippingUnavailable)
+		}
+	}
+	if stock := env.productStock(t, phone.ID); stock != 5 {
+		t.Errorf("stock is %d, want 5", stock)
+	}
+
+	// The standard method ships anywhere
+	result, err := env.Checkout.Checkout(alice.ID, CheckoutDetails{ShippingMethodID: env.shippingMethodID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Order.Shipping.Amount != 599 {
+		t.Errorf("shipping is %s, want 5.99 USD", result.Order.Shipping)
+	}
+}
+
+func TestShippingOptionsAreCheapestFirst(t *testing.T) {
+	env := newTestEnv(t)
+	express := env.createShippingMethod(t, "express", false, models.ShippingRate{
+		Price: models.NewMoney(1499, models.DefaultCurrency),
+	})
+	economy := env.createShippingMethod(t, "economy", false, models.ShippingRate{
+		Countries: []string{"US"},
+		Price:     models.NewMoney(299, models.DefaultCurrency),
+	})
+
+	alice := env.createUser(t, "alice")
+	phone := env.createProduct(t, "Phone", 1999, 5)
+	env.addToCart(t, alice, phone, 1)
+
+	options, err := env.Cart.GetShippingOptions(alice.ID, models.Destination{Country: "US", State: "CA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint{economy.ID, env.shippingMethodID, express.ID}
+	if len(options) != len(want) {
+		t.Fatalf("got %d options, want %d", len(options), len(want))
+	}
+	for i, option := range options {
+		if option.MethodID != want[i] {
+			t.Errorf("option %d is method %d (%s), want method %d", i, option.MethodID, option.Price, want[i])
+		}
+	}
+}
+
+func (e *testEnv) createShippingMethod(t *testing.T, code string, disabled bool, rates ...models.ShippingRate) *models.ShippingMethod {
+	t.Helper()
+	method := &models.ShippingMethod{Code: code, Name: code, MinDays: 1, MaxDays: 5, Disabled: disabled, Rates: rates}
+	if err := e.DB.Create(method).Error; err != nil {
+		t.Fatalf("creating shipping method %s: %v", code, err)
+	}
+	return method
+}
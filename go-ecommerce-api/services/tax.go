@@ -0,0 +1,99 @@
+package services
+
+import (
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+)
+
+// TaxServices manages the tax table and calculates tax from it. Lines no rule
+// matches are taxed at Fallback.
+type TaxServices struct {
+	Repo     *repositories.TaxRuleRepository
+	Fallback utils.TaxCalculator
+}
+
+func NewTaxServices(repo *repositories.TaxRuleRepository) *TaxServices {
+	return &TaxServices{
+		Repo:     repo,
+		Fallback: utils.FlatTax{Rate: utils.TaxRate},
+	}
+}
+
+func (s *TaxServices) GetAll() ([]models.TaxRule, error) {
+	return s.Repo.List()
+}
+
+// Create validates and saves a new tax rule
+func (s *TaxServices) Create(rule *models.TaxRule) error {
+	normalizeTaxRule(rule)
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	rule.ID = 0
+	return s.Repo.Create(rule)
+}
+
+// Update replaces the tax rule's settings
+func (s *TaxServices) Update(id uint, rule *models.TaxRule) error {
+	normalizeTaxRule(rule)
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return notFound(s.Repo.Update(id, rule))
+}
+
+func (s *TaxServices) Delete(id uint) error {
+	return notFound(s.Repo.Delete(id))
+}
+
+// CalculateTax implements utils.TaxCalculator using the most specific rule
+// that matches each line's destination and product category
+func (s *TaxServices) CalculateTax(destination models.Destination, lines []utils.TaxLine) ([]utils.LineTax, error) {
+	rules, err := s.Repo.ForCountry(destination.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	fallback, err := s.Fallback.CalculateTax(destination, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	taxes := make([]utils.LineTax, len(lines))
+	for i, line := range lines {
+		rule := matchTaxRule(rules, destination, line.Category)
+		if rule == nil {
+			taxes[i] = fallback[i]
+			continue
+		}
+		taxes[i] = utils.LineTax{
+			Rate:      rule.Rate,
+			Amount:    utils.TaxOn(line.Amount, rule.Rate, rule.Inclusive),
+			Inclusive: rule.Inclusive,
+		}
+	}
+	return taxes, nil
+}
+
+// matchTaxRule returns the most specific rule for the line, or nil if none matches
+func matchTaxRule(rules []models.TaxRule, destination models.Destination, category string) *models.TaxRule {
+	var best *models.TaxRule
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(destination, category) {
+			continue
+		}
+		if best == nil || rule.Specificity() > best.Specificity() {
+			best = rule
+		}
+	}
+	return best
+}
+
+func normalizeTaxRule(rule *models.TaxRule) {
+	destination := models.Destination{Country: rule.Country, State: rule.State}.Normalize()
+	rule.Country = destination.Country
+	rule.State = destination.State
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"go-ecommerce-api/utils"
+	"testing"
+)
+
+// taxTable is a US state rate charged on top of prices and an EU VAT rate
+// included in them, with a reduced VAT rate for one category
+var taxTable = []models.TaxRule{
+	{Country: "US", State: "CA", Rate: 0.0725},
+	{Country: "DE", Rate: 0.19, Inclusive: true},
+	{Country: "DE", Category: "Home", Rate: 0.07, Inclusive: true},
+}
+
+func TestTaxTableUsesMostSpecificRule(t *testing.T) {
+	env := newTestEnv(t)
+	for _, rule := range taxTable {
+		if err := env.DB.Create(&rule).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	taxes := NewTaxServices(repositories.NewTaxRuleRepository(env.DB))
+
+	tests := []struct {
+		name          string
+		destination   models.Destination
+		category      string
+		amount        int64
+		wantRate      float64
+		wantTax       int64
+		wantInclusive bool
+	}{
+		{"state rate", models.Destination{Country: "US", State: "CA"}, "Electronics", 10000, 0.0725, 725, false},
+		{"country VAT", models.Destination{Country: "DE"}, "Electronics", 11900, 0.19, 1900, true},
+		{"category VAT", models.Destination{Country: "DE"}, "Home", 10700, 0.07, 700, true},
+		{"no rule", models.Destination{Country: "US", State: "NY"}, "Electronics", 10000, utils.TaxRate, 1800, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := taxes.CalculateTax(tt.destination, []utils.TaxLine{
+				{Category: tt.category, Amount: models.NewMoney(tt.amount, "USD")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := lines[0]
+			if got.Rate != tt.wantRate || got.Amount.Amount != tt.wantTax || got.Inclusive != tt.wantInclusive {
+				t.Errorf("got %+v, want rate %v, tax %d, inclusive %v", got, tt.wantRate, tt.wantTax, tt.wantInclusive)
+			}
+		})
+	}
+}
+
+func TestCheckoutChargesTaxOnlyOnceForInclusivePrices(t *testing.T) {
+	tests := []struct {
+		name            string
+		country, state  string
+		wantTax         int64
+		wantTaxIncluded int64
+		wantTotal       int64
+	}{
+		// 19999 * 0.0725 = 1449.93
+		{"tax on top", "US", "CA", 1450, 0, 21449},
+		// 19999 / 1.19 = 16805.88, so 3193 of the price is VAT
+		{"tax included", "DE", "", 0, 3193, 19999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			for _, rule := range taxTable {
+				if err := env.DB.Create(&rule).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			alice := env.createUser(t, "alice")
+			address := env.createAddress(t, alice)
+			env.DB.Model(address).Updates(map[string]interface{}{"country": tt.country, "state": tt.state})
+			// Ships free, so the total is the price and its tax
+			phone := env.createProduct(t, "Phone", 19999, 5)
+
+			env.addToCart(t, alice, phone, 1)
+			result := env.checkout(t, alice)
+
+			order := result.Order
+			if order.Tax.Amount != tt.wantTax || order.TaxIncluded.Amount != tt.wantTaxIncluded || order.Total.Amount != tt.wantTotal {
+				t.Errorf("order has tax %d, tax included %d and total %d, want %d, %d and %d",
+					order.Tax.Amount, order.TaxIncluded.Amount, order.Total.Amount, tt.wantTax, tt.wantTaxIncluded, tt.wantTotal)
+			}
+
+			// The amount charged is worked out again from the stored order
+			var stored models.Order
+			if err := env.DB.Preload("Items").First(&stored, order.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			amount, err := payableAmount(&stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if amount.Amount != tt.wantTotal || result.PaymentIntent.Amount != tt.wantTotal {
+				t.Errorf("payable amount is %d and the intent is for %d, want %d", amount.Amount, result.PaymentIntent.Amount, tt.wantTotal)
+			}
+		})
+	}
+}
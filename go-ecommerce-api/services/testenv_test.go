@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-ecommerce-api/config"
+	"go-ecommerce-api/database"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testEnv is the services wired together as router.NewServices does, on a
+// fresh database, an in-memory Redis and the fake payment provider.
+//
+// Tests run against the Postgres database in TEST_DATABASE_URL when it is
+// set, which is emptied first, and otherwise against a SQLite file. Only
+// Postgres takes the row locks, so concurrency tests prove the most there.
+type testEnv struct {
+	DB        *gorm.DB
+	Redis     *memoryRedis
+	Provider  *FakeProvider
+	Inventory repositories.InventoryRepository
+	Addresses *AddressServices
+	Cart      *CartServices
+	Orders    *OrderServices
+	Expiry    *OrderExpiryServices
+	Checkout  *CheckoutServices
+	Payments  *PaymentService
+	Refunds   *RefundServices
+	Webhooks  *WebhookServices
+	Guard     *LoginGuard
+	Auth      *AuthServices
+	MFA       *MFAServices
+	Account   *AccountServices
+	Mail      mailbox
+
+	shippingMethodID uint
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	t.Setenv("JWT_SECRET", "test access secret")
+	t.Setenv("JWT_REFRESH_SECRET", "test refresh secret")
+
+	db := openTestDB(t)
+	redisClient := newMemoryRedis()
+	provider := NewFakeProvider("")
+
+	guard := NewLoginGuard(redisClient, &config.Config{
+		LoginBackoffAfter:  3,
+		LoginBackoffBase:   time.Second,
+		LoginMaxFailures:   5,
+		LoginIPMaxFailures: 20,
+		LoginLockout:       15 * time.Minute,
+	})
+	userServ := NewUserServices(repositories.NewUserRepository(db, redisClient))
+	mfaRepo := repositories.NewMFARepository(db)
+	authServ := NewAuthServices(userServ, repositories.NewSessionRepository(db), mfaRepo, redisClient, guard)
+	mail := make(mailbox, 10)
+
+	addressServ := NewAddressServices(repositories.NewAddressRepository(db))
+	couponServ := NewCouponServices(repositories.NewCouponRepository(db))
+	taxServ := NewTaxServices(repositories.NewTaxRuleRepository(db))
+	shippingServ := NewShippingServices(repositories.NewShippingMethodRepository(db))
+	pricingServ := NewPricingServices(couponServ, taxServ, shippingServ)
+
+	cartRepo := repositories.NewCartRepository(db, redisClient)
+	inventoryRepo := repositories.NewInventoryRepository(db, redisClient)
+	orderRepo := repositories.NewOrderRepository(db, redisClient)
+	paymentRepo := repositories.NewPaymentRepository(db)
+	uow := repositories.NewUnitOfWork(db)
+
+	refundServ := NewRefundServices(uow, repositories.NewRefundRepository(db), paymentRepo, orderRepo, provider)
+	paymentServ := NewPaymentService(uow, paymentRepo, orderRepo, inventoryRepo, provider, refundServ)
+
+	env := &testEnv{
+		DB:        db,
+		Redis:     redisClient,
+		Provider:  provider,
+		Inventory: inventoryRepo,
+		Addresses: addressServ,
+		Cart:      NewCartServices(cartRepo, couponServ, pricingServ),
+		Orders:    NewOrderServices(orderRepo, paymentRepo, inventoryRepo, paymentServ, refundServ),
+		Expiry:    NewOrderExpiryServices(orderRepo, paymentRepo, inventoryRepo, cartRepo, paymentServ, false),
+		Checkout:  NewCheckoutServices(uow, orderRepo, cartRepo, inventoryRepo, paymentServ, couponServ, pricingServ, addressServ),
+		Payments:  paymentServ,
+		Refunds:   refundServ,
+		Webhooks:  NewWebhookServices(repositories.NewWebhookEventRepository(db), paymentServ, provider),
+		Guard:     guard,
+		Auth:      authServ,
+		MFA:       NewMFAServices(uow, userServ, mfaRepo, authServ, guard, redisClient, "Shop"),
+		Account:   NewAccountServices(userServ, authServ, repositories.NewUserTokenRepository(db), mail, "https://shop.test", time.Hour, 24*time.Hour),
+		Mail:      mail,
+	}
+
+	// The migrations seed a standard shipping method
+	var method models.ShippingMethod
+	if err := db.First(&method).Error; err != nil {
+		t.Fatalf("loading the seeded shipping method: %v", err)
+	}
+	env.shippingMethodID = method.ID
+
+	return env
+}
+
+// openTestDB returns an empty, migrated database
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	var db *gorm.DB
+	var err error
+	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+		db, err = gorm.Open(postgres.Open(dsn), config)
+	} else {
+		// Immediate transactions take SQLite's write lock up front, so
+		// concurrent transactions wait for each other instead of deadlocking
+		path := filepath.Join(t.TempDir(), "test.db")
+		db, err = gorm.Open(sqlite.Open(path+"?_txlock=immediate&_pragma=busy_timeout(10000)"), config)
+	}
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := database.WrapDatabase(db).Migrate(); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	if db.Dialector.Name() == "postgres" {
+		// Start from the state a fresh migration leaves behind
+		tables, err := db.Migrator().GetTables()
+		if err != nil {
+			t.Fatalf("listing tables: %v", err)
+		}
+		for _, table := range tables {
+			if err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %q RESTART IDENTITY CASCADE", table)).Error; err != nil {
+				t.Fatalf("emptying %s: %v", table, err)
+			}
+		}
+		if err := database.WrapDatabase(db).Migrate(); err != nil {
+			t.Fatalf("migrating test database: %v", err)
+		}
+	}
+
+	return db
+}
+
+func (e *testEnv) createUser(t *testing.T, name string) *models.User {
+	t.Helper()
+	user := &models.User{Name: name, Email: name + "@example.com", Password: "not a hash", Role: "user"}
+	if err := e.DB.Create(user).Error; err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	return user
+}
+
+func (e *testEnv) createProduct(t *testing.T, name string, price int64, stock int) *models.Product {
+	t.Helper()
+	product := &models.Product{
+		Name:     name,
+		Price:    models.NewMoney(price, models.DefaultCurrency),
+		Category: "Electronics",
+		Stock:    stock,
+	}
+	if err := e.DB.Create(product).Error; err != nil {
+		t.Fatalf("creating product %s: %v", name, err)
+	}
+	return product
+}
+
+func (e *testEnv) createAddress(t *testing.T, user *models.User) *models.Address {
+	t.Helper()
+	address := &models.Address{PostalAddress: models.PostalAddress{
+		Name:       user.Name,
+		Line1:      "1 Market St",
+		City:       "San Francisco",
+		State:      "CA",
+		PostalCode: "94105",
+		Country:    "US",
+	}}
+	if err := e.Addresses.Create(user.ID, address); err != nil {
+		t.Fatalf("creating address for %s: %v", user.Name, err)
+	}
+	return address
+}
+
+func (e *testEnv) addToCart(t *testing.T, user *models.User, product *models.Product, quantity int) *models.CartItem {
+	t.Helper()
+	cart, err := e.Cart.GetCartByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("loading cart of %s: %v", user.Name, err)
+	}
+	item, err := e.Cart.AddItem(cart.ID, product.ID, quantity)
+	if err != nil {
+		t.Fatalf("adding %s to the cart of %s: %v", product.Name, user.Name, err)
+	}
+	return item
+}
+
+// checkout places the user's cart as an order with a payment intent, shipped
+// to their default address
+func (e *testEnv) checkout(t *testing.T, user *models.User) *CheckoutResult {
+	t.Helper()
+	result, err := e.Checkout.Checkout(user.ID, CheckoutDetails{ShippingMethodID: e.shippingMethodID})
+	if err != nil {
+		t.Fatalf("checking out the cart of %s: %v", user.Name, err)
+	}
+	return result
+}
+
+// pay confirms the order's intent at the provider and delivers the
+// payment_intent.succeeded webhook for it
+func (e *testEnv) pay(t *testing.T, intentID string) {
+	t.Helper()
+	if _, err := e.Provider.ConfirmIntent(intentID); err != nil {
+		t.Fatalf("confirming intent %s: %v", intentID, err)
+	}
+	e.deliver(t, EventPaymentIntentSucceeded, intentID)
+}
+
+// deliver sends the provider's webhook event of the given type for the intent
+func (e *testEnv) deliver(t *testing.T, eventType string, intentID string) {
+	t.Helper()
+	payload, signature, err := e.Provider.Event(eventType, intentID)
+	if err != nil {
+		t.Fatalf("building %s event: %v", eventType, err)
+	}
+	if err := e.Webhooks.HandleWebhook(payload, signature); err != nil {
+		t.Fatalf("handling %s event: %v", eventType, err)
+	}
+}
+
+func (e *testEnv) productStock(t *testing.T, productID uint) int {
+	t.Helper()
+	var product models.Product
+	if err := e.DB.First(&product, productID).Error; err != nil {
+		t.Fatalf("loading product %d: %v", productID, err)
+	}
+	return product.Stock
+}
+
+func (e *testEnv) order(t *testing.T, orderID uint) *models.Order {
+	t.Helper()
+	var order models.Order
+	if err := e.DB.First(&order, orderID).Error; err != nil {
+		t.Fatalf("loading order %d: %v", orderID, err)
+	}
+	return &order
+}
+
+// mailbox is a Mailer keeping the emails sent for the test to read
+type mailbox chan Email
+
+func (m mailbox) Send(email Email) error {
+	m <- email
+	return nil
+}
+
+// next waits for the next email. Emails are sent in the background.
+func (m mailbox) next(t *testing.T) Email {
+	t.Helper()
+	select {
+	case email := <-m:
+		return email
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email was sent")
+		return Email{}
+	}
+}
+
+// memoryRedis is an in-memory database.RedisClient
+type memoryRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newMemoryRedis() *memoryRedis {
+	return &memoryRedis{
+		values:  make(map[string]string),
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (r *memoryRedis) Set(ctx context.Context, key string, value interface{}) error {
+	return r.SetWithTTL(ctx, key, value, 5*time.Minute)
+}
+
+func (r *memoryRedis) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set(key, value, ttl)
+	return nil
+}
+
+func (r *memoryRedis) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.get(key); ok {
+		return false, nil
+	}
+	r.set(key, value, ttl)
+	return true, nil
+}
+
+func (r *memoryRedis) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.get(key)
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (r *memoryRedis) Del(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.values, key)
+	delete(r.expires, key)
+	return nil
+}
+
+func (r *memoryRedis) Exists(ctx context.Context, key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.get(key)
+	return ok, nil
+}
+
+func (r *memoryRedis) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, _ := r.get(key)
+	n, _ := strconv.ParseInt(value, 10, 64)
+	n++
+	r.set(key, n, ttl)
+	return n, nil
+}
+
+func (r *memoryRedis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.get(key); !ok {
+		return -2, nil
+	}
+	return time.Until(r.expires[key]), nil
+}
+
+func (r *memoryRedis) Close() error {
+	return nil
+}
+
+func (r *memoryRedis) get(key string) (string, bool) {
+	value, ok := r.values[key]
+	if ok && time.Now().After(r.expires[key]) {
+		delete(r.values, key)
+		delete(r.expires, key)
+		return "", false
+	}
+	return value, ok
+}
+
+func (r *memoryRedis) set(key string, value interface{}, ttl time.Duration) {
+	switch v := value.(type) {
+	case string:
+		r.values[key] = v
+	case []byte:
+		r.values[key] = string(v)
+	default:
+		r.values[key] = fmt.Sprint(v)
+	}
+	r.expires[key] = time.Now().Add(ttl)
+}
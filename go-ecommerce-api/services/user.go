@@ -3,6 +3,7 @@ package services
 import (
 	"go-ecommerce-api/models"
 	"go-ecommerce-api/repositories"
+	"time"
 )
 
 type UserServices interface {
@@ -12,6 +13,11 @@ type UserServices interface {
 	Update(user *models.User, id uint) error
 	Delete(id uint) error
 	GetUserByEmail(e string) (*models.User, error)
+	IncrementTokenVersion(id uint) error
+	IncrementTokenVersionTx(tx *repositories.Tx, id uint) error
+	UpdatePassword(id uint, hashedPassword string) error
+	SetEmailVerified(id uint, verifiedAt *time.Time) error
+	SetRole(id uint, role string) error
 }
 
 type userServices struct {
@@ -42,8 +48,59 @@ func (s userServices) Create(user *models.User) error {
 	return s.repo.Create(user)
 }
 
+// Update the user's own details. A new email has to be verified again.
 func (s userServices) Update(user *models.User, id uint) error {
-	return s.repo.Update(user, id)
+	current, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	// Only the token version counter, admins and email verification change these
+	user.Role = ""
+	user.TokenVersion = 0
+	user.Disabled = false
+	user.EmailVerifiedAt = nil
+
+	if err := s.repo.Update(user, id); err != nil {
+		return err
+	}
+	if user.Email != "" && user.Email != current.Email {
+		return s.repo.SetEmailVerified(id, nil)
+	}
+	return nil
+}
+
+// SetRole changes the user's role. Their tokens are invalidated, so none
+// carries the old role.
+func (s userServices) SetRole(id uint, role string) error {
+	current, err := s.repo.GetByID(id)
+	if err != nil {
+		return notFound(err)
+	}
+	if current.Role == role {
+		return nil
+	}
+	if err := s.repo.UpdateRole(id, role); err != nil {
+		return err
+	}
+	return s.repo.IncrementTokenVersion(id)
+}
+
+func (s userServices) IncrementTokenVersion(id uint) error {
+	return s.repo.IncrementTokenVersion(id)
+}
+
+// IncrementTokenVersionTx is IncrementTokenVersion within a unit of work
+func (s userServices) IncrementTokenVersionTx(tx *repositories.Tx, id uint) error {
+	return s.repo.WithTx(tx.DB).IncrementTokenVersion(id)
+}
+
+func (s userServices) UpdatePassword(id uint, hashedPassword string) error {
+	return s.repo.UpdatePassword(id, hashedPassword)
+}
+
+func (s userServices) SetEmailVerified(id uint, verifiedAt *time.Time) error {
+	return s.repo.SetEmailVerified(id, verifiedAt)
 }
 
 func (s userServices) Delete(id uint) error {
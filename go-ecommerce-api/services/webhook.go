@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-api/models"
+	"go-ecommerce-api/repositories"
+	"time"
+)
+
+var ErrWebhookNotReplayable = errors.New("only failed or stalled webhook events can be replayed")
+
+// webhookProcessingLease is how long an attempt at an event may take. An event
+// still processing after that is taken to have crashed, and a redelivery or
+// replay may take it over.
+const webhookProcessingLease = 5 * time.Minute
+
+type WebhookServices struct {
+	Repo     *repositories.WebhookEventRepository
+	Payments *PaymentService
+	Provider PaymentProvider
+}
+
+func NewWebhookServices(repo *repositories.WebhookEventRepository, payments *PaymentService, provider PaymentProvider) *WebhookServices {
+	return &WebhookServices{
+		Repo:     repo,
+		Payments: payments,
+		Provider: provider,
+	}
+}
+
+// HandleWebhook verifies and records an incoming event and applies it once.
+// Redeliveries of an event that was processed, or is being processed, are
+// acknowledged without doing anything; a redelivered event that failed, or
+// whose processing stalled, is retried.
+func (s *WebhookServices) HandleWebhook(payload []byte, signature string) error {
+	event, err := s.Provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record := &models.WebhookEvent{
+		Provider:            s.Provider.Name(),
+		EventID:             event.ID,
+		Type:                event.Type,
+		Payload:             string(payload),
+		Status:              models.WebhookEventProcessing,
+		Attempts:            1,
+		ProcessingStartedAt: &now,
+	}
+	created, err := s.Repo.Record(record)
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		if record.Status == models.WebhookEventProcessed {
+			return nil
+		}
+		claimed, err := s.Repo.Claim(record.ID, time.Now().Add(-webhookProcessingLease))
+		if err != nil || !claimed {
+			return err
+		}
+	}
+
+	return s.process(record.ID, event)
+}
+
+// ListEvents lists stored events, optionally only those with the given status
+func (s *WebhookServices) ListEvents(status string) ([]models.WebhookEvent, error) {
+	return s.Repo.List(status)
+}
+
+// ReplayEvent processes a failed or stalled event again from its stored payload
+func (s *WebhookServices) ReplayEvent(id uint) (*models.WebhookEvent, error) {
+	record, err := s.Repo.GetByID(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if record.Provider != s.Provider.Name() {
+		return nil, fmt.Errorf("%w: event came from %s", ErrWebhookNotReplayable, record.Provider)
+	}
+
+	claimed, err := s.Repo.Claim(record.ID, time.Now().Add(-webhookProcessingLease))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrWebhookNotReplayable
+	}
+
+	event, err := s.Provider.ParseEvent([]byte(record.Payload))
+	if err != nil {
+		s.Repo.MarkFailed(record.ID, err.Error())
+		return nil, err
+	}
+
+	processErr := s.process(record.ID, event)
+
+	// The stored event is returned even when processing failed again, with the new error
+	record, err = s.Repo.GetByID(record.ID)
+	if err != nil {
+		return nil, err
+	}
+	return record, processErr
+}
+
+// process applies the event and records the outcome
+func (s *WebhookServices) process(id uint, event *PaymentEvent) error {
+	if err := s.Payments.ProcessEvent(event); err != nil {
+		if markErr := s.Repo.MarkFailed(id, err.Error()); markErr != nil {
+			return markErr
+		}
+		return err
+	}
+	return s.Repo.MarkProcessed(id)
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"go-ecommerce-api/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSucceededEventDeliveredConcurrentlyIsAppliedOnce(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 2)
+	result := env.checkout(t, alice)
+	if _, err := env.Provider.ConfirmIntent(result.PaymentIntent.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, err := env.Provider.Event(EventPaymentIntentSucceeded, result.PaymentIntent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider redelivers while the first delivery is still in flight
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- env.Webhooks.HandleWebhook(payload, signature)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("delivery failed: %v", err)
+		}
+	}
+
+	var events []models.WebhookEvent
+	env.DB.Find(&events)
+	if len(events) != 1 || events[0].Status != models.WebhookEventProcessed || events[0].Attempts != 1 {
+		t.Errorf("got events %+v, want one processed in a single attempt", events)
+	}
+
+	order := env.order(t, result.Order.ID)
+	if order.Status != models.OrderStatusProcessing || order.PaymentStatus != "succeeded" {
+		t.Errorf("order is %s with payment %s, want %s with payment succeeded", order.Status, order.PaymentStatus, models.OrderStatusProcessing)
+	}
+
+	var reservations []models.StockReservation
+	env.DB.Where("order_id = ?", order.ID).Find(&reservations)
+	if len(reservations) != 1 || reservations[0].Status != models.ReservationCommitted {
+		t.Errorf("got reservations %+v, want one committed", reservations)
+	}
+	if stock := env.productStock(t, phone.ID); stock != 3 {
+		t.Errorf("stock is %d, want 3", stock)
+	}
+}
+
+func TestCanceledEventsDeliveredConcurrentlyReleaseStockOnce(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 2)
+	result := env.checkout(t, alice)
+	if _, err := env.Provider.CancelIntent(result.PaymentIntent.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Separate events for the same cancellation, e.g. one sent again under a
+	// new ID after the provider timed out
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		payload, signature, err := env.Provider.Event(EventPaymentIntentCanceled, result.PaymentIntent.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := env.Webhooks.HandleWebhook(payload, signature); err != nil {
+				t.Errorf("delivery failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stock := env.productStock(t, phone.ID); stock != 5 {
+		t.Errorf("stock is %d, want the 5 there were before checkout", stock)
+	}
+	if order := env.order(t, result.Order.ID); order.Status != models.OrderStatusCancelled {
+		t.Errorf("order is %s, want %s", order.Status, models.OrderStatusCancelled)
+	}
+}
+
+func TestReplayedSuccessKeepsRefund(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.createUser(t, "alice")
+	env.createAddress(t, alice)
+	phone := env.createProduct(t, "Phone", 19999, 5)
+
+	env.addToCart(t, alice, phone, 1)
+	result := env.checkout(t, alice)
+	env.pay(t, result.PaymentIntent.ID)
+	if _, err := env.Refunds.RefundOrder(result.Order.ID, nil, "Damaged"); err != nil {
+		t.Fatal(err)
+	}
+	refunded := env.order(t, result.Order.ID)
+
+	// The provider sends the success again under a new event ID
+	env.deliver(t, EventPaymentIntentSucceeded, result.PaymentIntent.ID)
+
+	var payment models.Payment
+	env.DB.Where("payment_intent_id = ?", result.PaymentIntent.ID).First(&payment)
+	if payment.Status != "refunded" {
+		t.Errorf("payment is %s, want refunded", payment.Status)
+	}
+	if order := env.order(t, result.Order.ID); order.Status != refunded.Status || order.PaymentStatus != refunded.PaymentStatus {
+		t.Errorf("order is %s with payment %s, want it left %s with payment %s",
+			order.Status, order.PaymentStatus, refunded.Status, refunded.PaymentStatus)
+	}
+}
+
+func TestSucceededEventWithInvalidOrderIDIsSkipped(t *testing.T) {
+	env := newTestEnv(t)
+
+	err := env.Payments.ProcessEvent(&PaymentEvent{
+		Type: EventPaymentIntentSucceeded,
+		Intent: &PaymentIntent{
+			ID:       "pi_unknown",
+			Status:   IntentSucceeded,
+			Metadata: map[string]string{"order_id": "not-a-number"},
+		},
+	})
+	if err != nil {
+		t.Errorf("got error %v, want the event skipped", err)
+	}
+}
+
+func TestRedeliveryTakesOverStalledEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		startedAt time.Time
+		wantPaid  bool
+	}{
+		{"attempt still within its lease", time.Now().Add(-time.Minute), false},
+		{"attempt that stalled", time.Now().Add(-2 * webhookProcessingLease), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			alice := env.createUser(t, "alice")
+			env.createAddress(t, alice)
+			phone := env.createProduct(t, "Phone", 19999, 5)
+
+			env.addToCart(t, alice, phone, 1)
+			result := env.checkout(t, alice)
+			if _, err := env.Provider.ConfirmIntent(result.PaymentIntent.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			payload, signature, err := env.Provider.Event(EventPaymentIntentSucceeded, result.PaymentIntent.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			event, err := env.Provider.VerifyWebhook(payload, signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// An earlier delivery was recorded but its attempt never finished
+			startedAt := tt.startedAt
+			if err := env.DB.Create(&models.WebhookEvent{
+				Provider:            env.Provider.Name(),
+				EventID:             event.ID,
+				Type:                event.Type,
+				Payload:             string(payload),
+				Status:              models.WebhookEventProcessing,
+				Attempts:            1,
+				ProcessingStartedAt: &startedAt,
+			}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			if err := env.Webhooks.HandleWebhook(payload, signature); err != nil {
+				t.Fatalf("redelivery failed: %v", err)
+			}
+
+			paid := env.order(t, result.Order.ID).Status == models.OrderStatusProcessing
+			if paid != tt.wantPaid {
+				t.Errorf("order paid is %v, want %v", paid, tt.wantPaid)
+			}
+		})
+	}
+}
@@ -2,41 +2,137 @@ package utils
 
 import "go-ecommerce-api/models"
 
-const (
-	TaxRate               = 0.18  // 18% tax rate
-	ShippingCost          = 5.99  // Shipping cost
-	FreeShippingThreshold = 100.0 // Free shipping for orders over $100
-)
+const TaxRate = 0.18 // 18% tax rate, when no tax rule matches
 
-// CalculateCartTotals calculates the subtotal, shipping, tax, and total for a cart
-func CalculateCartTotals(cart *models.Cart) *models.CartSummary {
-	var subtotal float64
-
-	// Calculate subtotal from cart items
+// CartSubtotal sums the price of the loaded cart items. All items must be
+// priced in the same currency; an empty cart is zero in the default currency.
+func CartSubtotal(cart *models.Cart) (models.Money, error) {
+	var subtotal models.Money
 	for _, item := range cart.Items {
 		if item.Product.ID != 0 { // Ensure product is loaded
-			subtotal += item.Product.Price * float64(item.Quantity)
+			var err error
+			subtotal, err = subtotal.Add(item.Product.Price.Mul(item.Quantity))
+			if err != nil {
+				return models.Money{}, err
+			}
 		}
 	}
 
-	// Calculate shipping
-	var shipping float64
-	if subtotal > 0 && subtotal <= FreeShippingThreshold {
-		shipping = ShippingCost
+	currency := subtotal.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	return models.NewMoney(subtotal.Amount, currency), nil
+}
+
+// CartWeight sums the weight of the loaded cart items, in grams
+func CartWeight(cart *models.Cart) int {
+	weight := 0
+	for _, item := range cart.Items {
+		weight += item.Product.Weight * item.Quantity
+	}
+	return weight
+}
+
+// CalculateCartTotals quotes a cart shipped to destination for the given
+// shipping charge: each line with its share of the discount and its tax, and
+// the totals. All items must be priced in the same currency. coupon may be
+// nil; if the cart doesn't qualify for it models.ErrCouponNotApplicable is
+// returned.
+func CalculateCartTotals(cart *models.Cart, coupon *models.Coupon, shipping models.Money, taxes TaxCalculator, destination models.Destination) (*models.PriceQuote, error) {
+	subtotal, err := CartSubtotal(cart)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate tax
-	tax := subtotal * TaxRate
+	currency := subtotal.Currency
+	if shipping.Currency == "" {
+		shipping.Currency = currency
+	}
+	if shipping.Currency != currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+
+	// Apply the coupon, if any
+	var discount *models.Discount
+	discountAmount := models.NewMoney(0, currency)
+	if coupon != nil {
+		discount, err = CalculateDiscount(coupon, cart, subtotal, shipping)
+		if err != nil {
+			return nil, err
+		}
+		discountAmount = discount.Amount
+	}
+
+	// Calculate tax per line on what the item costs after the discount
+	lines, err := quoteLines(cart, coupon, discount, taxes, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tax included in the prices is already part of the subtotal
+	tax := models.NewMoney(0, currency)
+	taxIncluded := models.NewMoney(0, currency)
+	for _, line := range lines {
+		if line.TaxInclusive {
+			taxIncluded.Amount += line.Tax.Amount
+		} else {
+			tax.Amount += line.Tax.Amount
+		}
+	}
 
 	// Calculate total
-	total := subtotal + shipping + tax
+	total := models.NewMoney(subtotal.Amount+shipping.Amount+tax.Amount-discountAmount.Amount, currency)
+
+	return &models.PriceQuote{
+		Lines:       lines,
+		Subtotal:    subtotal,
+		Discount:    discount,
+		Shipping:    shipping,
+		Tax:         tax,
+		TaxIncluded: taxIncluded,
+		Total:       total,
+	}, nil
+}
+
+// quoteLines prices each loaded cart item and taxes it on its price less its
+// share of the discount
+func quoteLines(cart *models.Cart, coupon *models.Coupon, discount *models.Discount, taxes TaxCalculator, destination models.Destination) ([]models.QuoteLine, error) {
+	shares := AllocateDiscount(coupon, cart, discount)
+
+	quoted := []models.QuoteLine{}
+	var lines []TaxLine
+	for _, item := range cart.Items {
+		if item.Product.ID == 0 {
+			continue
+		}
+		price := item.Product.Price
+		amount := price.Mul(item.Quantity)
+
+		quoted = append(quoted, models.QuoteLine{
+			CartItemID: item.ID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			UnitPrice:  price,
+			Subtotal:   amount,
+			Discount:   models.NewMoney(shares[item.ID], price.Currency),
+		})
+		amount.Amount -= shares[item.ID]
+		lines = append(lines, TaxLine{Category: item.Product.Category, Amount: amount})
+	}
+	if len(lines) == 0 {
+		return quoted, nil
+	}
+
+	lineTaxes, err := taxes.CalculateTax(destination.Normalize(), lines)
+	if err != nil {
+		return nil, err
+	}
 
-	return &models.CartSummary{
-		Cart:     *cart,
-		Subtotal: subtotal,
-		Shipping: shipping,
-		Tax:      tax,
-		TaxRate:  TaxRate,
-		Total:    total,
+	for i := range quoted {
+		quoted[i].TaxRate = lineTaxes[i].Rate
+		quoted[i].Tax = lineTaxes[i].Amount
+		quoted[i].TaxInclusive = lineTaxes[i].Inclusive
 	}
+	return quoted, nil
 }
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"go-ecommerce-api/models"
+	"sort"
+)
+
+// CalculateDiscount works out what the coupon takes off a cart with the given
+// subtotal and shipping charge. Returns models.ErrCouponNotApplicable if the
+// cart doesn't meet the coupon's conditions.
+func CalculateDiscount(coupon *models.Coupon, cart *models.Cart, subtotal models.Money, shipping models.Money) (*models.Discount, error) {
+	if !coupon.MinSubtotal.IsZero() {
+		if coupon.MinSubtotal.Currency != subtotal.Currency {
+			return nil, fmt.Errorf("%w: only valid for %s carts", models.ErrCouponNotApplicable, coupon.MinSubtotal.Currency)
+		}
+		if subtotal.Amount < coupon.MinSubtotal.Amount {
+			return nil, fmt.Errorf("%w: requires a subtotal of at least %s", models.ErrCouponNotApplicable, coupon.MinSubtotal)
+		}
+	}
+
+	eligible := eligibleItems(coupon, cart)
+	if len(eligible) == 0 {
+		return nil, fmt.Errorf("%w: no eligible items in the cart", models.ErrCouponNotApplicable)
+	}
+
+	var eligibleSubtotal models.Money
+	for _, item := range eligible {
+		var err error
+		eligibleSubtotal, err = eligibleSubtotal.Add(item.Product.Price.Mul(item.Quantity))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	discount := &models.Discount{
+		Code:        coupon.Code,
+		Description: coupon.Description,
+		Amount:      models.NewMoney(0, subtotal.Currency),
+	}
+
+	switch coupon.Type {
+	case models.CouponPercentage:
+		discount.Amount = eligibleSubtotal.MulRate(coupon.PercentOff / 100)
+
+	case models.CouponFixedAmount:
+		if coupon.AmountOff.Currency != subtotal.Currency {
+			return nil, fmt.Errorf("%w: only valid for %s carts", models.ErrCouponNotApplicable, coupon.AmountOff.Currency)
+		}
+		discount.Amount = models.NewMoney(min(coupon.AmountOff.Amount, eligibleSubtotal.Amount), subtotal.Currency)
+
+	case models.CouponFreeShipping:
+		if shipping.Amount == 0 {
+			return nil, fmt.Errorf("%w: shipping is already free", models.ErrCouponNotApplicable)
+		}
+		discount.Amount = shipping
+		discount.Shipping = shipping
+
+	case models.CouponBuyXGetY:
+		free := buyXGetYDiscount(eligible, coupon.BuyQuantity, coupon.GetQuantity)
+		if free == 0 {
+			return nil, fmt.Errorf("%w: buy %d to get %d free", models.ErrCouponNotApplicable, coupon.BuyQuantity, coupon.GetQuantity)
+		}
+		discount.Amount = models.NewMoney(free, subtotal.Currency)
+	}
+
+	return discount, nil
+}
+
+// eligibleItems returns the cart items the coupon applies to
+func eligibleItems(coupon *models.Coupon, cart *models.Cart) []models.CartItem {
+	var items []models.CartItem
+	for _, item := range cart.Items {
+		if item.Product.ID == 0 {
+			continue
+		}
+		if coupon.Category != "" && item.Product.Category != coupon.Category {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// buyXGetYDiscount groups the eligible units from most to least expensive in
+// runs of buy+get, and makes the get cheapest units of each full run free
+func buyXGetYDiscount(items []models.CartItem, buy int, get int) int64 {
+	var prices []int64
+	for _, item := range items {
+		for i := 0; i < item.Quantity; i++ {
+			prices = append(prices, item.Product.Price.Amount)
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] > prices[j] })
+
+	var free int64
+	group := buy + get
+	for start := 0; start+group <= len(prices); start += group {
+		for _, price := range prices[start+buy : start+group] {
+			free += price
+		}
+	}
+	return free
+}
+
+// AllocateDiscount spreads the part of the discount taken off items over the
+// coupon's eligible cart items in proportion to their cost, so that each line
+// can be taxed on what it really costs. Returns the share per cart item ID.
+func AllocateDiscount(coupon *models.Coupon, cart *models.Cart, discount *models.Discount) map[uint]int64 {
+	shares := map[uint]int64{}
+	if coupon == nil || discount == nil {
+		return shares
+	}
+
+	amount := discount.Amount.Amount - discount.Shipping.Amount
+	eligible := eligibleItems(coupon, cart)
+	if amount <= 0 || len(eligible) == 0 {
+		return shares
+	}
+
+	var total int64
+	for _, item := range eligible {
+		total += item.Product.Price.Amount * int64(item.Quantity)
+	}
+	if total == 0 {
+		return shares
+	}
+
+	// The last line takes the rounding remainder
+	allocated := int64(0)
+	for i, item := range eligible {
+		share := amount * item.Product.Price.Amount * int64(item.Quantity) / total
+		if i == len(eligible)-1 {
+			share = amount - allocated
+		}
+		shares[item.ID] = share
+		allocated += share
+	}
+	return shares
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"go-ecommerce-api/models"
 	"os"
@@ -9,6 +12,15 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 24 * time.Hour
+	// How long a user has to enter their second factor after the password
+	MFAChallengeTTL = 5 * time.Minute
+
+	mfaChallengeAudience = "mfa"
+)
+
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -19,25 +31,63 @@ type Token struct {
 }
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID       uint   `json:"user_id"`
+	Role         string `json:"role"`
+	SessionID    string `json:"sid"`
+	TokenVersion int    `json:"ver"`
+	MFA          bool   `json:"mfa"` // the session was started with a second factor
 	jwt.RegisteredClaims
 }
 
+// RefreshClaims identify the refresh token by its ID (jti), so the session
+// can tell the current token apart from ones it already rotated away
 type RefreshClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID       uint   `json:"user_id"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	SessionID    string `json:"sid"`
+	TokenVersion int    `json:"ver"`
+	MFA          bool   `json:"mfa"` // the session was started with a second factor
 	jwt.RegisteredClaims
 }
 
-func GenerateTokenPair(User *models.User) (*TokenPair, error) {
+// NewTokenID returns a random ID for sessions and refresh tokens
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 of a token sent to the user, which is what gets
+// stored so a database leak doesn't leak usable tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MFAChallengeClaims prove the password was checked while the second factor
+// is still to come. They can't be used as access tokens.
+type MFAChallengeClaims struct {
+	UserID       uint `json:"user_id"`
+	TokenVersion int  `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// GenerateTokenPair issues tokens for the user's session. refreshID becomes
+// the refresh token's jti, and mfa tells whether the session was started with
+// a second factor.
+func GenerateTokenPair(User *models.User, sessionID string, refreshID string, mfa bool) (*TokenPair, error) {
 	// ACCESS TOKEN
 	accessClaims := Claims{
-		UserID: User.ID,
-		Role:   User.Role,
+		UserID:       User.ID,
+		Role:         User.Role,
+		SessionID:    sessionID,
+		TokenVersion: User.TokenVersion,
+		MFA:          mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -50,11 +100,15 @@ func GenerateTokenPair(User *models.User) (*TokenPair, error) {
 
 	// REFRESH TOKEN
 	refreshClaims := RefreshClaims{
-		UserID: User.ID,
-		Email:  User.Email,
-		Role:   User.Role,
+		UserID:       User.ID,
+		Email:        User.Email,
+		Role:         User.Role,
+		SessionID:    sessionID,
+		TokenVersion: User.TokenVersion,
+		MFA:          mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        refreshID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -103,3 +157,37 @@ func ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
 
 	return nil, errors.New("invalid refresh token")
 }
+
+// GenerateMFAChallenge issues the token a user exchanges, along with a code
+// from their second factor, for a token pair. challengeID becomes its jti.
+func GenerateMFAChallenge(User *models.User, challengeID string) (string, error) {
+	claims := MFAChallengeClaims{
+		UserID:       User.ID,
+		TokenVersion: User.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID,
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(MFAChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+func ValidateMFAChallenge(tokenString string) (*MFAChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MFAChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}, jwt.WithAudience(mfaChallengeAudience))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*MFAChallengeClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid MFA challenge")
+}
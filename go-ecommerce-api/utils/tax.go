@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"go-ecommerce-api/models"
+	"math"
+)
+
+// TaxLine is a cart line to be taxed. Amount is what the line costs after discounts.
+type TaxLine struct {
+	Category string
+	Amount   models.Money
+}
+
+// LineTax is the tax on one TaxLine
+type LineTax struct {
+	Rate      float64
+	Amount    models.Money
+	Inclusive bool // Amount is already part of the line's price
+}
+
+// TaxCalculator works out the tax on cart lines shipped to a destination. It
+// returns one LineTax per line, in the same order.
+type TaxCalculator interface {
+	CalculateTax(destination models.Destination, lines []TaxLine) ([]LineTax, error)
+}
+
+// FlatTax charges the same rate on every line, on top of its price
+type FlatTax struct {
+	Rate float64
+}
+
+func (t FlatTax) CalculateTax(destination models.Destination, lines []TaxLine) ([]LineTax, error) {
+	taxes := make([]LineTax, len(lines))
+	for i, line := range lines {
+		taxes[i] = LineTax{
+			Rate:   t.Rate,
+			Amount: TaxOn(line.Amount, t.Rate, false),
+		}
+	}
+	return taxes, nil
+}
+
+// TaxOn returns the tax on amount at rate, rounded to the minor unit. For
+// tax-inclusive prices it is the part of amount that is tax.
+func TaxOn(amount models.Money, rate float64, inclusive bool) models.Money {
+	if !inclusive {
+		return amount.MulRate(rate)
+	}
+	net := int64(math.Round(float64(amount.Amount) / (1 + rate)))
+	return models.NewMoney(amount.Amount-net, amount.Currency)
+}
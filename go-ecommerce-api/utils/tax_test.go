@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"go-ecommerce-api/models"
+	"testing"
+)
+
+func TestTaxOn(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    models.Money
+		rate      float64
+		inclusive bool
+		want      int64
+	}{
+		{"exclusive", models.NewMoney(1000, "USD"), 0.2, false, 200},
+		{"exclusive rounds half up", models.NewMoney(1000, "USD"), 0.0725, false, 73},
+		{"exclusive zero rate", models.NewMoney(1000, "USD"), 0, false, 0},
+		{"inclusive", models.NewMoney(1190, "EUR"), 0.19, true, 190},
+		{"inclusive rounds the net price", models.NewMoney(999, "EUR"), 0.2, true, 166},
+		{"inclusive zero rate", models.NewMoney(999, "EUR"), 0, true, 0},
+		{"inclusive zero-decimal currency", models.NewMoney(1100, "JPY"), 0.1, true, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TaxOn(tt.amount, tt.rate, tt.inclusive)
+			if got.Amount != tt.want || got.Currency != tt.amount.Currency {
+				t.Errorf("got %s, want %d %s", got, tt.want, tt.amount.Currency)
+			}
+		})
+	}
+}